@@ -0,0 +1,71 @@
+package admin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"skulpture/landing/auth"
+	"skulpture/landing/leads"
+	"skulpture/landing/orphans"
+	"skulpture/landing/privacy"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler serves the admin API, mounted behind auth.Middleware. Lifecycle
+// changes are written to mirror when it's not nil, orphans are reconciled
+// when the storage backend supports it
+func Handler(store *leads.SQLiteStore, mirror leads.Mirror, subjects *privacy.Service, orphans *orphans.Reconciler) http.Handler {
+	api := &api{store: store, mirror: mirror, subjects: subjects, orphans: orphans}
+
+	r := chi.NewRouter()
+	r.Get("/enquiries", api.listEnquiries)
+	r.Get("/enquiries/{id}", api.getEnquiry)
+	r.Patch("/enquiries/{id}", api.updateEnquiry)
+	r.Post("/enquiries/{id}/notes", api.addNote)
+	r.Get("/enquiries/{id}/audit", api.getAuditLog)
+	r.Post("/subjects/export", api.exportSubject)
+	r.Post("/subjects/erase", api.eraseSubject)
+	if orphans != nil {
+		r.Post("/orphans/reconcile", api.reconcileOrphans)
+	}
+
+	return r
+}
+
+type api struct {
+	store    *leads.SQLiteStore
+	mirror   leads.Mirror
+	subjects *privacy.Service
+	orphans  *orphans.Reconciler
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.ErrorContext(r.Context(), "error", "admin response", err.Error())
+	}
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// actor is who's making the request, recorded in the audit log
+func actor(r *http.Request) string {
+	if principal := auth.PrincipalFrom(r.Context()); principal != nil {
+		return principal.Subject
+	}
+
+	return "unknown"
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "error", "admin", err.Error(), "path", r.URL.Path)
+	}
+
+	writeJSON(w, r, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,272 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	enums "skulpture/landing/enums"
+	"skulpture/landing/leads"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const DEFAULT_LIMIT = 25
+
+var statuses = []enums.ProcessingStatus{
+	enums.ProcessingReceived,
+	enums.ProcessingQuarantined,
+	enums.ProcessingConfirmed,
+	enums.ProcessingFailed,
+}
+
+type enquirySummary struct {
+	Id          string                 `json:"id"`
+	Email       string                 `json:"email"`
+	FirstName   string                 `json:"firstName"`
+	LastName    string                 `json:"lastName"`
+	Enquiry     string                 `json:"enquiry"`
+	Category    string                 `json:"category"`
+	Country     string                 `json:"country"`
+	Status      enums.ProcessingStatus `json:"status"`
+	State       enums.LeadState        `json:"state"`
+	Assignee    string                 `json:"assignee"`
+	EmailStatus enums.EmailStatus      `json:"emailStatus"`
+	CreatedAt   time.Time              `json:"createdAt"`
+}
+
+type enquiryList struct {
+	Enquiries []enquirySummary `json:"enquiries"`
+	Total     int              `json:"total"`
+	Limit     int              `json:"limit"`
+	Offset    int              `json:"offset"`
+}
+
+type attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	Backend     string `json:"backend"`
+	Link        string `json:"link"`
+}
+
+type message struct {
+	Id          string       `json:"id"`
+	FromEmail   string       `json:"fromEmail"`
+	FromName    string       `json:"fromName"`
+	Subject     string       `json:"subject"`
+	Text        string       `json:"text"`
+	Attachments []attachment `json:"attachments"`
+	ReceivedAt  time.Time    `json:"receivedAt"`
+}
+
+type enquiryDetail struct {
+	enquirySummary
+	Mobile        string              `json:"mobile"`
+	IpAddress     string              `json:"ipAddress"`
+	Policy        enums.CountryPolicy `json:"policy"`
+	Route         string              `json:"route"`
+	Environment   string              `json:"environment"`
+	EmailStatusAt *time.Time          `json:"emailStatusAt"`
+	Attachments   []attachment        `json:"attachments"`
+	Messages      []message           `json:"messages"`
+	Notes         []note              `json:"notes"`
+	UpdatedAt     time.Time           `json:"updatedAt"`
+}
+
+// listEnquiries searches with ?q=, filters by ?status=, ?state=, ?assignee=, ?from= and ?to=
+// (RFC 3339 or YYYY-MM-DD, to is inclusive for dates) and pages with
+// ?limit= and ?offset=
+func (a *api) listEnquiries(w http.ResponseWriter, r *http.Request) {
+	query, err := parseSearchQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+
+		return
+	}
+
+	result, err := a.store.Search(r.Context(), query)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	list := enquiryList{
+		Enquiries: make([]enquirySummary, 0, len(result.Leads)),
+		Total:     result.Total,
+		Limit:     query.Limit,
+		Offset:    query.Offset,
+	}
+	for _, lead := range result.Leads {
+		list.Enquiries = append(list.Enquiries, summarise(&lead))
+	}
+
+	writeJSON(w, r, http.StatusOK, list)
+}
+
+func (a *api) getEnquiry(w http.ResponseWriter, r *http.Request) {
+	a.writeEnquiry(w, r, chi.URLParam(r, "id"))
+}
+
+func (a *api) writeEnquiry(w http.ResponseWriter, r *http.Request, id string) {
+	lead, err := a.store.Get(r.Context(), id)
+	if errors.Is(err, leads.ErrNotFound) {
+		writeError(w, r, http.StatusNotFound, err)
+
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	messages, err := a.store.Messages(r.Context(), lead.Id)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	notes, err := a.store.Notes(r.Context(), lead.Id)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	detail := enquiryDetail{
+		enquirySummary: summarise(lead),
+		Mobile:         lead.Mobile,
+		IpAddress:      lead.IpAddress,
+		Policy:         lead.Policy,
+		Route:          lead.Route,
+		Environment:    lead.Environment,
+		Attachments:    attachments(lead.Attachments),
+		Messages:       make([]message, 0, len(messages)),
+		Notes:          make([]note, 0, len(notes)),
+		UpdatedAt:      lead.UpdatedAt,
+	}
+	if lead.EmailStatusAt.Valid {
+		detail.EmailStatusAt = &lead.EmailStatusAt.Time
+	}
+	for _, stored := range messages {
+		detail.Messages = append(detail.Messages, message{
+			Id:          stored.Id,
+			FromEmail:   stored.FromEmail,
+			FromName:    stored.FromName,
+			Subject:     stored.Subject,
+			Text:        stored.TextBody,
+			Attachments: attachments(stored.Attachments),
+			ReceivedAt:  stored.ReceivedAt,
+		})
+	}
+
+	for _, stored := range notes {
+		detail.Notes = append(detail.Notes, toNote(stored))
+	}
+
+	writeJSON(w, r, http.StatusOK, detail)
+}
+
+func parseSearchQuery(values url.Values) (leads.SearchQuery, error) {
+	query := leads.SearchQuery{
+		Text:     values.Get("q"),
+		Status:   enums.ProcessingStatus(values.Get("status")),
+		State:    enums.LeadState(values.Get("state")),
+		Assignee: values.Get("assignee"),
+		Limit:    DEFAULT_LIMIT,
+	}
+
+	if _, ok := leads.Transitions[query.State]; query.State != "" && !ok {
+		return query, fmt.Errorf("invalid state %q", query.State)
+	}
+
+	if query.Status != "" && !slices.Contains(statuses, query.Status) {
+		return query, fmt.Errorf("invalid status %q", query.Status)
+	}
+
+	var err error
+	if limit := values.Get("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil || query.Limit < 1 || query.Limit > leads.MAX_SEARCH_LIMIT {
+			return query, fmt.Errorf("limit must be between 1 and %d", leads.MAX_SEARCH_LIMIT)
+		}
+	}
+
+	if offset := values.Get("offset"); offset != "" {
+		query.Offset, err = strconv.Atoi(offset)
+		if err != nil || query.Offset < 0 {
+			return query, errors.New("offset must be 0 or more")
+		}
+	}
+
+	if query.From, err = parseTime(values.Get("from"), false); err != nil {
+		return query, fmt.Errorf("invalid from: %w", err)
+	}
+
+	if query.To, err = parseTime(values.Get("to"), true); err != nil {
+		return query, fmt.Errorf("invalid to: %w", err)
+	}
+
+	return query, nil
+}
+
+// parseTime accepts RFC 3339 or a UTC date, endOfDay moves a date to the
+// start of the next day so the whole day is included
+func parseTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not RFC 3339 or YYYY-MM-DD", value)
+	}
+
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t, nil
+}
+
+func summarise(lead *leads.Lead) enquirySummary {
+	return enquirySummary{
+		Id:          lead.Id,
+		Email:       lead.Email,
+		FirstName:   lead.FirstName,
+		LastName:    lead.LastName,
+		Enquiry:     lead.Enquiry,
+		Category:    lead.Category,
+		Country:     lead.Country,
+		Status:      lead.ProcessingStatus,
+		State:       lead.State,
+		Assignee:    lead.Assignee,
+		EmailStatus: lead.EmailStatus,
+		CreatedAt:   lead.CreatedAt.UTC(),
+	}
+}
+
+func attachments(stored []leads.Attachment) []attachment {
+	converted := make([]attachment, 0, len(stored))
+	for _, a := range stored {
+		converted = append(converted, attachment{
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+			Backend:     a.Backend,
+			Link:        a.Link,
+		})
+	}
+
+	return converted
+}
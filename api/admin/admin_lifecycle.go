@@ -0,0 +1,173 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	enums "skulpture/landing/enums"
+	"skulpture/landing/leads"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const MAX_NOTE_LENGTH = 10_000
+
+type note struct {
+	Id        string    `json:"id"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type auditEntry struct {
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type auditLog struct {
+	Entries []auditEntry `json:"entries"`
+}
+
+// enquiryUpdate leaves fields that aren't set unchanged, an empty
+// assignee unassigns
+type enquiryUpdate struct {
+	State    *enums.LeadState `json:"state"`
+	Assignee *string          `json:"assignee"`
+}
+
+type noteRequest struct {
+	Body string `json:"body"`
+}
+
+func (a *api) updateEnquiry(w http.ResponseWriter, r *http.Request) {
+	var update enquiryUpdate
+	if err := decode(r, &update); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+
+		return
+	}
+
+	if update.State != nil {
+		if _, ok := leads.Transitions[*update.State]; !ok {
+			writeError(w, r, http.StatusBadRequest, fmt.Errorf("invalid state %q", *update.State))
+
+			return
+		}
+	}
+
+	id := chi.URLParam(r, "id")
+
+	if update.Assignee != nil {
+		assignee := strings.TrimSpace(*update.Assignee)
+		update.Assignee = &assignee
+	}
+
+	lead, err := a.store.Update(r.Context(), id, update.State, update.Assignee, actor(r))
+	switch {
+	case errors.Is(err, leads.ErrNotFound):
+		writeError(w, r, http.StatusNotFound, err)
+
+		return
+	case errors.Is(err, leads.ErrInvalidTransition):
+		writeError(w, r, http.StatusConflict, err)
+
+		return
+	case err != nil:
+		writeError(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	a.mirrorLifecycle(r, lead)
+
+	a.writeEnquiry(w, r, id)
+}
+
+// mirrorLifecycle updates the lead's sheet row, the database is the source of
+// truth so a failure is only logged
+func (a *api) mirrorLifecycle(r *http.Request, lead *leads.Lead) {
+	if a.mirror == nil {
+		return
+	}
+
+	if err := a.mirror.SetLifecycle(r.Context(), lead); err != nil {
+		slog.ErrorContext(r.Context(), "error", "lifecycle mirror", err.Error(), "lead", lead.Id)
+	}
+}
+
+func (a *api) addNote(w http.ResponseWriter, r *http.Request) {
+	var request noteRequest
+	if err := decode(r, &request); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+
+		return
+	}
+
+	body := strings.TrimSpace(request.Body)
+	if body == "" || len(body) > MAX_NOTE_LENGTH {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("body must be 1 to %d characters", MAX_NOTE_LENGTH))
+
+		return
+	}
+
+	created, err := a.store.AddNote(r.Context(), chi.URLParam(r, "id"), actor(r), body)
+	if errors.Is(err, leads.ErrNotFound) {
+		writeError(w, r, http.StatusNotFound, err)
+
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, toNote(*created))
+}
+
+func (a *api) getAuditLog(w http.ResponseWriter, r *http.Request) {
+	entries, err := a.store.AuditLog(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	log := auditLog{Entries: make([]auditEntry, 0, len(entries))}
+	for _, entry := range entries {
+		log.Entries = append(log.Entries, auditEntry{
+			Actor:     entry.Actor,
+			Action:    entry.Action,
+			Detail:    entry.Detail,
+			CreatedAt: entry.CreatedAt,
+		})
+	}
+
+	writeJSON(w, r, http.StatusOK, log)
+}
+
+func toNote(stored leads.Note) note {
+	return note{
+		Id:        stored.Id,
+		Author:    stored.Author,
+		Body:      stored.Body,
+		CreatedAt: stored.CreatedAt,
+	}
+}
+
+func decode(r *http.Request, body any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 64<<10))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(body); err != nil {
+		return fmt.Errorf("invalid body: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package admin
+
+import (
+	"net/http"
+	"skulpture/landing/storage"
+	"time"
+)
+
+type orphanResponse struct {
+	Id        string    `json:"id"`
+	Filename  string    `json:"filename"`
+	Lead      string    `json:"lead"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type reconcileResponse struct {
+	Checked int              `json:"checked"`
+	Orphans []orphanResponse `json:"orphans"`
+	Deleted int              `json:"deleted"`
+}
+
+// reconcileOrphans runs the reconciler now, ?dryRun=true only reports
+// orphans even when they're configured to be deleted
+func (a *api) reconcileOrphans(w http.ResponseWriter, r *http.Request) {
+	reportOnly := !a.orphans.Delete || r.URL.Query().Get("dryRun") == "true"
+
+	report, err := a.orphans.Reconcile(r.Context(), reportOnly)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	response := reconcileResponse{Checked: report.Checked, Orphans: make([]orphanResponse, 0, len(report.Orphans)), Deleted: report.Deleted}
+	for _, file := range report.Orphans {
+		response.Orphans = append(response.Orphans, orphanResponse{
+			Id:        file.Id,
+			Filename:  file.Filename,
+			Lead:      file.Metadata[storage.META_LEAD],
+			CreatedAt: file.CreatedAt,
+		})
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
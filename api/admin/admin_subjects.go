@@ -0,0 +1,96 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"skulpture/landing/privacy"
+	"time"
+)
+
+// subjectRequest takes the email in the body, keeping it out of access logs
+type subjectRequest struct {
+	Email string `json:"email"`
+}
+
+type erasureResponse struct {
+	Leads          []string `json:"leads"`
+	Files          int      `json:"files"`
+	SheetRows      int      `json:"sheetRows"`
+	Deliveries     int64    `json:"webhookDeliveries"`
+	RemovalRequest string   `json:"removalRequest"`
+	Warnings       []string `json:"warnings"`
+}
+
+// exportSubject responds with a zip of everything held about the email.
+// It's built in a temporary file first so a failure part way through is an
+// error rather than a truncated download
+func (a *api) exportSubject(w http.ResponseWriter, r *http.Request) {
+	var request subjectRequest
+	if err := decode(r, &request); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+
+		return
+	}
+
+	file, err := os.CreateTemp("", "subject-export-*.zip")
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := a.subjects.Export(r.Context(), request.Email, file, actor(r)); err != nil {
+		writeError(w, r, subjectErrorStatus(err), err)
+
+		return
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="subject-export-%s.zip"`, time.Now().UTC().Format("20060102-150405")))
+	w.Header().Set("Cache-Control", "no-store")
+	io.Copy(w, file)
+}
+
+func (a *api) eraseSubject(w http.ResponseWriter, r *http.Request) {
+	var request subjectRequest
+	if err := decode(r, &request); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+
+		return
+	}
+
+	erasure, err := a.subjects.Erase(r.Context(), request.Email, actor(r))
+	if err != nil {
+		writeError(w, r, subjectErrorStatus(err), err)
+
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, erasureResponse{
+		Leads:          erasure.Leads,
+		Files:          erasure.Files,
+		SheetRows:      erasure.SheetRows,
+		Deliveries:     erasure.Deliveries,
+		RemovalRequest: erasure.RemovalRequest,
+		Warnings:       erasure.Warnings,
+	})
+}
+
+func subjectErrorStatus(err error) int {
+	if errors.Is(err, privacy.ErrInvalidEmail) {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,349 @@
+package admin
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"skulpture/landing/auth"
+	"skulpture/landing/database/databasetest"
+	enums "skulpture/landing/enums"
+	"skulpture/landing/leads"
+	"skulpture/landing/leads/leadstest"
+	"skulpture/landing/orphans"
+	"skulpture/landing/privacy"
+	"skulpture/landing/storage"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func get(t *testing.T, handler http.Handler, target string, body any) int {
+	t.Helper()
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, target, nil))
+
+	if body != nil {
+		if err := json.NewDecoder(res.Body).Decode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return res.Code
+}
+
+func TestEnquiries(t *testing.T) {
+	store := leads.NewSQLiteStore(databasetest.Open(t))
+	ctx := context.Background()
+
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, name := range []string{"Jane", "Joe", "Ann"} {
+		status := enums.ProcessingConfirmed
+		if name == "Ann" {
+			status = enums.ProcessingQuarantined
+		}
+
+		assert.NoError(t, store.Create(ctx, &leads.Lead{
+			Id:               fmt.Sprintf("9b2f8c4e-0000-4000-8000-00000000020%d", i),
+			FirstName:        name,
+			LastName:         "Doe",
+			Email:            fmt.Sprintf("%s@example.com", name),
+			Enquiry:          "A new website",
+			Environment:      string(enums.Test),
+			ProcessingStatus: status,
+			CreatedAt:        created.AddDate(0, 0, i),
+			Attachments: []leads.Attachment{
+				{Id: fmt.Sprintf("brief-%d.pdf", i), Backend: "local", Filename: "brief.pdf", Size: 10, Link: "https://example.com/brief.pdf"},
+			},
+		}))
+	}
+
+	handler := Handler(store, nil, nil, nil)
+
+	tests := []struct {
+		name     string
+		target   string
+		status   int
+		expected []string
+		total    int
+	}{
+		{"newest first", "/enquiries", http.StatusOK, []string{"Ann", "Joe", "Jane"}, 3},
+		{"search", "/enquiries?q=jan", http.StatusOK, []string{"Jane"}, 1},
+		{"status", "/enquiries?status=quarantined", http.StatusOK, []string{"Ann"}, 1},
+		{"dates are inclusive", "/enquiries?from=2026-03-01&to=2026-03-02", http.StatusOK, []string{"Joe", "Jane"}, 2},
+		{"rfc 3339", "/enquiries?from=2026-03-02T12:00:00Z", http.StatusOK, []string{"Ann", "Joe"}, 2},
+		{"page", "/enquiries?limit=1&offset=2", http.StatusOK, []string{"Jane"}, 3},
+		{"invalid status", "/enquiries?status=won", http.StatusBadRequest, nil, 0},
+		{"invalid limit", "/enquiries?limit=1000", http.StatusBadRequest, nil, 0},
+		{"invalid date", "/enquiries?from=yesterday", http.StatusBadRequest, nil, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var list enquiryList
+			status := get(t, handler, test.target, &list)
+			assert.Equal(t, test.status, status)
+
+			if test.status != http.StatusOK {
+				return
+			}
+
+			names := []string{}
+			for _, enquiry := range list.Enquiries {
+				names = append(names, enquiry.FirstName)
+			}
+			assert.Equal(t, test.expected, names)
+			assert.Equal(t, test.total, list.Total)
+		})
+	}
+}
+
+func TestEnquiry(t *testing.T) {
+	store := leads.NewSQLiteStore(databasetest.Open(t))
+	ctx := context.Background()
+
+	lead := &leads.Lead{
+		Id:               "9b2f8c4e-0000-4000-8000-000000000300",
+		FirstName:        "Jane",
+		LastName:         "Doe",
+		Email:            "jane@example.com",
+		Mobile:           "+447700900000",
+		Enquiry:          "A new website",
+		Environment:      string(enums.Test),
+		ProcessingStatus: enums.ProcessingConfirmed,
+		Attachments: []leads.Attachment{
+			{Id: "brief.pdf", Backend: "local", Filename: "brief.pdf", Size: 10, Link: "https://example.com/brief.pdf"},
+		},
+	}
+	assert.NoError(t, store.Create(ctx, lead))
+	assert.NoError(t, store.AddMessage(ctx, &leads.Message{
+		Id:        "inbound-1",
+		LeadId:    lead.Id,
+		FromEmail: "jane@example.com",
+		Subject:   "Re: We've received your enquiry",
+		TextBody:  "Drawings attached",
+		Attachments: []leads.Attachment{
+			{Id: "drawings.pdf", Backend: "local", Filename: "drawings.pdf", Link: "https://example.com/drawings.pdf"},
+		},
+	}))
+
+	handler := Handler(store, nil, nil, nil)
+
+	var detail enquiryDetail
+	assert.Equal(t, http.StatusOK, get(t, handler, "/enquiries/"+lead.Id, &detail))
+	assert.Equal(t, "+447700900000", detail.Mobile)
+	assert.Equal(t, []attachment{{Filename: "brief.pdf", Size: 10, Backend: "local", Link: "https://example.com/brief.pdf"}}, detail.Attachments)
+	assert.Len(t, detail.Messages, 1)
+	assert.Equal(t, "https://example.com/drawings.pdf", detail.Messages[0].Attachments[0].Link)
+
+	var notFound errorResponse
+	assert.Equal(t, http.StatusNotFound, get(t, handler, "/enquiries/missing", &notFound))
+	assert.Equal(t, leads.ErrNotFound.Error(), notFound.Error)
+}
+
+type recordingMirror struct {
+	leads []leads.Lead
+}
+
+func (m *recordingMirror) SetLifecycle(ctx context.Context, lead *leads.Lead) error {
+	m.leads = append(m.leads, *lead)
+
+	return nil
+}
+
+func send(t *testing.T, handler http.Handler, method string, target string, body string, response any) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{Subject: "ops@skulpture.xyz", Method: auth.METHOD_API_KEY}))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if response != nil {
+		if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return res.Code
+}
+
+func TestEnquiryLifecycle(t *testing.T) {
+	store := leads.NewSQLiteStore(databasetest.Open(t))
+	ctx := context.Background()
+
+	lead := &leads.Lead{
+		Id:               "9b2f8c4e-0000-4000-8000-000000000400",
+		FirstName:        "Jane",
+		LastName:         "Doe",
+		Email:            "jane@example.com",
+		Enquiry:          "A new website",
+		Environment:      string(enums.Test),
+		ProcessingStatus: enums.ProcessingConfirmed,
+	}
+	assert.NoError(t, store.Create(ctx, lead))
+
+	mirror := &recordingMirror{}
+	handler := Handler(store, mirror, nil, nil)
+	target := "/enquiries/" + lead.Id
+
+	tests := []struct {
+		name     string
+		body     string
+		status   int
+		state    enums.LeadState
+		assignee string
+	}{
+		{"triage and assign", `{"state":"triaged","assignee":"sam"}`, http.StatusOK, enums.LeadTriaged, "sam"},
+		{"invalid transition", `{"state":"won","assignee":"alex"}`, http.StatusConflict, "", ""},
+		{"not assigned by a failed transition", `{}`, http.StatusOK, enums.LeadTriaged, "sam"},
+		{"unknown state", `{"state":"closed"}`, http.StatusBadRequest, "", ""},
+		{"unknown field", `{"owner":"sam"}`, http.StatusBadRequest, "", ""},
+		{"unassign", `{"assignee":""}`, http.StatusOK, enums.LeadTriaged, ""},
+		{"contacted", `{"state":"contacted"}`, http.StatusOK, enums.LeadContacted, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var detail enquiryDetail
+			status := send(t, handler, http.MethodPatch, target, test.body, &detail)
+			assert.Equal(t, test.status, status)
+
+			if test.status != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, test.state, detail.State)
+			assert.Equal(t, test.assignee, detail.Assignee)
+		})
+	}
+
+	assert.Len(t, mirror.leads, 4)
+	assert.Equal(t, enums.LeadContacted, mirror.leads[3].State)
+
+	assert.Equal(t, http.StatusNotFound, send(t, handler, http.MethodPatch, "/enquiries/missing", `{"state":"triaged"}`, nil))
+
+	var created note
+	assert.Equal(t, http.StatusCreated, send(t, handler, http.MethodPost, target+"/notes", `{"body":"Called, wants a quote"}`, &created))
+	assert.Equal(t, "ops@skulpture.xyz", created.Author)
+	assert.Equal(t, http.StatusBadRequest, send(t, handler, http.MethodPost, target+"/notes", `{"body":" "}`, nil))
+	assert.Equal(t, http.StatusNotFound, send(t, handler, http.MethodPost, "/enquiries/missing/notes", `{"body":"hello"}`, nil))
+
+	var detail enquiryDetail
+	assert.Equal(t, http.StatusOK, get(t, handler, target, &detail))
+	assert.Equal(t, []note{created}, detail.Notes)
+
+	var log auditLog
+	assert.Equal(t, http.StatusOK, get(t, handler, target+"/audit", &log))
+
+	actions := []string{}
+	for _, entry := range log.Entries {
+		assert.Equal(t, "ops@skulpture.xyz", entry.Actor)
+		actions = append(actions, entry.Action+" "+entry.Detail)
+	}
+	assert.Equal(t, []string{
+		"state new → triaged",
+		"assign (none) → sam",
+		"assign sam → (none)",
+		"state triaged → contacted",
+		"note " + created.Id,
+	}, actions)
+
+	var list enquiryList
+	assert.Equal(t, http.StatusOK, get(t, handler, "/enquiries?state=contacted", &list))
+	assert.Equal(t, 1, list.Total)
+	assert.Equal(t, http.StatusOK, get(t, handler, "/enquiries?assignee=sam", &list))
+	assert.Equal(t, 0, list.Total)
+	assert.Equal(t, http.StatusBadRequest, get(t, handler, "/enquiries?state=closed", nil))
+}
+
+func TestSubjects(t *testing.T) {
+	store := leads.NewSQLiteStore(databasetest.Open(t))
+	ctx := context.Background()
+
+	files, err := storage.NewLocalStore(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := files.Put(ctx, storage.Object{
+		Filename: "brief.pdf",
+		Metadata: map[string]string{storage.META_LEAD: "9b2f8c4e-0000-4000-8000-000000000800", storage.META_EMAIL: "jane@example.com"},
+		Body:     strings.NewReader("%PDF"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, store.Create(ctx, &leads.Lead{
+		Id:               "9b2f8c4e-0000-4000-8000-000000000800",
+		FirstName:        "Jane",
+		LastName:         "Doe",
+		Email:            "jane@example.com",
+		Enquiry:          "A new website",
+		Environment:      string(enums.Test),
+		ProcessingStatus: enums.ProcessingConfirmed,
+		Attachments:      []leads.Attachment{{Id: stored.Id, Backend: "local", Filename: "brief.pdf"}},
+	}))
+
+	handler := Handler(store, nil, privacy.New(privacy.Config{Store: store, Files: files, Backend: "local"}), nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/subjects/export", strings.NewReader(`{"email":"jane@example.com"}`))
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "application/zip", res.Header().Get("Content-Type"))
+
+	archive, err := zip.NewReader(bytes.NewReader(res.Body.Bytes()), int64(res.Body.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, archive.File, 2)
+
+	assert.Equal(t, http.StatusBadRequest, send(t, handler, http.MethodPost, "/subjects/export", `{"email":"jane"}`, nil))
+
+	var erasure erasureResponse
+	assert.Equal(t, http.StatusOK, send(t, handler, http.MethodPost, "/subjects/erase", `{"email":"jane@example.com"}`, &erasure))
+	assert.Equal(t, []string{"9b2f8c4e-0000-4000-8000-000000000800"}, erasure.Leads)
+	assert.Equal(t, 1, erasure.Files)
+
+	assert.Equal(t, http.StatusNotFound, get(t, handler, "/enquiries/9b2f8c4e-0000-4000-8000-000000000800", nil))
+
+	var log auditLog
+	assert.Equal(t, http.StatusOK, get(t, handler, "/enquiries/9b2f8c4e-0000-4000-8000-000000000800/audit", &log))
+	assert.Equal(t, "ops@skulpture.xyz", log.Entries[len(log.Entries)-1].Actor)
+	assert.Equal(t, leads.AUDIT_ERASE, log.Entries[len(log.Entries)-1].Action)
+}
+
+func TestReconcileOrphans(t *testing.T) {
+	f := leadstest.New(t)
+	f.Put(t, "brief.pdf", "%PDF", map[string]string{storage.META_LEAD: "never-recorded", storage.META_ENVIRONMENT: string(enums.Test)})
+
+	store := f.Store
+	reconciler, err := orphans.New(orphans.Config{Environment: string(enums.Test), Store: store, Files: f.Files, Sheets: f.Sheets, Delete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := Handler(store, nil, nil, reconciler)
+
+	var report reconcileResponse
+	assert.Equal(t, http.StatusOK, send(t, handler, http.MethodPost, "/orphans/reconcile?dryRun=true", "", &report))
+	assert.Equal(t, 1, report.Checked)
+	assert.Len(t, report.Orphans, 1)
+	assert.Equal(t, "never-recorded", report.Orphans[0].Lead)
+	assert.Equal(t, 0, report.Deleted)
+
+	assert.Equal(t, http.StatusOK, send(t, handler, http.MethodPost, "/orphans/reconcile", "", &report))
+	assert.Equal(t, 1, report.Deleted)
+
+	assert.Equal(t, http.StatusOK, send(t, handler, http.MethodPost, "/orphans/reconcile", "", &report))
+	assert.Equal(t, 0, report.Checked)
+
+	assert.Equal(t, http.StatusNotFound, send(t, Handler(store, nil, nil, nil), http.MethodPost, "/orphans/reconcile", "", nil))
+}
@@ -0,0 +1,110 @@
+package admission
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Budget is a non-blocking counting semaphore, requests that don't fit
+// are turned away instead of queueing and holding on to memory
+type Budget struct {
+	name       string
+	mu         sync.Mutex
+	used       int64
+	capacity   int64
+	underflows int64
+}
+
+func NewBudget(name string, capacity int64) *Budget {
+	return &Budget{
+		name:     name,
+		capacity: capacity,
+	}
+}
+
+func (b *Budget) TryAcquire(n int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.used+n > b.capacity {
+		return false
+	}
+
+	b.used += n
+
+	return true
+}
+
+func (b *Budget) Release(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// more released than was acquired is a bug in the caller, e.g. a double
+	// release, which would otherwise quietly raise the capacity
+	if n > b.used {
+		b.underflows++
+		slog.Error("underflow", "admission", b.name, "used", b.used, "released", n)
+
+		b.used = 0
+
+		return
+	}
+
+	b.used -= n
+}
+
+// Underflows is how many releases were for more than had been acquired
+func (b *Budget) Underflows() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.underflows
+}
+
+func (b *Budget) Used() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.used
+}
+
+type Controller struct {
+	Parses     *Budget
+	Bytes      *Budget
+	Jobs       *Budget
+	RetryAfter time.Duration
+}
+
+// Reject responds with 503 and a Retry-After hint
+func (c *Controller) Reject(w http.ResponseWriter, r *http.Request, budget *Budget) {
+	slog.WarnContext(r.Context(), "rejected", "admission", budget.name, "used", budget.Used(), "capacity", budget.capacity)
+
+	w.Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(c.RetryAfter.Seconds()))))
+	http.Error(w, "Server is busy, please try again shortly", http.StatusServiceUnavailable)
+}
+
+// Middleware reserves the request's size from the in-flight byte budget until
+// the handler returns. Requests without a content length reserve the maximum
+func (c *Controller) Middleware(maxRequestSize int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			size := maxRequestSize
+			if r.ContentLength >= 0 {
+				size = min(r.ContentLength, maxRequestSize)
+			}
+
+			if !c.Bytes.TryAcquire(size) {
+				c.Reject(w, r, c.Bytes)
+
+				return
+			}
+			defer c.Bytes.Release(size)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package admission
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget(t *testing.T) {
+	budget := NewBudget("test", 10)
+
+	assert.True(t, budget.TryAcquire(6))
+	assert.False(t, budget.TryAcquire(5))
+	assert.True(t, budget.TryAcquire(4))
+
+	budget.Release(6)
+	assert.Equal(t, int64(4), budget.Used())
+	assert.True(t, budget.TryAcquire(5))
+	assert.Equal(t, int64(0), budget.Underflows())
+}
+
+func TestBudgetCountsUnderflows(t *testing.T) {
+	budget := NewBudget("test", 10)
+
+	assert.True(t, budget.TryAcquire(3))
+	budget.Release(3)
+	budget.Release(3)
+
+	assert.Equal(t, int64(0), budget.Used())
+	assert.Equal(t, int64(1), budget.Underflows())
+
+	assert.True(t, budget.TryAcquire(10))
+	assert.False(t, budget.TryAcquire(1), "an underflow doesn't raise the capacity")
+}
+
+func TestMiddlewareRejectsWhenBytesExhausted(t *testing.T) {
+	controller := &Controller{
+		Bytes:      NewBudget("bytes", 10),
+		RetryAfter: 1500 * time.Millisecond,
+	}
+
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	handler := controller.Middleware(100)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	}))
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("12345678")))
+	<-entered
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("12345")))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	close(release)
+	assert.Eventually(t, func() bool { return controller.Bytes.Used() == 0 }, time.Second, 10*time.Millisecond)
+}
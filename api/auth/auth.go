@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Principal is who made an authenticated request, recorded against changes
+type Principal struct {
+	// Subject is the API key's name or the identity provider's email or subject
+	Subject string
+	Method  string
+}
+
+const (
+	METHOD_API_KEY = "api_key"
+	METHOD_OIDC    = "oidc"
+)
+
+type Authenticator interface {
+	// Authenticate returns ErrInvalidToken for tokens it doesn't accept
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom is the authenticated principal, nil outside Middleware
+func PrincipalFrom(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+
+	return principal
+}
+
+// Middleware requires a bearer token accepted by one of the authenticators
+func Middleware(authenticators ...Authenticator) func(http.Handler) http.Handler {
+	return middleware(bearerToken, `Bearer realm="admin"`, authenticators)
+}
+
+// BasicMiddleware is Middleware for browsers, which prompt for basic auth
+// credentials. The password is the token, the username is ignored
+func BasicMiddleware(authenticators ...Authenticator) func(http.Handler) http.Handler {
+	return middleware(basicToken, `Basic realm="admin", charset="UTF-8"`, authenticators)
+}
+
+func middleware(token func(r *http.Request) (string, bool), challenge string, authenticators []Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := token(r)
+			if !ok {
+				unauthorized(w, challenge)
+
+				return
+			}
+
+			principal, err := Authenticate(r.Context(), token, authenticators...)
+			if errors.Is(err, ErrInvalidToken) {
+				slog.DebugContext(r.Context(), "unauthorized", "auth", err.Error())
+				unauthorized(w, challenge)
+
+				return
+			}
+			if err != nil {
+				slog.ErrorContext(r.Context(), "error", "auth", err.Error())
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// Authenticate tries each authenticator in turn
+func Authenticate(ctx context.Context, token string, authenticators ...Authenticator) (*Principal, error) {
+	var errs []error
+	for _, authenticator := range authenticators {
+		principal, err := authenticator.Authenticate(ctx, token)
+		if errors.Is(err, ErrInvalidToken) {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		return principal, err
+	}
+
+	return nil, errors.Join(append([]error{ErrInvalidToken}, errs...)...)
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
+
+func basicToken(r *http.Request) (string, bool) {
+	_, password, ok := r.BasicAuth()
+	if !ok || strings.TrimSpace(password) == "" {
+		return "", false
+	}
+
+	return strings.TrimSpace(password), true
+}
+
+func unauthorized(w http.ResponseWriter, challenge string) {
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+// ErrNoAllowedEmails is returned without an allowlist, as every account the
+// issuer vouches for, e.g. any Google account, would be an admin
+var ErrNoAllowedEmails = errors.New("oidc requires allowed emails or @domains")
+
+// OIDC authenticates identity provider issued JWTs, e.g. Google or Entra ID
+// tokens for our client. The provider is discovered on first use so an
+// identity provider outage doesn't stop the server starting
+type OIDC struct {
+	issuer   string
+	audience string
+	allowed  []string
+
+	mu       sync.Mutex
+	verifier *oidc.IDTokenVerifier
+}
+
+type claims struct {
+	Email         string `json:"email"`
+	EmailVerified *bool  `json:"email_verified"`
+}
+
+// NewOIDC accepts tokens for audience from the allowed emails or @domains,
+// at least one is required
+func NewOIDC(issuer string, audience string, allowed []string) (*OIDC, error) {
+	trimmed := []string{}
+	for _, value := range allowed {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" && value != "@" {
+			trimmed = append(trimmed, value)
+		}
+	}
+
+	if len(trimmed) == 0 {
+		return nil, ErrNoAllowedEmails
+	}
+
+	return &OIDC{
+		issuer:   issuer,
+		audience: audience,
+		allowed:  trimmed,
+	}, nil
+}
+
+func (o *OIDC) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	// API keys and other opaque tokens aren't JWTs
+	if strings.Count(token, ".") != 2 {
+		return nil, ErrInvalidToken
+	}
+
+	verifier, err := o.getVerifier(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	var claims claims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if claims.EmailVerified != nil && !*claims.EmailVerified {
+		claims.Email = ""
+	}
+
+	if !o.isAllowed(claims.Email) {
+		return nil, fmt.Errorf("%w: %q is not allowed", ErrInvalidToken, claims.Email)
+	}
+
+	subject := claims.Email
+	if subject == "" {
+		subject = idToken.Subject
+	}
+
+	return &Principal{Subject: subject, Method: METHOD_OIDC}, nil
+}
+
+func (o *OIDC) getVerifier(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.verifier != nil {
+		return o.verifier, nil
+	}
+
+	// the provider's key set outlives this request
+	provider, err := oidc.NewProvider(context.WithoutCancel(ctx), o.issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+
+	o.verifier = provider.Verifier(&oidc.Config{ClientID: o.audience})
+
+	return o.verifier, nil
+}
+
+func (o *OIDC) isAllowed(email string) bool {
+	if email == "" {
+		return false
+	}
+
+	email = strings.ToLower(email)
+	_, domain, _ := strings.Cut(email, "@")
+	for _, allowed := range o.allowed {
+		if allowed == email || allowed == "@"+domain {
+			return true
+		}
+	}
+
+	return false
+}
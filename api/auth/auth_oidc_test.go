@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockIdentityProvider serves discovery and a key set, tokens are signed
+// with its key
+type mockIdentityProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+}
+
+func newMockIdentityProvider(t *testing.T) *mockIdentityProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &mockIdentityProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                idp.server.URL,
+			"jwks_uri":                              idp.server.URL + "/jwks",
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"}},
+		})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func (idp *mockIdentityProvider) token(t *testing.T, claims map[string]any) string {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: idp.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := map[string]any{
+		"iss": idp.server.URL,
+		"aud": "landing-admin",
+		"sub": "user-1",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for key, value := range claims {
+		payload[key] = value
+	}
+
+	body, _ := json.Marshal(payload)
+	signed, err := signer.Sign(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := signed.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestOIDC(t *testing.T) {
+	idp := newMockIdentityProvider(t)
+	authenticator, err := NewOIDC(idp.server.URL, "landing-admin", []string{"@skulpture.xyz", " Contractor@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		claims   map[string]any
+		expected *Principal
+	}{
+		{"allowed domain", map[string]any{"email": "Jane@Skulpture.xyz", "email_verified": true}, &Principal{Subject: "Jane@Skulpture.xyz", Method: METHOD_OIDC}},
+		{"allowed email", map[string]any{"email": "contractor@example.com"}, &Principal{Subject: "contractor@example.com", Method: METHOD_OIDC}},
+		{"other domain", map[string]any{"email": "jane@example.com"}, nil},
+		{"unverified email", map[string]any{"email": "jane@skulpture.xyz", "email_verified": false}, nil},
+		{"other audience", map[string]any{"email": "jane@skulpture.xyz", "aud": "another-app"}, nil},
+		{"expired", map[string]any{"email": "jane@skulpture.xyz", "exp": time.Now().Add(-time.Minute).Unix()}, nil},
+		{"other issuer", map[string]any{"email": "jane@skulpture.xyz", "iss": "https://accounts.example.com"}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(context.Background(), idp.token(t, test.claims))
+			if test.expected == nil {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, principal)
+			}
+		})
+	}
+
+	_, err = authenticator.Authenticate(context.Background(), "sk_not_a_jwt")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestOIDCDiscoveryFailure(t *testing.T) {
+	idp := newMockIdentityProvider(t)
+	token := idp.token(t, nil)
+	idp.server.Close()
+
+	authenticator, err := NewOIDC(idp.server.URL, "landing-admin", []string{"@skulpture.xyz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = authenticator.Authenticate(context.Background(), token)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidToken, "an outage isn't the caller's fault")
+}
+
+func TestOIDCRequiresAllowedEmails(t *testing.T) {
+	for _, allowed := range [][]string{nil, {}, {""}, {" ", "@"}} {
+		_, err := NewOIDC("https://accounts.google.com", "landing-admin", allowed)
+		assert.ErrorIs(t, err, ErrNoAllowedEmails, allowed)
+	}
+}
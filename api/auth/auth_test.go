@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"skulpture/landing/database/databasetest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestKeys(t *testing.T) *APIKeys {
+	t.Helper()
+
+	db := databasetest.Open(t)
+
+	return NewAPIKeys(db)
+}
+
+func TestAPIKeys(t *testing.T) {
+	keys := openTestKeys(t)
+	ctx := context.Background()
+
+	created, key, err := keys.Create(ctx, "zapier")
+	assert.NoError(t, err)
+	assert.Regexp(t, `^sk_[A-Za-z0-9_-]{43}$`, key)
+
+	principal, err := keys.Authenticate(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, &Principal{Subject: "zapier", Method: METHOD_API_KEY}, principal)
+
+	_, err = keys.Authenticate(ctx, key+"x")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	listed, err := keys.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, listed, 1)
+	assert.True(t, listed[0].LastUsedAt.Valid)
+
+	assert.NoError(t, keys.Revoke(ctx, created.Id))
+	assert.ErrorIs(t, keys.Revoke(ctx, created.Id), ErrKeyNotFound)
+
+	_, err = keys.Authenticate(ctx, key)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestMiddleware(t *testing.T) {
+	keys := openTestKeys(t)
+	_, key, err := keys.Create(context.Background(), "dashboard")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := Middleware(keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(PrincipalFrom(r.Context()).Subject))
+	}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{"api key", "Bearer " + key, http.StatusOK},
+		{"lower case scheme", "bearer " + key, http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"basic", "Basic " + key, http.StatusUnauthorized},
+		{"unknown key", "Bearer sk_unknown", http.StatusUnauthorized},
+		{"not a key", "Bearer header.payload.signature", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/enquiries", nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+
+			assert.Equal(t, test.status, res.Code)
+			if test.status == http.StatusOK {
+				assert.Equal(t, "dashboard", res.Body.String())
+			} else {
+				assert.Equal(t, `Bearer realm="admin"`, res.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestBasicMiddleware(t *testing.T) {
+	keys := openTestKeys(t)
+	_, key, err := keys.Create(context.Background(), "dashboard")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := BasicMiddleware(keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(PrincipalFrom(r.Context()).Subject))
+	}))
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		bearer   bool
+		status   int
+	}{
+		{"api key", "jane", key, false, http.StatusOK},
+		{"any username", "", key, false, http.StatusOK},
+		{"missing", "", "", false, http.StatusUnauthorized},
+		{"bearer", "", key, true, http.StatusUnauthorized},
+		{"unknown key", "jane", "sk_unknown", false, http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/dashboard/", nil)
+			if test.bearer {
+				req.Header.Set("Authorization", "Bearer "+test.password)
+			} else if test.password != "" {
+				req.SetBasicAuth(test.username, test.password)
+			}
+
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+
+			assert.Equal(t, test.status, res.Code)
+			if test.status == http.StatusOK {
+				assert.Equal(t, "dashboard", res.Body.String())
+			} else {
+				assert.Equal(t, `Basic realm="admin", charset="UTF-8"`, res.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ATTEMPTS = 3
+const BACKOFF = time.Second
+
+// Webhook payloads differ between Slack (and compatible, e.g. Mattermost)
+// and Discord
+type Format string
+
+const (
+	FormatSlack   Format = "slack"
+	FormatDiscord Format = "discord"
+)
+
+// Enquiry is the summary posted to chat
+type Enquiry struct {
+	Id          string
+	FirstName   string
+	LastName    string
+	Email       string
+	Enquiry     string
+	Attachments int
+	Category    string
+	Environment string
+}
+
+// Alert is a warning for whoever runs the service
+type Alert struct {
+	Title       string
+	Text        string
+	Environment string
+}
+
+type Webhook struct {
+	Url    string
+	Format Format
+}
+
+// Notifier posts new leads to incoming webhooks
+type Notifier struct {
+	client   *http.Client
+	webhooks []Webhook
+	attempts int
+	backoff  time.Duration
+}
+
+// New takes comma separated webhook URLs, Discord webhooks are recognised
+// by their host and everything else is sent in Slack's format
+func New(urls string) (*Notifier, error) {
+	webhooks := []Webhook{}
+	for _, raw := range strings.Split(urls, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		parsed, err := url.Parse(raw)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid chat webhook url %q", raw)
+		}
+
+		format := FormatSlack
+		if host := parsed.Hostname(); host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com") {
+			format = FormatDiscord
+		}
+
+		webhooks = append(webhooks, Webhook{Url: raw, Format: format})
+	}
+
+	return &Notifier{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		webhooks: webhooks,
+		attempts: ATTEMPTS,
+		backoff:  BACKOFF,
+	}, nil
+}
+
+func (n *Notifier) Webhooks() []Webhook {
+	return n.webhooks
+}
+
+// Notify posts to every webhook, a failing webhook doesn't stop the others
+func (n *Notifier) Notify(ctx context.Context, enquiry Enquiry) error {
+	return n.broadcast(ctx, func(format Format) any {
+		return payload(format, enquiry)
+	})
+}
+
+// Alert posts an operational warning, e.g. storage running out
+func (n *Notifier) Alert(ctx context.Context, alert Alert) error {
+	return n.broadcast(ctx, func(format Format) any {
+		return alertPayload(format, alert)
+	})
+}
+
+func (n *Notifier) broadcast(ctx context.Context, build func(format Format) any) error {
+	var errs []error
+	for _, webhook := range n.webhooks {
+		payload, err := json.Marshal(build(webhook.Format))
+		if err != nil {
+			return err
+		}
+
+		if err := n.post(ctx, webhook.Url, payload); err != nil {
+			errs = append(errs, fmt.Errorf("%s webhook: %w", webhook.Format, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+// post retries network errors, rate limits and server errors with
+// exponential backoff, honouring Retry-After when it's longer
+func (n *Notifier) post(ctx context.Context, webhookUrl string, payload []byte) error {
+	var err error
+	for attempt := range n.attempts {
+		err = n.send(ctx, webhookUrl, payload)
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || attempt == n.attempts-1 {
+			break
+		}
+
+		wait := n.backoff << attempt
+		if retryable.retryAfter > wait {
+			wait = retryable.retryAfter
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+
+	return err
+}
+
+func (n *Notifier) send(ctx context.Context, webhookUrl string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookUrl, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+
+		return &retryableError{err: err}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 300 {
+		io.Copy(io.Discard, res.Body)
+
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	err = fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+		seconds, _ := strconv.ParseFloat(res.Header.Get("Retry-After"), 64)
+
+		return &retryableError{err: err, retryAfter: time.Duration(seconds * float64(time.Second))}
+	}
+
+	return err
+}
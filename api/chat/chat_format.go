@@ -0,0 +1,126 @@
+package chat
+
+import (
+	"fmt"
+	enums "skulpture/landing/enums"
+	"strings"
+	"unicode/utf8"
+)
+
+const EXCERPT_LINES = 3
+const EXCERPT_LENGTH = 300
+
+// TITLE_LENGTH is the most characters of a Slack header, Discord allows
+// 256 in an embed title. Longer ones are rejected
+const TITLE_LENGTH = 150
+
+// excerpt is the first few lines of the enquiry
+func excerpt(enquiry string) string {
+	lines := strings.Split(strings.TrimSpace(enquiry), "\n")
+	truncated := len(lines) > EXCERPT_LINES
+	if truncated {
+		lines = lines[:EXCERPT_LINES]
+	}
+
+	text := strings.TrimSpace(strings.Join(lines, "\n"))
+	if utf8.RuneCountInString(text) > EXCERPT_LENGTH {
+		text = string([]rune(text)[:EXCERPT_LENGTH])
+		truncated = true
+	}
+
+	if truncated {
+		text += "…"
+	}
+
+	return text
+}
+
+// title is at most TITLE_LENGTH characters, names have no limit
+func title(enquiry Enquiry) string {
+	title := fmt.Sprintf("New enquiry from %s %s", enquiry.FirstName, enquiry.LastName)
+	if enquiry.Environment != "" && enquiry.Environment != string(enums.Production) {
+		title = fmt.Sprintf("[%s] %s", enquiry.Environment, title)
+	}
+
+	if utf8.RuneCountInString(title) > TITLE_LENGTH {
+		title = string([]rune(title)[:TITLE_LENGTH-1]) + "…"
+	}
+
+	return title
+}
+
+func payload(format Format, enquiry Enquiry) any {
+	if format == FormatDiscord {
+		return discordPayload(enquiry)
+	}
+
+	return slackPayload(enquiry)
+}
+
+// slackEscape escapes the characters Slack treats as control sequences
+// see: https://api.slack.com/reference/surfaces/formatting#escaping
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+func slackPayload(enquiry Enquiry) map[string]any {
+	fields := []map[string]any{
+		{"type": "mrkdwn", "text": fmt.Sprintf("*Email*\n<mailto:%s|%s>", enquiry.Email, slackEscape(enquiry.Email))},
+		{"type": "mrkdwn", "text": fmt.Sprintf("*Attachments*\n%d", enquiry.Attachments)},
+	}
+	if enquiry.Category != "" {
+		fields = append(fields, map[string]any{"type": "mrkdwn", "text": fmt.Sprintf("*Category*\n%s", slackEscape(enquiry.Category))})
+	}
+
+	return map[string]any{
+		"text": slackEscape(title(enquiry)),
+		"blocks": []map[string]any{
+			{"type": "header", "text": map[string]any{"type": "plain_text", "text": title(enquiry)}},
+			{"type": "section", "fields": fields},
+			{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": slackEscape(excerpt(enquiry.Enquiry))}},
+			{"type": "context", "elements": []map[string]any{{"type": "mrkdwn", "text": fmt.Sprintf("Lead %s", enquiry.Id)}}},
+		},
+	}
+}
+
+func discordPayload(enquiry Enquiry) map[string]any {
+	fields := []map[string]any{
+		{"name": "Email", "value": enquiry.Email, "inline": true},
+		{"name": "Attachments", "value": fmt.Sprint(enquiry.Attachments), "inline": true},
+	}
+	if enquiry.Category != "" {
+		fields = append(fields, map[string]any{"name": "Category", "value": enquiry.Category, "inline": true})
+	}
+
+	return map[string]any{
+		"content": title(enquiry),
+		// enquiries are user input, they mustn't ping anyone
+		"allowed_mentions": map[string]any{"parse": []string{}},
+		"embeds": []map[string]any{
+			{
+				"title":       title(enquiry),
+				"description": excerpt(enquiry.Enquiry),
+				"fields":      fields,
+				"footer":      map[string]any{"text": fmt.Sprintf("Lead %s", enquiry.Id)},
+			},
+		},
+	}
+}
+
+func alertPayload(format Format, alert Alert) map[string]any {
+	title := alert.Title
+	if alert.Environment != "" && alert.Environment != string(enums.Production) {
+		title = fmt.Sprintf("[%s] %s", alert.Environment, title)
+	}
+
+	if format == FormatDiscord {
+		return map[string]any{
+			"content":          fmt.Sprintf("**%s**\n%s", title, alert.Text),
+			"allowed_mentions": map[string]any{"parse": []string{}},
+		}
+	}
+
+	return map[string]any{
+		"text": fmt.Sprintf("*%s*\n%s", slackEscape(title), slackEscape(alert.Text)),
+	}
+}
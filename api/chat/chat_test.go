@@ -0,0 +1,189 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var enquiry = Enquiry{
+	Id:          "6f1c0b0e-lead",
+	FirstName:   "Jane",
+	LastName:    "Doe",
+	Email:       "jane@example.com",
+	Enquiry:     "Hi <@channel>,\nline two\nline three\nline four",
+	Attachments: 2,
+	Environment: "development",
+}
+
+func TestNew(t *testing.T) {
+	notifier, err := New(" https://hooks.slack.com/services/T/B/x , https://discord.com/api/webhooks/1/x,")
+	assert.NoError(t, err)
+	assert.Equal(t, []Webhook{
+		{Url: "https://hooks.slack.com/services/T/B/x", Format: FormatSlack},
+		{Url: "https://discord.com/api/webhooks/1/x", Format: FormatDiscord},
+	}, notifier.Webhooks())
+
+	notifier, err = New("")
+	assert.NoError(t, err)
+	assert.Empty(t, notifier.Webhooks())
+
+	_, err = New("hooks.slack.com/services")
+	assert.Error(t, err)
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name     string
+		enquiry  string
+		expected string
+	}{
+		{"short", "Hello", "Hello"},
+		{"first lines", "one\ntwo\nthree\nfour", "one\ntwo\nthree…"},
+		{"long line", strings.Repeat("é", EXCERPT_LENGTH+1), strings.Repeat("é", EXCERPT_LENGTH) + "…"},
+		{"trimmed", "\n  Hello  \n", "Hello"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, excerpt(test.enquiry))
+		})
+	}
+}
+
+func TestTitle(t *testing.T) {
+	long := strings.Repeat("é", TITLE_LENGTH)
+
+	tests := []struct {
+		name     string
+		enquiry  Enquiry
+		expected string
+	}{
+		{"production", Enquiry{FirstName: "Jane", LastName: "Doe", Environment: "production"}, "New enquiry from Jane Doe"},
+		{"other environment", Enquiry{FirstName: "Jane", LastName: "Doe", Environment: "development"}, "[development] New enquiry from Jane Doe"},
+		{"long name", Enquiry{FirstName: "Jane", LastName: long}, "New enquiry from Jane " + long[:2*(TITLE_LENGTH-23)] + "…"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, title(test.enquiry))
+			assert.LessOrEqual(t, utf8.RuneCountInString(title(test.enquiry)), TITLE_LENGTH)
+		})
+	}
+}
+
+func standIn(t *testing.T, statuses ...int) (*httptest.Server, *[]map[string]any, *atomic.Int32) {
+	var calls atomic.Int32
+	payloads := []map[string]any{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(calls.Add(1)) - 1
+
+		var payload map[string]any
+		json.NewDecoder(r.Body).Decode(&payload)
+		payloads = append(payloads, payload)
+
+		if call < len(statuses) {
+			w.WriteHeader(statuses[call])
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &payloads, &calls
+}
+
+func TestNotifySlack(t *testing.T) {
+	server, payloads, _ := standIn(t)
+
+	notifier, _ := New(server.URL)
+	assert.NoError(t, notifier.Notify(context.Background(), enquiry))
+
+	assert.Len(t, *payloads, 1)
+	payload := (*payloads)[0]
+	assert.Equal(t, "[development] New enquiry from Jane Doe", payload["text"])
+
+	encoded, _ := json.Marshal(payload["blocks"])
+	assert.Contains(t, string(encoded), "Hi \\u0026lt;@channel\\u0026gt;,\\nline two\\nline three…", "user input is escaped")
+	assert.Contains(t, string(encoded), "*Attachments*\\n2")
+	assert.Contains(t, string(encoded), "Lead 6f1c0b0e-lead")
+}
+
+func TestNotifyDiscord(t *testing.T) {
+	server, payloads, _ := standIn(t)
+
+	notifier, _ := New(server.URL)
+	notifier.webhooks[0].Format = FormatDiscord
+	assert.NoError(t, notifier.Notify(context.Background(), enquiry))
+
+	payload := (*payloads)[0]
+	assert.Equal(t, map[string]any{"parse": []any{}}, payload["allowed_mentions"])
+
+	embed := payload["embeds"].([]any)[0].(map[string]any)
+	assert.Equal(t, "Hi <@channel>,\nline two\nline three…", embed["description"])
+	assert.Equal(t, "Lead 6f1c0b0e-lead", embed["footer"].(map[string]any)["text"])
+}
+
+func TestAlert(t *testing.T) {
+	server, payloads, _ := standIn(t)
+
+	notifier, _ := New(server.URL + "," + server.URL)
+	notifier.webhooks[1].Format = FormatDiscord
+	alert := Alert{Title: "Storage is 80% full", Text: "8 GB of 10 GB used", Environment: "development"}
+	assert.NoError(t, notifier.Alert(context.Background(), alert))
+
+	assert.Len(t, *payloads, 2)
+	assert.Equal(t, "*[development] Storage is 80% full*\n8 GB of 10 GB used", (*payloads)[0]["text"])
+	assert.Equal(t, "**[development] Storage is 80% full**\n8 GB of 10 GB used", (*payloads)[1]["content"])
+}
+
+func TestNotifyRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		calls    int32
+		err      bool
+	}{
+		{"server error then success", []int{http.StatusBadGateway, http.StatusOK}, 2, false},
+		{"rate limited then success", []int{http.StatusTooManyRequests, http.StatusNoContent}, 2, false},
+		{"gives up", []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}, ATTEMPTS, true},
+		{"client errors aren't retried", []int{http.StatusNotFound}, 1, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, _, calls := standIn(t, test.statuses...)
+
+			notifier, _ := New(server.URL)
+			notifier.backoff = time.Millisecond
+
+			err := notifier.Notify(context.Background(), enquiry)
+			if test.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.calls, calls.Load())
+		})
+	}
+}
+
+func TestNotifyCancelled(t *testing.T) {
+	server, _, calls := standIn(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	notifier, _ := New(server.URL)
+	notifier.backoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, notifier.Notify(ctx, enquiry), context.DeadlineExceeded)
+	assert.Equal(t, int32(1), calls.Load())
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"skulpture/landing/auth"
+	enums "skulpture/landing/enums"
+	"skulpture/landing/leads"
+	"skulpture/landing/privacy"
+	"skulpture/landing/storage"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `usage:
+  landing webhooks list [pending|delivered|failed]
+  landing webhooks replay <delivery id>...
+  landing suppressions list
+  landing suppressions remove <email>...
+  landing apikeys create <name>
+  landing apikeys list
+  landing apikeys revoke <id>...
+  landing subjects export <email> <file.zip>
+  landing subjects erase <email>
+  landing retention purge [--dry-run]
+  landing orphans reconcile [--dry-run]`
+
+// runCommand runs maintenance commands against the configured database,
+// e.g. `docker exec <container> /landing webhooks list failed`
+func runCommand(ctx context.Context, args []string) int {
+	db = createDatabase(ctx)
+	defer db.Close()
+
+	var err error
+	switch {
+	case len(args) >= 2 && args[0] == "webhooks" && args[1] == "list":
+		err = listWebhookDeliveries(ctx, args[2:])
+	case len(args) >= 3 && args[0] == "webhooks" && args[1] == "replay":
+		err = replayWebhookDeliveries(ctx, args[2:])
+	case len(args) == 2 && args[0] == "suppressions" && args[1] == "list":
+		err = listSuppressions(ctx)
+	case len(args) >= 3 && args[0] == "suppressions" && args[1] == "remove":
+		err = removeSuppressions(ctx, args[2:])
+	case len(args) == 3 && args[0] == "apikeys" && args[1] == "create":
+		err = createAPIKey(ctx, args[2])
+	case len(args) == 2 && args[0] == "apikeys" && args[1] == "list":
+		err = listAPIKeys(ctx)
+	case len(args) >= 3 && args[0] == "apikeys" && args[1] == "revoke":
+		err = revokeAPIKeys(ctx, args[2:])
+	case len(args) == 4 && args[0] == "subjects" && args[1] == "export":
+		err = exportSubject(ctx, args[2], args[3])
+	case len(args) == 3 && args[0] == "subjects" && args[1] == "erase":
+		err = eraseSubject(ctx, args[2])
+	case len(args) == 2 && args[0] == "retention" && args[1] == "purge":
+		err = purgeRetention(ctx, false)
+	case len(args) == 3 && args[0] == "retention" && args[1] == "purge" && args[2] == "--dry-run":
+		err = purgeRetention(ctx, true)
+	case len(args) == 2 && args[0] == "orphans" && args[1] == "reconcile":
+		err = reconcileOrphans(ctx, false)
+	case len(args) == 3 && args[0] == "orphans" && args[1] == "reconcile" && args[2] == "--dry-run":
+		err = reconcileOrphans(ctx, true)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		return 1
+	}
+
+	return 0
+}
+
+func listWebhookDeliveries(ctx context.Context, args []string) error {
+	var status enums.DeliveryStatus
+	if len(args) > 0 {
+		status = enums.DeliveryStatus(args[0])
+	}
+
+	dispatcher := createWebhookDispatcher(ctx)
+	deliveries, err := dispatcher.List(ctx, status, 100)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEVENT\tSUBSCRIPTION\tSTATUS\tATTEMPTS\tLAST STATUS\tCREATED\tLAST ERROR")
+	for _, delivery := range deliveries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%s\t%s\n",
+			delivery.Id, delivery.EventType, delivery.Subscription, delivery.Status, delivery.Attempts,
+			delivery.LastStatusCode, delivery.CreatedAt.Format(time.RFC3339), delivery.LastError)
+	}
+
+	return w.Flush()
+}
+
+// replayWebhookDeliveries queues deliveries again, the running server
+// sends them on its next poll
+func replayWebhookDeliveries(ctx context.Context, ids []string) error {
+	dispatcher := createWebhookDispatcher(ctx)
+
+	var errs []error
+	for _, id := range ids {
+		if err := dispatcher.Replay(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+
+			continue
+		}
+
+		fmt.Printf("queued %s\n", id)
+	}
+
+	return errors.Join(errs...)
+}
+
+func listSuppressions(ctx context.Context) error {
+	suppressions, err := leads.NewSQLiteStore(db).Suppressions(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EMAIL\tREASON\tMESSAGE ID\tCREATED")
+	for _, suppression := range suppressions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			suppression.Email, suppression.Reason, suppression.MessageId, suppression.CreatedAt.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
+// removeSuppressions lets confirmations go to an address again,
+// e.g. once a mailbox that bounced has been fixed
+func removeSuppressions(ctx context.Context, emails []string) error {
+	store := leads.NewSQLiteStore(db)
+
+	var errs []error
+	for _, email := range emails {
+		if err := store.Unsuppress(ctx, email); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", email, err))
+
+			continue
+		}
+
+		fmt.Printf("removed %s\n", email)
+	}
+
+	return errors.Join(errs...)
+}
+
+// createAPIKey prints the key once, only its hash is stored
+func createAPIKey(ctx context.Context, name string) error {
+	created, key, err := auth.NewAPIKeys(db).Create(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "created %s (%s), the key won't be shown again\n", created.Name, created.Id)
+	fmt.Println(key)
+
+	return nil
+}
+
+func listAPIKeys(ctx context.Context) error {
+	keys, err := auth.NewAPIKeys(db).List(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tCREATED\tLAST USED\tREVOKED")
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			key.Id, key.Name, key.CreatedAt.Format(time.RFC3339), formatNullTime(key.LastUsedAt), formatNullTime(key.RevokedAt))
+	}
+
+	return w.Flush()
+}
+
+func revokeAPIKeys(ctx context.Context, ids []string) error {
+	keys := auth.NewAPIKeys(db)
+
+	var errs []error
+	for _, id := range ids {
+		if err := keys.Revoke(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+
+			continue
+		}
+
+		fmt.Printf("revoked %s\n", id)
+	}
+
+	return errors.Join(errs...)
+}
+
+// COMMAND_ACTOR is recorded in the audit log for changes made from the CLI
+const COMMAND_ACTOR = "cli"
+
+// createCommandPrivacyService creates what the privacy service works
+// across, as the server would
+func createCommandPrivacyService(ctx context.Context) *privacy.Service {
+	attachmentStore = createAttachmentStore(ctx)
+	notifier = createNotifier(ctx)
+	leadStore = createLeadStore(ctx)
+	webhookDispatcher = createWebhookDispatcher(ctx)
+
+	return createPrivacyService(ctx)
+}
+
+func exportSubject(ctx context.Context, email string, path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = createCommandPrivacyService(ctx).Export(ctx, email, file, COMMAND_ACTOR)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+
+		return err
+	}
+
+	fmt.Printf("exported %s\n", path)
+
+	return nil
+}
+
+func eraseSubject(ctx context.Context, email string) error {
+	erasure, err := createCommandPrivacyService(ctx).Erase(ctx, email, COMMAND_ACTOR)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("erased %d leads, %d files, %d sheet rows, %d webhook deliveries\n",
+		len(erasure.Leads), erasure.Files, erasure.SheetRows, erasure.Deliveries)
+	if erasure.RemovalRequest != "" {
+		fmt.Printf("requested removal from the email provider, request %s\n", erasure.RemovalRequest)
+	}
+	for _, warning := range erasure.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+
+	return nil
+}
+
+// purgeRetention applies GO_ENV's retention policy now, without waiting
+// for the lease
+func purgeRetention(ctx context.Context, dryRun bool) error {
+	attachmentStore = createAttachmentStore(ctx)
+	leadStore = createLeadStore(ctx)
+	webhookDispatcher = createWebhookDispatcher(ctx)
+
+	job := createRetentionJob(ctx)
+	if job == nil {
+		return fmt.Errorf("no retention policy for %s", GO_ENV.Value())
+	}
+	job.DryRun = job.DryRun || dryRun
+
+	result, err := job.Purge(ctx)
+
+	verb := "removed"
+	if result.DryRun {
+		verb = "would remove"
+	}
+	fmt.Printf("%s %d files, %d leads, %d sheet rows, %d webhook deliveries\n",
+		verb, len(result.Files), len(result.Leads), result.SheetRows, result.Deliveries)
+
+	return err
+}
+
+// reconcileOrphans checks for orphans now, deleting them when ORPHAN_ACTION
+// is delete
+func reconcileOrphans(ctx context.Context, dryRun bool) error {
+	attachmentStore = createAttachmentStore(ctx)
+	leadStore = createLeadStore(ctx)
+
+	reconciler := createOrphanReconciler(ctx)
+	if reconciler == nil {
+		return fmt.Errorf("the %s storage backend can't list files", STORAGE_BACKEND.Value())
+	}
+
+	report, err := reconciler.Reconcile(ctx, dryRun || !reconciler.Delete)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tLEAD\tFILENAME\tCREATED")
+	for _, file := range report.Orphans {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", file.Id, file.Metadata[storage.META_LEAD], file.Filename, file.CreatedAt.Format(time.RFC3339))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("checked %d files, %d orphans, %d deleted\n", report.Checked, len(report.Orphans), report.Deleted)
+
+	return nil
+}
+
+func formatNullTime(t sql.NullTime) string {
+	if !t.Valid {
+		return "-"
+	}
+
+	return t.Time.Format(time.RFC3339)
+}
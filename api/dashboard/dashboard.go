@@ -0,0 +1,190 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"skulpture/landing/auth"
+	"skulpture/landing/emails"
+	"skulpture/landing/leads"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Pages are rendered into the "layout" template defined in
+// layout.html.tmpl, each page defines "title" and "content"
+//
+//go:embed templates/*.tmpl
+var files embed.FS
+
+var funcs = map[string]any{
+	"date":  formatDate,
+	"bytes": emails.FormatBytes,
+}
+
+var layout = template.Must(template.New("").Option("missingkey=zero").Funcs(funcs).ParseFS(files, "templates/layout.html.tmpl"))
+
+var pages = map[string]*template.Template{
+	"inbox":      page("inbox"),
+	"lead":       page("lead"),
+	"quarantine": page("quarantine"),
+}
+
+// Release resumes processing a lead approved from quarantine, e.g. sending
+// its confirmation and support notification
+type Release func(ctx context.Context, id string) error
+
+type Config struct {
+	// Path the dashboard is mounted at, links are built from it
+	Path  string
+	Store *leads.SQLiteStore
+	// Mirror is told about lifecycle changes when it's not nil
+	Mirror  leads.Mirror
+	Release Release
+}
+
+type dashboard struct {
+	Config
+}
+
+// Handler serves the staff dashboard, mounted behind auth.BasicMiddleware
+// so browsers prompt for an API key
+func Handler(config Config) http.Handler {
+	d := &dashboard{Config: config}
+	d.Path = strings.TrimSuffix(d.Path, "/")
+
+	r := chi.NewRouter()
+	r.Use(headers, sameOrigin)
+
+	r.Get("/", d.inbox)
+	r.Get("/leads/{id}", d.lead)
+	r.Post("/leads/{id}/state", d.transition)
+	r.Post("/leads/{id}/assignee", d.assign)
+	r.Post("/leads/{id}/notes", d.addNote)
+	r.Get("/quarantine", d.quarantine)
+	r.Post("/leads/{id}/approve", d.approve)
+	r.Post("/leads/{id}/reject", d.reject)
+
+	return r
+}
+
+func page(name string) *template.Template {
+	return template.Must(template.Must(layout.Clone()).ParseFS(files, "templates/"+name+".html.tmpl"))
+}
+
+// view is the model every page is rendered with, Data is the page's own
+type view struct {
+	Path    string
+	Subject string
+	Data    any
+}
+
+func (d *dashboard) render(w http.ResponseWriter, r *http.Request, name string, data any) {
+	model := view{Path: d.Path, Data: data}
+	if principal := auth.PrincipalFrom(r.Context()); principal != nil {
+		model.Subject = principal.Subject
+	}
+
+	// rendered in full first so an error doesn't leave half a page
+	var buf bytes.Buffer
+	if err := pages[name].ExecuteTemplate(&buf, "layout", model); err != nil {
+		d.error(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+func (d *dashboard) error(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "error", "dashboard", err.Error(), "path", r.URL.Path)
+	}
+
+	http.Error(w, err.Error(), status)
+}
+
+// redirect after a form post, so reloading doesn't post it again
+func (d *dashboard) redirect(w http.ResponseWriter, r *http.Request, path string) {
+	http.Redirect(w, r, d.Path+path, http.StatusSeeOther)
+}
+
+// actor is who's making the request, recorded in the audit log
+func actor(r *http.Request) string {
+	if principal := auth.PrincipalFrom(r.Context()); principal != nil {
+		return principal.Subject
+	}
+
+	return "unknown"
+}
+
+func headers(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; img-src 'self'; form-action 'self'; frame-ancestors 'none'")
+		w.Header().Set("Referrer-Policy", "same-origin")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin rejects cross site form posts. Browsers send basic auth
+// credentials with them, so they'd otherwise be forgeable
+func sameOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		if !isSameOrigin(r) {
+			slog.WarnContext(r.Context(), "forbidden", "dashboard", "cross origin request", "origin", r.Header.Get("Origin"))
+			http.Error(w, "Cross origin requests are not allowed", http.StatusForbidden)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isSameOrigin uses Sec-Fetch-Site when the browser sends it, falling back
+// to Origin. Requests with neither aren't from a browser
+func isSameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return parsed.Host == r.Host
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format("2 Jan 2006 15:04")
+}
@@ -0,0 +1,203 @@
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	enums "skulpture/landing/enums"
+	"skulpture/landing/leads"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const PAGE_SIZE = 50
+
+const MAX_NOTE_LENGTH = 10_000
+
+var states = []enums.LeadState{
+	enums.LeadNew,
+	enums.LeadTriaged,
+	enums.LeadContacted,
+	enums.LeadWon,
+	enums.LeadLost,
+	enums.LeadSpam,
+}
+
+type inboxPage struct {
+	Query    leads.SearchQuery
+	States   []enums.LeadState
+	Leads    []leads.Lead
+	Total    int
+	Previous string
+	Next     string
+}
+
+type leadPage struct {
+	Lead        *leads.Lead
+	Transitions []enums.LeadState
+	Reviewable  bool
+	Messages    []leads.Message
+	Notes       []leads.Note
+	AuditLog    []leads.AuditEntry
+}
+
+// inbox lists leads newest first, filtered by ?q=, ?state= and ?assignee=
+func (d *dashboard) inbox(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	offset, _ := strconv.Atoi(values.Get("offset"))
+	query := leads.SearchQuery{
+		Text:     strings.TrimSpace(values.Get("q")),
+		State:    enums.LeadState(values.Get("state")),
+		Assignee: strings.TrimSpace(values.Get("assignee")),
+		Limit:    PAGE_SIZE,
+		Offset:   max(offset, 0),
+	}
+	if _, ok := leads.Transitions[query.State]; !ok {
+		query.State = ""
+	}
+
+	result, err := d.Store.Search(r.Context(), query)
+	if err != nil {
+		d.error(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	page := inboxPage{
+		Query:  query,
+		States: states,
+		Leads:  result.Leads,
+		Total:  result.Total,
+	}
+	if query.Offset > 0 {
+		page.Previous = pageLink(values, max(query.Offset-PAGE_SIZE, 0))
+	}
+	if query.Offset+len(result.Leads) < result.Total {
+		page.Next = pageLink(values, query.Offset+PAGE_SIZE)
+	}
+
+	d.render(w, r, "inbox", page)
+}
+
+func pageLink(values url.Values, offset int) string {
+	link := url.Values{}
+	for key, value := range values {
+		link[key] = value
+	}
+	link.Set("offset", strconv.Itoa(offset))
+
+	return "?" + link.Encode()
+}
+
+func (d *dashboard) lead(w http.ResponseWriter, r *http.Request) {
+	lead, err := d.Store.Get(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, leads.ErrNotFound) {
+		d.error(w, r, http.StatusNotFound, err)
+
+		return
+	}
+	if err != nil {
+		d.error(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	page := leadPage{
+		Lead:        lead,
+		Transitions: leads.Transitions[lead.State],
+		Reviewable:  isReviewable(lead),
+	}
+
+	if page.Messages, err = d.Store.Messages(r.Context(), lead.Id); err != nil {
+		d.error(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	if page.Notes, err = d.Store.Notes(r.Context(), lead.Id); err != nil {
+		d.error(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	if page.AuditLog, err = d.Store.AuditLog(r.Context(), lead.Id); err != nil {
+		d.error(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	d.render(w, r, "lead", page)
+}
+
+func (d *dashboard) transition(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	lead, err := d.Store.Transition(r.Context(), id, enums.LeadState(r.PostFormValue("state")), actor(r))
+	if !d.updated(w, r, lead, err) {
+		return
+	}
+
+	d.redirect(w, r, "/leads/"+id)
+}
+
+func (d *dashboard) assign(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	lead, err := d.Store.Assign(r.Context(), id, strings.TrimSpace(r.PostFormValue("assignee")), actor(r))
+	if !d.updated(w, r, lead, err) {
+		return
+	}
+
+	d.redirect(w, r, "/leads/"+id)
+}
+
+func (d *dashboard) addNote(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	body := strings.TrimSpace(r.PostFormValue("body"))
+	if body == "" || len(body) > MAX_NOTE_LENGTH {
+		d.error(w, r, http.StatusBadRequest, fmt.Errorf("note must be 1 to %d characters", MAX_NOTE_LENGTH))
+
+		return
+	}
+
+	_, err := d.Store.AddNote(r.Context(), id, actor(r), body)
+	if !d.updated(w, r, nil, err) {
+		return
+	}
+
+	d.redirect(w, r, "/leads/"+id+"#notes")
+}
+
+// updated reports whether a change succeeded, writing the error response
+// when it didn't. Changed leads are written to the mirror
+func (d *dashboard) updated(w http.ResponseWriter, r *http.Request, lead *leads.Lead, err error) bool {
+	switch {
+	case errors.Is(err, leads.ErrNotFound):
+		d.error(w, r, http.StatusNotFound, err)
+
+		return false
+	case errors.Is(err, leads.ErrInvalidTransition), errors.Is(err, leads.ErrNotQuarantined):
+		d.error(w, r, http.StatusConflict, err)
+
+		return false
+	case err != nil:
+		d.error(w, r, http.StatusInternalServerError, err)
+
+		return false
+	}
+
+	if lead != nil && d.Mirror != nil {
+		// the database is the source of truth, a failure is only logged
+		if err := d.Mirror.SetLifecycle(r.Context(), lead); err != nil {
+			slog.ErrorContext(r.Context(), "error", "lifecycle mirror", err.Error(), "lead", lead.Id)
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,61 @@
+package dashboard
+
+import (
+	"net/http"
+	enums "skulpture/landing/enums"
+	"skulpture/landing/leads"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type quarantinePage struct {
+	Leads []leads.Lead
+	Total int
+}
+
+// quarantine lists leads held by a geo policy that haven't been reviewed
+func (d *dashboard) quarantine(w http.ResponseWriter, r *http.Request) {
+	result, err := d.Store.Search(r.Context(), leads.SearchQuery{
+		Status: enums.ProcessingQuarantined,
+		State:  enums.LeadNew,
+		Limit:  leads.MAX_SEARCH_LIMIT,
+	})
+	if err != nil {
+		d.error(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	d.render(w, r, "quarantine", quarantinePage{Leads: result.Leads, Total: result.Total})
+}
+
+// approve releases the lead to be processed as if it hadn't been quarantined
+func (d *dashboard) approve(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	lead, err := d.Store.Approve(r.Context(), id, actor(r))
+	if !d.updated(w, r, lead, err) {
+		return
+	}
+
+	if err := d.Release(r.Context(), id); err != nil {
+		d.error(w, r, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	d.redirect(w, r, "/quarantine")
+}
+
+func (d *dashboard) reject(w http.ResponseWriter, r *http.Request) {
+	lead, err := d.Store.Reject(r.Context(), chi.URLParam(r, "id"), actor(r))
+	if !d.updated(w, r, lead, err) {
+		return
+	}
+
+	d.redirect(w, r, "/quarantine")
+}
+
+func isReviewable(lead *leads.Lead) bool {
+	return lead.ProcessingStatus == enums.ProcessingQuarantined && lead.State != enums.LeadSpam
+}
@@ -0,0 +1,164 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"skulpture/landing/auth"
+	"skulpture/landing/database/databasetest"
+	enums "skulpture/landing/enums"
+	"skulpture/landing/leads"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createLeads(t *testing.T, store *leads.SQLiteStore, statuses ...enums.ProcessingStatus) []string {
+	t.Helper()
+
+	ids := []string{}
+	for i, status := range statuses {
+		id := fmt.Sprintf("9b2f8c4e-0000-4000-8000-00000000050%d", i)
+		assert.NoError(t, store.Create(context.Background(), &leads.Lead{
+			Id:               id,
+			FirstName:        fmt.Sprintf("Jane%d", i),
+			LastName:         "Doe",
+			Email:            fmt.Sprintf("jane%d@example.com", i),
+			Enquiry:          "A <b>new</b> website",
+			Environment:      string(enums.Test),
+			ProcessingStatus: status,
+			CreatedAt:        time.Date(2026, 3, 1, 12, i, 0, 0, time.UTC),
+		}))
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func request(handler http.Handler, method string, target string, form url.Values, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{Subject: "ops@skulpture.xyz", Method: auth.METHOD_API_KEY}))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	return res
+}
+
+var sameSite = map[string]string{"Sec-Fetch-Site": "same-origin"}
+
+func TestInbox(t *testing.T) {
+	store := leads.NewSQLiteStore(databasetest.Open(t))
+	createLeads(t, store, enums.ProcessingConfirmed, enums.ProcessingConfirmed)
+	handler := Handler(Config{Path: "/dashboard", Store: store})
+
+	res := request(handler, http.MethodGet, "/", nil, nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+	assert.Equal(t, "no-store", res.Header().Get("Cache-Control"))
+	assert.Contains(t, res.Body.String(), `href="/dashboard/leads/9b2f8c4e-0000-4000-8000-000000000500"`)
+	assert.Contains(t, res.Body.String(), "ops@skulpture.xyz")
+	assert.Less(t, strings.Index(res.Body.String(), "Jane1"), strings.Index(res.Body.String(), "Jane0"))
+
+	res = request(handler, http.MethodGet, "/?q=jane1", nil, nil)
+	assert.Contains(t, res.Body.String(), "Jane1")
+	assert.NotContains(t, res.Body.String(), "Jane0")
+
+	res = request(handler, http.MethodGet, "/?state=won", nil, nil)
+	assert.Contains(t, res.Body.String(), "No enquiries")
+}
+
+func TestLead(t *testing.T) {
+	store := leads.NewSQLiteStore(databasetest.Open(t))
+	ids := createLeads(t, store, enums.ProcessingConfirmed)
+	handler := Handler(Config{Path: "/dashboard", Store: store})
+	target := "/leads/" + ids[0]
+
+	res := request(handler, http.MethodGet, target, nil, nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), "A &lt;b&gt;new&lt;/b&gt; website")
+	assert.Contains(t, res.Body.String(), `value="triaged"`)
+	assert.NotContains(t, res.Body.String(), "Approve")
+
+	assert.Equal(t, http.StatusNotFound, request(handler, http.MethodGet, "/leads/missing", nil, nil).Code)
+
+	tests := []struct {
+		name     string
+		path     string
+		form     url.Values
+		headers  map[string]string
+		status   int
+		location string
+	}{
+		{"transition", "/state", url.Values{"state": {"triaged"}}, sameSite, http.StatusSeeOther, "/dashboard" + target},
+		{"invalid transition", "/state", url.Values{"state": {"won"}}, sameSite, http.StatusConflict, ""},
+		{"assign", "/assignee", url.Values{"assignee": {"sam"}}, map[string]string{"Origin": "http://example.com"}, http.StatusSeeOther, "/dashboard" + target},
+		{"note", "/notes", url.Values{"body": {"Called, wants a quote"}}, sameSite, http.StatusSeeOther, "/dashboard" + target + "#notes"},
+		{"empty note", "/notes", url.Values{"body": {" "}}, sameSite, http.StatusBadRequest, ""},
+		{"cross site", "/state", url.Values{"state": {"spam"}}, map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden, ""},
+		{"cross origin", "/state", url.Values{"state": {"spam"}}, map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := request(handler, http.MethodPost, target+test.path, test.form, test.headers)
+			assert.Equal(t, test.status, res.Code)
+			assert.Equal(t, test.location, res.Header().Get("Location"))
+		})
+	}
+
+	lead, _ := store.Get(context.Background(), ids[0])
+	assert.Equal(t, enums.LeadTriaged, lead.State)
+	assert.Equal(t, "sam", lead.Assignee)
+
+	res = request(handler, http.MethodGet, target, nil, nil)
+	assert.Contains(t, res.Body.String(), "Called, wants a quote")
+	assert.Contains(t, res.Body.String(), "new → triaged")
+}
+
+func TestQuarantine(t *testing.T) {
+	store := leads.NewSQLiteStore(databasetest.Open(t))
+	ids := createLeads(t, store, enums.ProcessingQuarantined, enums.ProcessingQuarantined, enums.ProcessingConfirmed)
+
+	released := []string{}
+	handler := Handler(Config{
+		Path:  "/dashboard",
+		Store: store,
+		Release: func(ctx context.Context, id string) error {
+			released = append(released, id)
+
+			return nil
+		},
+	})
+
+	res := request(handler, http.MethodGet, "/quarantine", nil, nil)
+	assert.Contains(t, res.Body.String(), "Jane0")
+	assert.Contains(t, res.Body.String(), "Jane1")
+	assert.NotContains(t, res.Body.String(), "Jane2")
+
+	res = request(handler, http.MethodPost, "/leads/"+ids[0]+"/approve", nil, sameSite)
+	assert.Equal(t, http.StatusSeeOther, res.Code)
+	assert.Equal(t, "/dashboard/quarantine", res.Header().Get("Location"))
+	assert.Equal(t, []string{ids[0]}, released)
+
+	res = request(handler, http.MethodPost, "/leads/"+ids[1]+"/reject", nil, sameSite)
+	assert.Equal(t, http.StatusSeeOther, res.Code)
+
+	assert.Equal(t, http.StatusConflict, request(handler, http.MethodPost, "/leads/"+ids[0]+"/approve", nil, sameSite).Code)
+	assert.Equal(t, http.StatusConflict, request(handler, http.MethodPost, "/leads/"+ids[2]+"/reject", nil, sameSite).Code)
+	assert.Equal(t, []string{ids[0]}, released)
+
+	res = request(handler, http.MethodGet, "/quarantine", nil, nil)
+	assert.Contains(t, res.Body.String(), "Nothing to review")
+
+	lead, _ := store.Get(context.Background(), ids[1])
+	assert.Equal(t, enums.LeadSpam, lead.State)
+}
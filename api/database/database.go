@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Open opens the SQLite database at path and applies pending migrations
+func Open(ctx context.Context, dbPath string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+	// sortable and parsed back into time.Time for TIMESTAMP columns
+	params.Add("_time_format", "sqlite")
+	// replicas share the file, take the write lock up front
+	// rather than failing to upgrade a read lock mid transaction
+	params.Add("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?%s", dbPath, params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Migrate(ctx, db); err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Migrate applies migrations/NNNN_name.sql files in order, each in its own
+// transaction, recording the applied version in schema_migrations
+func Migrate(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return err
+	}
+
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".sql")
+
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return fmt.Errorf("migration %s: invalid version: %w", file, err)
+		}
+
+		if err := apply(ctx, db, version, name, file); err != nil {
+			return fmt.Errorf("migration %s: %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+func apply(ctx context.Context, db *sql.DB, version int, name string, file string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var applied bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)", version).Scan(&applied)
+	if err != nil {
+		return err
+	}
+
+	if applied {
+		return nil
+	}
+
+	statements, err := migrations.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, string(statements)); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES (?, ?)", version, name); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "migrated", "database", name)
+
+	return tx.Commit()
+}
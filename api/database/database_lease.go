@@ -0,0 +1,26 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// AcquireLease takes the named lease until ttl from now, if it has expired
+// or holder already has it. Leases aren't released, a job holding one for
+// its interval runs once per interval across replicas
+func AcquireLease(ctx context.Context, db *sql.DB, name string, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+
+	res, err := db.ExecContext(ctx, `INSERT INTO job_leases (name, holder, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+		WHERE job_leases.expires_at <= ? OR job_leases.holder = excluded.holder`,
+		name, holder, now.Add(ttl), now)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected == 1, err
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquireLease(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, filepath.Join(t.TempDir(), "landing.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	acquired, err := AcquireLease(ctx, db, "retention", "replica-1", time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = AcquireLease(ctx, db, "retention", "replica-2", time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, acquired, "held by another replica")
+
+	acquired, err = AcquireLease(ctx, db, "reconcile", "replica-2", time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, acquired, "leases are per job")
+
+	acquired, err = AcquireLease(ctx, db, "retention", "replica-1", -time.Second)
+	assert.NoError(t, err)
+	assert.True(t, acquired, "renewed by the holder")
+
+	acquired, err = AcquireLease(ctx, db, "retention", "replica-2", time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, acquired, "taken over once expired")
+}
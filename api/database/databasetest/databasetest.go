@@ -0,0 +1,24 @@
+// Package databasetest opens migrated databases for tests
+package databasetest
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"skulpture/landing/database"
+	"testing"
+)
+
+// Open opens a migrated database in the test's temp dir, closed when the
+// test finishes
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+
+	db, err := database.Open(context.Background(), filepath.Join(t.TempDir(), "landing.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
@@ -0,0 +1,31 @@
+CREATE TABLE leads (
+	id TEXT PRIMARY KEY,
+	email TEXT NOT NULL,
+	first_name TEXT NOT NULL,
+	last_name TEXT NOT NULL,
+	mobile TEXT NOT NULL DEFAULT '',
+	enquiry TEXT NOT NULL,
+	country TEXT NOT NULL DEFAULT '',
+	policy TEXT NOT NULL DEFAULT '',
+	environment TEXT NOT NULL,
+	processing_status TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX leads_email ON leads (email);
+CREATE INDEX leads_created_at ON leads (created_at);
+
+CREATE TABLE attachments (
+	id TEXT NOT NULL,
+	lead_id TEXT NOT NULL REFERENCES leads (id) ON DELETE CASCADE,
+	backend TEXT NOT NULL,
+	filename TEXT NOT NULL,
+	content_type TEXT NOT NULL DEFAULT '',
+	size INTEGER NOT NULL DEFAULT 0,
+	link TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (backend, id)
+);
+
+CREATE INDEX attachments_lead_id ON attachments (lead_id);
@@ -0,0 +1 @@
+ALTER TABLE leads ADD COLUMN ip_address TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE leads ADD COLUMN category TEXT NOT NULL DEFAULT '';
+ALTER TABLE leads ADD COLUMN route TEXT NOT NULL DEFAULT '';
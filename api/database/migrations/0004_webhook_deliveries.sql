@@ -0,0 +1,19 @@
+CREATE TABLE webhook_deliveries (
+	id TEXT PRIMARY KEY,
+	event_id TEXT NOT NULL,
+	event_type TEXT NOT NULL,
+	subscription TEXT NOT NULL,
+	url TEXT NOT NULL,
+	payload BLOB NOT NULL,
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_status_code INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at TIMESTAMP NOT NULL,
+	delivered_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX webhook_deliveries_event_id ON webhook_deliveries (event_id);
@@ -0,0 +1,13 @@
+ALTER TABLE leads ADD COLUMN confirmation_message_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE leads ADD COLUMN email_status TEXT NOT NULL DEFAULT '';
+ALTER TABLE leads ADD COLUMN email_status_at TIMESTAMP;
+
+CREATE INDEX leads_confirmation_message_id ON leads (confirmation_message_id);
+
+-- addresses we won't email again, stored lower case
+CREATE TABLE suppressions (
+	email TEXT PRIMARY KEY,
+	reason TEXT NOT NULL,
+	message_id TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,18 @@
+-- replies to the confirmation email, threaded onto the lead
+CREATE TABLE messages (
+	-- Postmark's inbound message id, so redelivered webhooks aren't stored twice
+	id TEXT PRIMARY KEY,
+	lead_id TEXT NOT NULL REFERENCES leads (id) ON DELETE CASCADE,
+	from_email TEXT NOT NULL,
+	from_name TEXT NOT NULL DEFAULT '',
+	subject TEXT NOT NULL DEFAULT '',
+	text_body TEXT NOT NULL DEFAULT '',
+	html_body TEXT NOT NULL DEFAULT '',
+	received_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX messages_lead_id ON messages (lead_id, received_at);
+
+-- null for files uploaded with the enquiry
+ALTER TABLE attachments ADD COLUMN message_id TEXT REFERENCES messages (id) ON DELETE CASCADE;
@@ -0,0 +1,40 @@
+-- full text search over names, email and enquiry, kept in step by triggers.
+-- lead_id rather than external content, leads has no stable integer rowid
+CREATE VIRTUAL TABLE leads_search USING fts5(
+	lead_id UNINDEXED,
+	first_name,
+	last_name,
+	email,
+	enquiry,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO leads_search (lead_id, first_name, last_name, email, enquiry)
+	SELECT id, first_name, last_name, email, enquiry FROM leads;
+
+CREATE TRIGGER leads_search_insert AFTER INSERT ON leads BEGIN
+	INSERT INTO leads_search (lead_id, first_name, last_name, email, enquiry)
+		VALUES (new.id, new.first_name, new.last_name, new.email, new.enquiry);
+END;
+
+CREATE TRIGGER leads_search_update AFTER UPDATE OF first_name, last_name, email, enquiry ON leads BEGIN
+	DELETE FROM leads_search WHERE lead_id = old.id;
+	INSERT INTO leads_search (lead_id, first_name, last_name, email, enquiry)
+		VALUES (new.id, new.first_name, new.last_name, new.email, new.enquiry);
+END;
+
+CREATE TRIGGER leads_search_delete AFTER DELETE ON leads BEGIN
+	DELETE FROM leads_search WHERE lead_id = old.id;
+END;
+
+CREATE INDEX leads_processing_status ON leads (processing_status, created_at);
+
+CREATE TABLE api_keys (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	-- sha256 of the key, the key itself is only shown when created
+	hash TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMP NOT NULL,
+	last_used_at TIMESTAMP,
+	revoked_at TIMESTAMP
+);
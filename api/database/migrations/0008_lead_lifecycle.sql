@@ -0,0 +1,28 @@
+ALTER TABLE leads ADD COLUMN state TEXT NOT NULL DEFAULT 'new';
+ALTER TABLE leads ADD COLUMN assignee TEXT NOT NULL DEFAULT '';
+-- the Google Sheets tab the lead was appended to, the default when empty
+ALTER TABLE leads ADD COLUMN sheet TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX leads_state ON leads (state, created_at);
+
+CREATE TABLE notes (
+	id TEXT PRIMARY KEY,
+	lead_id TEXT NOT NULL REFERENCES leads (id) ON DELETE CASCADE,
+	author TEXT NOT NULL,
+	body TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX notes_lead_id ON notes (lead_id, created_at);
+
+-- who changed what, kept when a lead is erased
+CREATE TABLE audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	lead_id TEXT NOT NULL,
+	actor TEXT NOT NULL,
+	action TEXT NOT NULL,
+	detail TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX audit_log_lead_id ON audit_log (lead_id, id);
@@ -0,0 +1,6 @@
+-- scheduled jobs run on one replica at a time, whoever holds the lease
+CREATE TABLE job_leases (
+	name TEXT PRIMARY KEY,
+	holder TEXT NOT NULL,
+	expires_at TIMESTAMP NOT NULL
+);
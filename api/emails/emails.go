@@ -0,0 +1,80 @@
+package emails
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Templates are rendered with the same model as their Postmark
+// counterpart, e.g. uuid, email, firstName, lastName, mobile, enquiry
+//
+//go:embed templates/*.tmpl
+var files embed.FS
+
+const CONFIRMATION = "confirmation"
+const SUPPORT = "support"
+const REPLY = "reply"
+
+var funcs = map[string]any{
+	"bytes": FormatBytes,
+}
+
+var htmlTemplates = htmltemplate.Must(htmltemplate.New("").Option("missingkey=zero").Funcs(funcs).ParseFS(files, "templates/*.html.tmpl"))
+var textTemplates = texttemplate.Must(texttemplate.New("").Option("missingkey=zero").Funcs(funcs).ParseFS(files, "templates/*.txt.tmpl"))
+
+type Rendered struct {
+	Subject  string
+	HtmlBody string
+	TextBody string
+}
+
+// Render renders the named email. The subject is defined in the text
+// template as `{{define "<name>.subject"}}`
+func Render(name string, model map[string]any) (*Rendered, error) {
+	textTemplate := textTemplates.Lookup(name + ".txt.tmpl")
+	htmlTemplate := htmlTemplates.Lookup(name + ".html.tmpl")
+	if textTemplate == nil || htmlTemplate == nil {
+		return nil, fmt.Errorf("email template %q not found", name)
+	}
+
+	var text bytes.Buffer
+	if err := textTemplate.Execute(&text, model); err != nil {
+		return nil, err
+	}
+
+	var subject bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&subject, name+".subject", model); err != nil {
+		return nil, err
+	}
+
+	var html bytes.Buffer
+	if err := htmlTemplate.Execute(&html, model); err != nil {
+		return nil, err
+	}
+
+	return &Rendered{
+		Subject:  strings.TrimSpace(subject.String()),
+		HtmlBody: html.String(),
+		TextBody: text.String(),
+	}, nil
+}
+
+// FormatBytes is a human readable size, e.g. 1.5 MB
+func FormatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,103 @@
+package emails
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	rendered, err := Render(CONFIRMATION, map[string]any{
+		"uuid":      "6f1c0b0e-lead",
+		"email":     "jane@example.com",
+		"firstName": "Jane",
+		"lastName":  "Doe",
+		"enquiry":   "<b>hello</b>",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "We've received your enquiry (ref 6f1c0b0e-lead)", rendered.Subject)
+	assert.Contains(t, rendered.TextBody, "<b>hello</b>")
+	assert.Contains(t, rendered.HtmlBody, "&lt;b&gt;hello&lt;/b&gt;")
+	assert.NotContains(t, rendered.TextBody, "Mobile:", "mobile is optional")
+	assert.Contains(t, rendered.TextBody, "Reference: 6f1c0b0e-lead")
+	assert.NotContains(t, rendered.TextBody, "Check on your enquiry", "status links are optional")
+
+	rendered, err = Render(CONFIRMATION, map[string]any{
+		"uuid":      "6f1c0b0e-lead",
+		"statusUrl": "https://api.skulpture.xyz/api/v1/contact/6f1c0b0e-lead?token=k.1.sig&x=1",
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, rendered.TextBody, "Check on your enquiry: https://api.skulpture.xyz/api/v1/contact/6f1c0b0e-lead?token=k.1.sig&x=1")
+	assert.Contains(t, rendered.HtmlBody, `href="https://api.skulpture.xyz/api/v1/contact/6f1c0b0e-lead?token=k.1.sig&amp;x=1"`)
+
+	_, err = Render("missing", nil)
+	assert.Error(t, err)
+}
+
+func TestRenderSupport(t *testing.T) {
+	rendered, err := Render(SUPPORT, map[string]any{
+		"uuid":      "6f1c0b0e-lead",
+		"email":     "jane@example.com",
+		"firstName": "Jane",
+		"lastName":  "Doe",
+		"enquiry":   "Hello",
+		"country":   "NZ",
+		"policy":    "allow",
+		"ipAddress": "203.0.113.7",
+		"attachments": []map[string]any{
+			{"filename": "brief.pdf", "size": int64(48 << 10), "link": "https://example.com/brief.pdf"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "New enquiry from Jane Doe", rendered.Subject)
+	assert.Contains(t, rendered.TextBody, "IP address: 203.0.113.7")
+	assert.Contains(t, rendered.TextBody, "Mobile: -")
+	assert.Contains(t, rendered.TextBody, "- brief.pdf (48.0 KB): https://example.com/brief.pdf")
+	assert.Contains(t, rendered.HtmlBody, `<a href="https://example.com/brief.pdf">brief.pdf</a>`)
+}
+
+func TestRenderReply(t *testing.T) {
+	rendered, err := Render(REPLY, map[string]any{
+		"uuid":       "6f1c0b0e-lead",
+		"firstName":  "Jane",
+		"lastName":   "Doe",
+		"enquiry":    "Hello",
+		"fromEmail":  "jane@example.com",
+		"subject":    "Re: We've received your enquiry",
+		"body":       "Drawings attached",
+		"receivedAt": "Fri, 02 Jan 2026 03:04:05 UTC",
+		"attachments": []map[string]any{
+			{"filename": "drawings.pdf", "size": int64(2 << 20), "link": "https://example.com/drawings.pdf"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Reply from Jane Doe: Re: We've received your enquiry", rendered.Subject)
+	assert.Contains(t, rendered.TextBody, "Original enquiry")
+	assert.Contains(t, rendered.TextBody, "- drawings.pdf (2.0 MB): https://example.com/drawings.pdf")
+
+	rendered, err = Render(REPLY, map[string]any{
+		"fromEmail": "someone@example.com",
+		"subject":   "Hello",
+		"body":      "Is anyone there?",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Unmatched reply from someone@example.com: Hello", rendered.Subject)
+	assert.NotContains(t, rendered.TextBody, "Original enquiry")
+	assert.Contains(t, rendered.HtmlBody, "someone@example.com replied but the enquiry couldn't be found")
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{15 << 20, "15.0 MB"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, FormatBytes(test.size))
+	}
+}
@@ -0,0 +1,10 @@
+package env
+
+type CountryPolicy string
+
+const (
+	PolicyAllow      CountryPolicy = "allow"
+	PolicyChallenge  CountryPolicy = "challenge"
+	PolicyQuarantine CountryPolicy = "quarantine"
+	PolicyBlock      CountryPolicy = "block"
+)
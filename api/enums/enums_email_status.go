@@ -0,0 +1,16 @@
+package env
+
+// EmailStatus is the latest we know of the confirmation email,
+// from Postmark's webhooks
+type EmailStatus string
+
+const (
+	EmailNone          EmailStatus = ""
+	EmailSent          EmailStatus = "sent"
+	EmailSoftBounced   EmailStatus = "soft_bounced"
+	EmailDelivered     EmailStatus = "delivered"
+	EmailOpened        EmailStatus = "opened"
+	EmailBounced       EmailStatus = "bounced"
+	EmailSpamComplaint EmailStatus = "spam_complaint"
+	EmailSuppressed    EmailStatus = "suppressed"
+)
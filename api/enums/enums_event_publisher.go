@@ -0,0 +1,9 @@
+package env
+
+type EventPublisher string
+
+const (
+	EventPublisherNone   EventPublisher = "none"
+	EventPublisherStdout EventPublisher = "stdout"
+	EventPublisherHTTP   EventPublisher = "http"
+)
@@ -0,0 +1,13 @@
+package env
+
+// LeadState is where staff are with a lead, set through the admin API
+type LeadState string
+
+const (
+	LeadNew       LeadState = "new"
+	LeadTriaged   LeadState = "triaged"
+	LeadContacted LeadState = "contacted"
+	LeadWon       LeadState = "won"
+	LeadLost      LeadState = "lost"
+	LeadSpam      LeadState = "spam"
+)
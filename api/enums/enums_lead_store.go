@@ -0,0 +1,8 @@
+package env
+
+type LeadStore string
+
+const (
+	LeadStoreSQLite LeadStore = "sqlite"
+	LeadStoreDual   LeadStore = "dual"
+)
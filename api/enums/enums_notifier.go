@@ -0,0 +1,16 @@
+package env
+
+type Notifier string
+
+const (
+	NotifierPostmark Notifier = "postmark"
+	NotifierSMTP     Notifier = "smtp"
+)
+
+type SMTPSecurity string
+
+const (
+	SMTPSecurityNone     SMTPSecurity = "none"
+	SMTPSecurityStartTLS SMTPSecurity = "starttls"
+	SMTPSecurityTLS      SMTPSecurity = "tls"
+)
@@ -0,0 +1,8 @@
+package env
+
+type OrphanAction string
+
+const (
+	OrphanReport OrphanAction = "report"
+	OrphanDelete OrphanAction = "delete"
+)
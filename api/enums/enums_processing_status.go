@@ -0,0 +1,10 @@
+package env
+
+type ProcessingStatus string
+
+const (
+	ProcessingReceived    ProcessingStatus = "received"
+	ProcessingQuarantined ProcessingStatus = "quarantined"
+	ProcessingConfirmed   ProcessingStatus = "confirmed"
+	ProcessingFailed      ProcessingStatus = "failed"
+)
@@ -0,0 +1,8 @@
+package env
+
+type RetentionAction string
+
+const (
+	RetentionDelete  RetentionAction = "delete"
+	RetentionArchive RetentionAction = "archive"
+)
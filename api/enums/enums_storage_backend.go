@@ -0,0 +1,9 @@
+package env
+
+type StorageBackend string
+
+const (
+	StorageGoogleDrive StorageBackend = "gdrive"
+	StorageLocal       StorageBackend = "local"
+	StorageS3          StorageBackend = "s3"
+)
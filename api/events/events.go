@@ -0,0 +1,88 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const SPEC_VERSION = "1.0"
+const CONTENT_TYPE = "application/cloudevents+json; charset=UTF-8"
+
+// Enquiry lifecycle event types
+const (
+	TYPE_RECEIVED             = "xyz.skulpture.enquiry.received"
+	TYPE_ATTACHMENTS_UPLOADED = "xyz.skulpture.enquiry.attachments.uploaded"
+	TYPE_RECORDED             = "xyz.skulpture.enquiry.recorded"
+	TYPE_CONFIRMATION_SENT    = "xyz.skulpture.enquiry.confirmation.sent"
+	TYPE_REPLY_RECEIVED       = "xyz.skulpture.enquiry.reply.received"
+	TYPE_FAILED               = "xyz.skulpture.enquiry.failed"
+)
+
+// Event is a CloudEvents 1.0 event in structured JSON mode. Subject is the
+// lead id, traceparent is the distributed tracing extension
+// see: https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/spec.md
+type Event struct {
+	SpecVersion     string `json:"specversion"`
+	Id              string `json:"id"`
+	Source          string `json:"source"`
+	Type            string `json:"type"`
+	Subject         string `json:"subject,omitempty"`
+	Time            string `json:"time"`
+	DataContentType string `json:"datacontenttype,omitempty"`
+	Data            any    `json:"data,omitempty"`
+	TraceParent     string `json:"traceparent,omitempty"`
+	TraceState      string `json:"tracestate,omitempty"`
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Emitter builds events for a source and hands them to a publisher
+type Emitter struct {
+	source    string
+	publisher Publisher
+}
+
+func NewEmitter(source string, publisher Publisher) *Emitter {
+	return &Emitter{
+		source:    source,
+		publisher: publisher,
+	}
+}
+
+func (e *Emitter) Emit(ctx context.Context, eventType string, subject string, data any) error {
+	return e.publisher.Publish(ctx, e.Event(ctx, eventType, subject, data))
+}
+
+// Event carries the trace of the span in ctx, if any
+func (e *Emitter) Event(ctx context.Context, eventType string, subject string, data any) Event {
+	event := Event{
+		SpecVersion: SPEC_VERSION,
+		Id:          uuid.NewString(),
+		Source:      e.source,
+		Type:        eventType,
+		Subject:     subject,
+		Time:        time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	if data != nil {
+		event.DataContentType = "application/json"
+		event.Data = data
+	}
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		event.TraceParent = traceParent(spanContext)
+		event.TraceState = spanContext.TraceState().String()
+	}
+
+	return event
+}
+
+// traceParent formats a W3C traceparent header value
+func traceParent(spanContext trace.SpanContext) string {
+	return "00-" + spanContext.TraceID().String() + "-" + spanContext.SpanID().String() + "-" + spanContext.TraceFlags().String()
+}
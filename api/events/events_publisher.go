@@ -0,0 +1,163 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrBufferFull = errors.New("event buffer is full")
+var ErrClosed = errors.New("event publisher is closed")
+
+// NoopPublisher drops events, for when publishing isn't configured
+type NoopPublisher struct{}
+
+func (NoopPublisher) Publish(ctx context.Context, event Event) error {
+	return nil
+}
+
+// StdoutPublisher writes one event per line, for log shippers to pick up
+type StdoutPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewStdoutPublisher(w io.Writer) *StdoutPublisher {
+	return &StdoutPublisher{
+		w: w,
+	}
+}
+
+func (p *StdoutPublisher) Publish(ctx context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err = p.w.Write(append(line, '\n'))
+
+	return err
+}
+
+// HTTPPublisher POSTs each event in structured mode
+// see: https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/bindings/http-protocol-binding.md#32-structured-content-mode
+type HTTPPublisher struct {
+	client *http.Client
+	url    string
+}
+
+func NewHTTPPublisher(url string) *HTTPPublisher {
+	return &HTTPPublisher{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		url: url,
+	}
+}
+
+func (p *HTTPPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", CONTENT_TYPE)
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+
+		return fmt.Errorf("status %d: %s", res.StatusCode, strings.Join(strings.Fields(string(message)), " "))
+	}
+
+	return nil
+}
+
+// AsyncPublisher publishes from a buffer on its own goroutine so emitting
+// never holds up a request, events are dropped when the buffer is full
+type AsyncPublisher struct {
+	publisher Publisher
+	buffer    chan Event
+	done      chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewAsyncPublisher(publisher Publisher, size int) *AsyncPublisher {
+	p := &AsyncPublisher{
+		publisher: publisher,
+		buffer:    make(chan Event, size),
+		done:      make(chan struct{}),
+	}
+
+	go p.run()
+
+	return p
+}
+
+func (p *AsyncPublisher) Publish(ctx context.Context, event Event) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+
+	select {
+	case p.buffer <- event:
+		return nil
+	default:
+		return ErrBufferFull
+	}
+}
+
+func (p *AsyncPublisher) run() {
+	defer close(p.done)
+
+	for event := range p.buffer {
+		// detached from the emitting request, which has usually finished
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := p.publisher.Publish(ctx, event); err != nil {
+			slog.ErrorContext(ctx, "error", "cloudevents", err.Error(), "type", event.Type, "subject", event.Subject)
+		}
+		cancel()
+	}
+}
+
+// Close publishes what's buffered, waiting until ctx is done at most
+func (p *AsyncPublisher) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.buffer)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
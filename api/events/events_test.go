@@ -0,0 +1,120 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestEmitterEvent(t *testing.T) {
+	emitter := NewEmitter("/landing-api", NoopPublisher{})
+
+	event := emitter.Event(context.Background(), TYPE_RECEIVED, "6f1c0b0e-lead", map[string]any{"email": "jane@example.com"})
+	assert.Equal(t, SPEC_VERSION, event.SpecVersion)
+	assert.Equal(t, "/landing-api", event.Source)
+	assert.Equal(t, TYPE_RECEIVED, event.Type)
+	assert.Equal(t, "6f1c0b0e-lead", event.Subject)
+	assert.Equal(t, "application/json", event.DataContentType)
+	assert.NotEmpty(t, event.Id)
+	assert.Empty(t, event.TraceParent, "no span")
+
+	traceId, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanId, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceId,
+		SpanID:     spanId,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	event = emitter.Event(ctx, TYPE_RECORDED, "6f1c0b0e-lead", nil)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", event.TraceParent)
+	assert.Empty(t, event.DataContentType)
+
+	encoded, _ := json.Marshal(event)
+	assert.NotContains(t, string(encoded), `"data"`)
+}
+
+func TestStdoutPublisher(t *testing.T) {
+	var buf bytes.Buffer
+	emitter := NewEmitter("/landing-api", NewStdoutPublisher(&buf))
+
+	assert.NoError(t, emitter.Emit(context.Background(), TYPE_RECEIVED, "a", nil))
+	assert.NoError(t, emitter.Emit(context.Background(), TYPE_RECORDED, "a", nil))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+
+	var event map[string]any
+	assert.NoError(t, json.Unmarshal(lines[1], &event))
+	assert.Equal(t, "1.0", event["specversion"])
+	assert.Equal(t, TYPE_RECORDED, event["type"])
+}
+
+func TestHTTPPublisher(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+
+		if r.URL.Path == "/fail" {
+			http.Error(w, "nope", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	emitter := NewEmitter("/landing-api", NewHTTPPublisher(server.URL))
+	assert.NoError(t, emitter.Emit(context.Background(), TYPE_FAILED, "a", map[string]any{"stage": "upload"}))
+
+	r := <-received
+	assert.Equal(t, CONTENT_TYPE, r.Header.Get("Content-Type"))
+
+	var event Event
+	assert.NoError(t, json.Unmarshal(<-bodies, &event))
+	assert.Equal(t, TYPE_FAILED, event.Type)
+	assert.Equal(t, map[string]any{"stage": "upload"}, event.Data)
+
+	err := NewHTTPPublisher(server.URL+"/fail").Publish(context.Background(), event)
+	assert.ErrorContains(t, err, "status 400: nope")
+}
+
+type blockingPublisher struct {
+	release   chan struct{}
+	published chan Event
+}
+
+func (p *blockingPublisher) Publish(ctx context.Context, event Event) error {
+	<-p.release
+	p.published <- event
+
+	return nil
+}
+
+func TestAsyncPublisher(t *testing.T) {
+	inner := &blockingPublisher{release: make(chan struct{}), published: make(chan Event, 10)}
+	async := NewAsyncPublisher(inner, 1)
+
+	assert.NoError(t, async.Publish(context.Background(), Event{Id: "1"}))
+
+	// the first is taken by the goroutine and blocks, the second fills the buffer
+	assert.Eventually(t, func() bool {
+		return async.Publish(context.Background(), Event{Id: "2"}) == nil
+	}, time.Second, time.Millisecond)
+	assert.ErrorIs(t, async.Publish(context.Background(), Event{Id: "3"}), ErrBufferFull)
+
+	close(inner.release)
+	assert.NoError(t, async.Close(context.Background()))
+	assert.Len(t, inner.published, 2, "buffered events are published on close")
+
+	assert.ErrorIs(t, async.Publish(context.Background(), Event{Id: "4"}), ErrClosed)
+	assert.NoError(t, async.Close(context.Background()), "closing twice is fine")
+}
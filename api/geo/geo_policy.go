@@ -0,0 +1,133 @@
+package geo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	enums "skulpture/landing/enums"
+	"strings"
+)
+
+// Set by Cloudflare on every proxied request
+// see: https://developers.cloudflare.com/fundamentals/reference/http-headers/#cf-ipcountry
+const HEADER_COUNTRY = "CF-IPCountry"
+
+const (
+	UNKNOWN_COUNTRY = "XX"
+	TOR_COUNTRY     = "T1"
+)
+
+type Policies struct {
+	Default   enums.CountryPolicy
+	Countries map[string]enums.CountryPolicy
+}
+
+type contextKey struct{}
+
+type Decision struct {
+	Country string
+	Policy  enums.CountryPolicy
+}
+
+// ParsePolicies reads rules in the form `NZ=allow,CN=block,T1=challenge`
+func ParsePolicies(rules string, fallback enums.CountryPolicy) (*Policies, error) {
+	policies := &Policies{
+		Default:   fallback,
+		Countries: map[string]enums.CountryPolicy{},
+	}
+
+	for rule := range strings.SplitSeq(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		country, policy, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid country rule %q, expected COUNTRY=POLICY", rule)
+		}
+
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if len(country) != 2 {
+			return nil, fmt.Errorf("invalid country code %q", country)
+		}
+
+		switch p := enums.CountryPolicy(strings.ToLower(strings.TrimSpace(policy))); p {
+		case enums.PolicyAllow, enums.PolicyChallenge, enums.PolicyQuarantine, enums.PolicyBlock:
+			policies.Countries[country] = p
+		default:
+			return nil, fmt.Errorf("invalid policy %q for country %s", policy, country)
+		}
+	}
+
+	return policies, nil
+}
+
+func (p *Policies) Resolve(country string) enums.CountryPolicy {
+	if policy, ok := p.Countries[country]; ok {
+		return policy
+	}
+
+	return p.Default
+}
+
+// Has reports whether any country, or the default, uses the given policy
+func (p *Policies) Has(policy enums.CountryPolicy) bool {
+	if p.Default == policy {
+		return true
+	}
+
+	for _, v := range p.Countries {
+		if v == policy {
+			return true
+		}
+	}
+
+	return false
+}
+
+func Country(r *http.Request) string {
+	country := strings.ToUpper(strings.TrimSpace(r.Header.Get(HEADER_COUNTRY)))
+	if len(country) != 2 {
+		return UNKNOWN_COUNTRY
+	}
+
+	return country
+}
+
+// Middleware rejects blocked countries and stores the decision
+// for the handler to act on challenge and quarantine
+func Middleware(policies *Policies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			decision := Decision{
+				Country: Country(r),
+			}
+			decision.Policy = policies.Resolve(decision.Country)
+
+			if decision.Policy == enums.PolicyBlock {
+				slog.WarnContext(r.Context(), "blocked", "country", decision.Country)
+				http.Error(w, "Enquiries are not accepted from your region", http.StatusForbidden)
+
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), contextKey{}, decision)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func FromContext(ctx context.Context) Decision {
+	decision, ok := ctx.Value(contextKey{}).(Decision)
+	if !ok {
+		return Decision{
+			Country: UNKNOWN_COUNTRY,
+			Policy:  enums.PolicyAllow,
+		}
+	}
+
+	return decision
+}
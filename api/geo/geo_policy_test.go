@@ -0,0 +1,80 @@
+package geo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	enums "skulpture/landing/enums"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   string
+		want    map[string]enums.CountryPolicy
+		wantErr bool
+	}{
+		{name: "empty", rules: "", want: map[string]enums.CountryPolicy{}},
+		{name: "single", rules: "CN=block", want: map[string]enums.CountryPolicy{"CN": enums.PolicyBlock}},
+		{name: "normalised", rules: " nz = Allow , t1=CHALLENGE,", want: map[string]enums.CountryPolicy{"NZ": enums.PolicyAllow, "T1": enums.PolicyChallenge}},
+		{name: "missing separator", rules: "CN", wantErr: true},
+		{name: "invalid country", rules: "CHN=block", wantErr: true},
+		{name: "invalid policy", rules: "CN=deny", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policies, err := ParsePolicies(tt.rules, enums.PolicyAllow)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, policies.Countries)
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	policies, err := ParsePolicies("CN=block,RU=challenge,XX=quarantine", enums.PolicyAllow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		country    string
+		wantStatus int
+		wantPolicy enums.CountryPolicy
+	}{
+		{country: "NZ", wantStatus: http.StatusOK, wantPolicy: enums.PolicyAllow},
+		{country: "cn", wantStatus: http.StatusForbidden},
+		{country: "RU", wantStatus: http.StatusOK, wantPolicy: enums.PolicyChallenge},
+		{country: "", wantStatus: http.StatusOK, wantPolicy: enums.PolicyQuarantine},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.country, func(t *testing.T) {
+			var decision Decision
+			handler := Middleware(policies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				decision = FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/contact", nil)
+			if tt.country != "" {
+				req.Header.Set(HEADER_COUNTRY, tt.country)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.wantPolicy, decision.Policy)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package inbound
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrz1836/postmark"
+)
+
+// MAX_REQUEST_SIZE allows for Postmark's 35 MB inbound limit once the
+// attachments are base64 encoded in the webhook's JSON
+// see: https://postmarkapp.com/developer/user-guide/inbound/parse-an-email
+const MAX_REQUEST_SIZE = 50 << 20
+
+var subjectReference = regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// ReplyAddress plus addresses the inbound address with the lead id, e.g.
+// abc123+<lead>@inbound.postmarkapp.com, which Postmark returns as the MailboxHash
+func ReplyAddress(address string, leadId string) string {
+	local, domain, ok := strings.Cut(address, "@")
+	if !ok {
+		return address
+	}
+
+	local, _, _ = strings.Cut(local, "+")
+
+	return fmt.Sprintf("%s+%s@%s", local, leadId, domain)
+}
+
+// LeadId finds the lead a reply is about from the plus addressed recipient,
+// falling back to the reference in the confirmation's subject
+func LeadId(message postmark.InboundMessage) (string, bool) {
+	candidates := append(mailboxHashes(message), subjectReference.FindString(message.Subject))
+
+	for _, candidate := range candidates {
+		if id, err := uuid.Parse(candidate); err == nil {
+			return id.String(), true
+		}
+	}
+
+	return "", false
+}
+
+// FromLead reports whether a reply can be threaded onto the lead, either it
+// was sent to the lead's reply address or it's from the lead's email. Anyone
+// can quote a lead's id in a subject so that alone isn't enough
+func FromLead(message postmark.InboundMessage, leadId string, email string) bool {
+	for _, candidate := range mailboxHashes(message) {
+		if id, err := uuid.Parse(candidate); err == nil && id.String() == leadId {
+			return true
+		}
+	}
+
+	for _, sender := range []string{Sender(message).Address, message.FromFull.Email, message.From} {
+		if sender != "" && strings.EqualFold(strings.TrimSpace(sender), strings.TrimSpace(email)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func mailboxHashes(message postmark.InboundMessage) []string {
+	hashes := []string{message.MailboxHash, mailboxHash(message.OriginalRecipient)}
+	for _, recipient := range append(message.ToFull, message.CcFull...) {
+		hashes = append(hashes, mailboxHash(recipient.Email))
+	}
+
+	return hashes
+}
+
+func mailboxHash(address string) string {
+	local, _, _ := strings.Cut(address, "@")
+	_, hash, _ := strings.Cut(local, "+")
+
+	return hash
+}
+
+// Sender is the address the reply came from, preferring the Reply-To
+func Sender(message postmark.InboundMessage) mail.Address {
+	if message.ReplyTo != "" {
+		if address, err := mail.ParseAddress(message.ReplyTo); err == nil {
+			return *address
+		}
+	}
+
+	name := message.FromFull.Name
+	if name == "" {
+		name = message.FromName
+	}
+
+	email := message.FromFull.Email
+	if email == "" {
+		email = message.From
+	}
+
+	return mail.Address{Name: name, Address: email}
+}
+
+// ReceivedAt is when the reply was sent, or now when the Date header is invalid
+func ReceivedAt(message postmark.InboundMessage) time.Time {
+	if at, err := message.Time(); err == nil {
+		return at
+	}
+
+	return time.Now()
+}
+
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// Attachments decodes the message's attachments, inline images included
+func Attachments(message postmark.InboundMessage) ([]Attachment, error) {
+	attachments := make([]Attachment, 0, len(message.Attachments))
+	for _, attachment := range message.Attachments {
+		content, err := base64.StdEncoding.DecodeString(attachment.Content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attachment %q: %w", attachment.Name, err)
+		}
+
+		attachments = append(attachments, Attachment{
+			Filename:    attachment.Name,
+			ContentType: attachment.ContentType,
+			Content:     content,
+		})
+	}
+
+	return attachments, nil
+}
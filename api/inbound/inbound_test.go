@@ -0,0 +1,130 @@
+package inbound
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/mrz1836/postmark"
+	"github.com/stretchr/testify/assert"
+)
+
+const lead = "6f1c0b0e-8c1a-4d6e-9f3b-2a7d5e4c1b90"
+
+func TestReplyAddress(t *testing.T) {
+	assert.Equal(t, "abc123+"+lead+"@inbound.postmarkapp.com", ReplyAddress("abc123@inbound.postmarkapp.com", lead))
+	assert.Equal(t, "replies+"+lead+"@skulpture.xyz", ReplyAddress("replies+old@skulpture.xyz", lead))
+	assert.Equal(t, "not an address", ReplyAddress("not an address", lead))
+}
+
+func TestLeadId(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  postmark.InboundMessage
+		expected string
+		ok       bool
+	}{
+		{
+			name:     "mailbox hash",
+			message:  postmark.InboundMessage{MailboxHash: lead},
+			expected: lead,
+			ok:       true,
+		},
+		{
+			name: "plus addressed cc",
+			message: postmark.InboundMessage{
+				ToFull: []postmark.Recipient{{Email: "support@skulpture.xyz"}},
+				CcFull: []postmark.Recipient{{Email: "abc123+" + lead + "@inbound.postmarkapp.com"}},
+			},
+			expected: lead,
+			ok:       true,
+		},
+		{
+			name:     "subject reference",
+			message:  postmark.InboundMessage{Subject: "RE: We've received your enquiry (ref 6F1C0B0E-8C1A-4D6E-9F3B-2A7D5E4C1B90)"},
+			expected: lead,
+			ok:       true,
+		},
+		{
+			name:     "mailbox hash that isn't a lead",
+			message:  postmark.InboundMessage{MailboxHash: "newsletter", Subject: "Re: " + lead},
+			expected: lead,
+			ok:       true,
+		},
+		{
+			name:    "no reference",
+			message: postmark.InboundMessage{Subject: "Re: We've received your enquiry"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id, ok := LeadId(test.message)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, id)
+		})
+	}
+}
+
+func TestFromLead(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  postmark.InboundMessage
+		expected bool
+	}{
+		{
+			name:     "plus addressed",
+			message:  postmark.InboundMessage{MailboxHash: lead, From: "someone@example.com"},
+			expected: true,
+		},
+		{
+			name:     "plus addressed to another lead",
+			message:  postmark.InboundMessage{MailboxHash: "0b1c2d3e-8c1a-4d6e-9f3b-2a7d5e4c1b90", From: "someone@example.com"},
+			expected: false,
+		},
+		{
+			name:     "subject reference from the lead",
+			message:  postmark.InboundMessage{Subject: "Re: " + lead, From: "Jane@Example.com"},
+			expected: true,
+		},
+		{
+			name:     "subject reference from the lead's reply-to",
+			message:  postmark.InboundMessage{Subject: "Re: " + lead, From: "jane@work.example.com", ReplyTo: "Jane <jane@example.com>"},
+			expected: true,
+		},
+		{
+			name:     "subject reference from someone else",
+			message:  postmark.InboundMessage{Subject: "Re: " + lead, From: "someone@example.com"},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, FromLead(test.message, lead, "jane@example.com"))
+		})
+	}
+}
+
+func TestSender(t *testing.T) {
+	sender := Sender(postmark.InboundMessage{From: "jane@example.com", FromName: "Jane Doe"})
+	assert.Equal(t, "jane@example.com", sender.Address)
+	assert.Equal(t, "Jane Doe", sender.Name)
+
+	sender = Sender(postmark.InboundMessage{From: "jane@example.com", ReplyTo: "Jane <jane@work.example.com>"})
+	assert.Equal(t, "jane@work.example.com", sender.Address)
+}
+
+func TestAttachments(t *testing.T) {
+	attachments, err := Attachments(postmark.InboundMessage{
+		Attachments: []postmark.Attachment{
+			{Name: "drawing.pdf", ContentType: "application/pdf", Content: base64.StdEncoding.EncodeToString([]byte("%PDF"))},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Attachment{{Filename: "drawing.pdf", ContentType: "application/pdf", Content: []byte("%PDF")}}, attachments)
+
+	_, err = Attachments(postmark.InboundMessage{
+		Attachments: []postmark.Attachment{{Name: "broken", Content: "not base64!"}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,79 @@
+package leads
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	enums "skulpture/landing/enums"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("lead not found")
+
+type Lead struct {
+	Id                    string
+	Email                 string
+	Mobile                string
+	FirstName             string
+	LastName              string
+	Enquiry               string
+	Category              string
+	Country               string
+	IpAddress             string
+	Policy                enums.CountryPolicy
+	Environment           string
+	ProcessingStatus      enums.ProcessingStatus
+	State                 enums.LeadState
+	Assignee              string
+	ConfirmationMessageId string
+	EmailStatus           enums.EmailStatus
+	EmailStatusAt         sql.NullTime
+	// Route is the name of the routing rule that matched, if any
+	Route string
+	// Sheet is the Google Sheets tab for the lead, the store's default when empty
+	Sheet       string
+	Attachments []Attachment
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type Attachment struct {
+	// Id within the storage backend
+	Id          string
+	Backend     string
+	Filename    string
+	ContentType string
+	Size        int64
+	Link        string
+}
+
+type Store interface {
+	Create(ctx context.Context, lead *Lead) error
+	SetProcessingStatus(ctx context.Context, id string, status enums.ProcessingStatus) error
+}
+
+// Mirror is kept up to date with lifecycle changes made through the admin API
+type Mirror interface {
+	SetLifecycle(ctx context.Context, lead *Lead) error
+}
+
+// EnquiryWithAttachments is the enquiry followed by links to its attachments,
+// as shown in the sheet and confirmation email
+func (l *Lead) EnquiryWithAttachments() string {
+	if len(l.Attachments) == 0 {
+		return l.Enquiry
+	}
+
+	return fmt.Sprintf("%s\nAttached files:\n%s", l.Enquiry, strings.Join(l.AttachmentLinks(), "\n"))
+}
+
+func (l *Lead) AttachmentLinks() []string {
+	links := make([]string, 0, len(l.Attachments))
+	for _, attachment := range l.Attachments {
+		links = append(links, fmt.Sprintf("- %s", attachment.Link))
+	}
+
+	return links
+}
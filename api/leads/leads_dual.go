@@ -0,0 +1,38 @@
+package leads
+
+import (
+	"context"
+	"log/slog"
+	enums "skulpture/landing/enums"
+)
+
+// DualStore writes to the database first and mirrors to the sheet.
+// The database remains the source of truth, so a failed mirror is
+// logged rather than failing the lead
+type DualStore struct {
+	primary *SQLiteStore
+	mirror  *SheetsStore
+}
+
+func NewDualStore(primary *SQLiteStore, mirror *SheetsStore) *DualStore {
+	return &DualStore{
+		primary: primary,
+		mirror:  mirror,
+	}
+}
+
+func (s *DualStore) Create(ctx context.Context, lead *Lead) error {
+	if err := s.primary.Create(ctx, lead); err != nil {
+		return err
+	}
+
+	if err := s.mirror.Create(ctx, lead); err != nil {
+		slog.ErrorContext(ctx, "error", "gsheets mirror", err.Error(), "lead", lead.Id)
+	}
+
+	return nil
+}
+
+func (s *DualStore) SetProcessingStatus(ctx context.Context, id string, status enums.ProcessingStatus) error {
+	return s.primary.SetProcessingStatus(ctx, id, status)
+}
@@ -0,0 +1,137 @@
+package leads
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	enums "skulpture/landing/enums"
+	"strings"
+	"time"
+)
+
+var ErrNotSuppressed = errors.New("address not suppressed")
+
+// emailStatusRanks orders statuses so events arriving out of order, e.g. a
+// delivery after an open, don't move a lead backwards
+var emailStatusRanks = map[enums.EmailStatus]int{
+	enums.EmailNone:          0,
+	enums.EmailSent:          1,
+	enums.EmailSoftBounced:   2,
+	enums.EmailDelivered:     3,
+	enums.EmailOpened:        4,
+	enums.EmailBounced:       5,
+	enums.EmailSpamComplaint: 6,
+	enums.EmailSuppressed:    7,
+}
+
+// SetConfirmation records the confirmation email's message id, which
+// Postmark's webhooks refer back to
+func (s *SQLiteStore) SetConfirmation(ctx context.Context, id string, messageId string) error {
+	now := time.Now().UTC()
+
+	res, err := s.db.ExecContext(ctx, `UPDATE leads SET
+			confirmation_message_id = ?, email_status = ?, email_status_at = ?, updated_at = ?
+		WHERE id = ?`,
+		messageId, enums.EmailSent, now, now, id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(res)
+}
+
+// SetEmailStatus updates the lead matching messageId, or leadId when there
+// is no message id match. Statuses only ever move forward
+func (s *SQLiteStore) SetEmailStatus(ctx context.Context, messageId string, leadId string, status enums.EmailStatus, at time.Time) error {
+	var id string
+	var current enums.EmailStatus
+
+	err := sql.ErrNoRows
+	if messageId != "" {
+		err = s.db.QueryRowContext(ctx, "SELECT id, email_status FROM leads WHERE confirmation_message_id = ?", messageId).Scan(&id, &current)
+	}
+	if errors.Is(err, sql.ErrNoRows) && leadId != "" {
+		err = s.db.QueryRowContext(ctx, "SELECT id, email_status FROM leads WHERE id = ?", leadId).Scan(&id, &current)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if emailStatusRanks[status] <= emailStatusRanks[current] {
+		return nil
+	}
+
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	// conditional on the status read, a concurrent event may have moved it on
+	_, err = s.db.ExecContext(ctx, `UPDATE leads SET email_status = ?, email_status_at = ?, updated_at = ?
+		WHERE id = ? AND email_status = ?`,
+		status, at.UTC(), time.Now().UTC(), id, current)
+
+	return err
+}
+
+// Suppress adds an address to the suppression list, keeping the first reason
+func (s *SQLiteStore) Suppress(ctx context.Context, email string, reason string, messageId string) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO suppressions (email, reason, message_id, created_at)
+		VALUES (?, ?, ?, ?) ON CONFLICT (email) DO NOTHING`,
+		normaliseEmail(email), reason, messageId, time.Now().UTC())
+
+	return err
+}
+
+func (s *SQLiteStore) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM suppressions WHERE email = ?)", normaliseEmail(email)).Scan(&exists)
+
+	return exists, err
+}
+
+func (s *SQLiteStore) Unsuppress(ctx context.Context, email string) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM suppressions WHERE email = ?", normaliseEmail(email))
+	if err != nil {
+		return err
+	}
+
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotSuppressed
+	}
+
+	return err
+}
+
+type Suppression struct {
+	Email     string
+	Reason    string
+	MessageId string
+	CreatedAt time.Time
+}
+
+func (s *SQLiteStore) Suppressions(ctx context.Context) ([]Suppression, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT email, reason, message_id, created_at FROM suppressions ORDER BY created_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppressions := []Suppression{}
+	for rows.Next() {
+		var suppression Suppression
+		if err := rows.Scan(&suppression.Email, &suppression.Reason, &suppression.MessageId, &suppression.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		suppressions = append(suppressions, suppression)
+	}
+
+	return suppressions, rows.Err()
+}
+
+func normaliseEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,87 @@
+package leads
+
+import (
+	"context"
+	"skulpture/landing/database/databasetest"
+	enums "skulpture/landing/enums"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteStoreEmailStatus(t *testing.T) {
+	store := NewSQLiteStore(databasetest.Open(t))
+	ctx := context.Background()
+
+	lead := &Lead{
+		Id:               "9b2f8c4e-0000-4000-8000-000000000002",
+		Email:            "test@example.com",
+		FirstName:        "Test",
+		LastName:         "123",
+		Enquiry:          "Hello world",
+		Environment:      string(enums.Test),
+		ProcessingStatus: enums.ProcessingReceived,
+	}
+	assert.NoError(t, store.Create(ctx, lead))
+	assert.NoError(t, store.SetConfirmation(ctx, lead.Id, "message-1"))
+
+	opened := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		messageId string
+		leadId    string
+		status    enums.EmailStatus
+		expected  enums.EmailStatus
+		err       error
+	}{
+		{"delivered by message id", "message-1", "", enums.EmailDelivered, enums.EmailDelivered, nil},
+		{"opened by lead id", "unknown", lead.Id, enums.EmailOpened, enums.EmailOpened, nil},
+		{"late delivery doesn't go backwards", "message-1", "", enums.EmailDelivered, enums.EmailOpened, nil},
+		{"unknown lead", "unknown", "missing", enums.EmailBounced, enums.EmailOpened, ErrNotFound},
+		{"spam complaint", "message-1", lead.Id, enums.EmailSpamComplaint, enums.EmailSpamComplaint, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := store.SetEmailStatus(ctx, test.messageId, test.leadId, test.status, opened)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			stored, err := store.Get(ctx, lead.Id)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, stored.EmailStatus)
+			assert.Equal(t, "message-1", stored.ConfirmationMessageId)
+		})
+	}
+
+	stored, _ := store.Get(ctx, lead.Id)
+	assert.WithinDuration(t, opened, stored.EmailStatusAt.Time, 0)
+}
+
+func TestSQLiteStoreSuppressions(t *testing.T) {
+	store := NewSQLiteStore(databasetest.Open(t))
+	ctx := context.Background()
+
+	assert.NoError(t, store.Suppress(ctx, " Jane@Example.com", "hard bounce", "message-1"))
+	assert.NoError(t, store.Suppress(ctx, "jane@example.com", "spam complaint", "message-2"))
+
+	suppressed, err := store.IsSuppressed(ctx, "JANE@example.com")
+	assert.NoError(t, err)
+	assert.True(t, suppressed)
+
+	suppressions, err := store.Suppressions(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, suppressions, 1)
+	assert.Equal(t, "hard bounce", suppressions[0].Reason, "first reason is kept")
+
+	assert.NoError(t, store.Unsuppress(ctx, "jane@example.com"))
+	assert.ErrorIs(t, store.Unsuppress(ctx, "jane@example.com"), ErrNotSuppressed)
+
+	suppressed, _ = store.IsSuppressed(ctx, "jane@example.com")
+	assert.False(t, suppressed)
+}
@@ -0,0 +1,290 @@
+package leads
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	enums "skulpture/landing/enums"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidTransition = errors.New("invalid state transition")
+var ErrNotQuarantined = errors.New("lead is not quarantined")
+
+// Transitions are the states a lead can move to from each state. Lost leads
+// can be reopened and spam restored when it was a false positive
+var Transitions = map[enums.LeadState][]enums.LeadState{
+	enums.LeadNew:       {enums.LeadTriaged, enums.LeadSpam},
+	enums.LeadTriaged:   {enums.LeadContacted, enums.LeadLost, enums.LeadSpam},
+	enums.LeadContacted: {enums.LeadWon, enums.LeadLost, enums.LeadSpam},
+	enums.LeadWon:       {},
+	enums.LeadLost:      {enums.LeadContacted},
+	enums.LeadSpam:      {enums.LeadNew},
+}
+
+// Audit log actions
+const (
+	AUDIT_STATE  = "state"
+	AUDIT_ASSIGN = "assign"
+	AUDIT_NOTE   = "note"
+	AUDIT_REVIEW = "review"
+	AUDIT_EXPORT = "export"
+	AUDIT_ERASE  = "erase"
+	// attachments removed by a retention policy, the lead is kept
+	AUDIT_RETENTION = "retention"
+)
+
+type Note struct {
+	Id        string
+	LeadId    string
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+type AuditEntry struct {
+	Id        int64
+	LeadId    string
+	Actor     string
+	Action    string
+	Detail    string
+	CreatedAt time.Time
+}
+
+func CanTransition(from enums.LeadState, to enums.LeadState) bool {
+	return slices.Contains(Transitions[from], to)
+}
+
+// Transition moves a lead to a new state, recording who moved it
+func (s *SQLiteStore) Transition(ctx context.Context, id string, to enums.LeadState, actor string) (*Lead, error) {
+	return s.Update(ctx, id, &to, nil, actor)
+}
+
+// Assign sets the staff member working the lead, empty unassigns
+func (s *SQLiteStore) Assign(ctx context.Context, id string, assignee string, actor string) (*Lead, error) {
+	return s.Update(ctx, id, nil, &assignee, actor)
+}
+
+// Update moves the lead to state and assigns it to assignee together, either
+// both change or neither does. Nil leaves them unchanged
+func (s *SQLiteStore) Update(ctx context.Context, id string, state *enums.LeadState, assignee *string, actor string) (*Lead, error) {
+	if state != nil {
+		if _, ok := Transitions[*state]; !ok {
+			return nil, fmt.Errorf("%w: unknown state %q", ErrInvalidTransition, *state)
+		}
+	}
+
+	return s.update(ctx, id, func(tx *sql.Tx, lead *Lead) error {
+		if state != nil {
+			if err := transition(ctx, tx, lead, *state, actor); err != nil {
+				return err
+			}
+		}
+
+		if assignee != nil {
+			return assign(ctx, tx, lead, *assignee, actor)
+		}
+
+		return nil
+	})
+}
+
+func transition(ctx context.Context, tx *sql.Tx, lead *Lead, to enums.LeadState, actor string) error {
+	if !CanTransition(lead.State, to) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, lead.State, to)
+	}
+
+	from := lead.State
+	lead.State = to
+
+	if _, err := tx.ExecContext(ctx, "UPDATE leads SET state = ?, updated_at = ? WHERE id = ?", to, lead.UpdatedAt, lead.Id); err != nil {
+		return err
+	}
+
+	return audit(ctx, tx, lead.Id, actor, AUDIT_STATE, fmt.Sprintf("%s → %s", from, to))
+}
+
+func assign(ctx context.Context, tx *sql.Tx, lead *Lead, assignee string, actor string) error {
+	if lead.Assignee == assignee {
+		return nil
+	}
+
+	from := lead.Assignee
+	lead.Assignee = assignee
+
+	if _, err := tx.ExecContext(ctx, "UPDATE leads SET assignee = ?, updated_at = ? WHERE id = ?", assignee, lead.UpdatedAt, lead.Id); err != nil {
+		return err
+	}
+
+	return audit(ctx, tx, lead.Id, actor, AUDIT_ASSIGN, fmt.Sprintf("%s → %s", orNone(from), orNone(assignee)))
+}
+
+// Approve releases a quarantined lead, the caller resumes processing it. Only
+// one of concurrent approvals succeeds
+func (s *SQLiteStore) Approve(ctx context.Context, id string, actor string) (*Lead, error) {
+	return s.update(ctx, id, func(tx *sql.Tx, lead *Lead) error {
+		if lead.ProcessingStatus != enums.ProcessingQuarantined || lead.State == enums.LeadSpam {
+			return ErrNotQuarantined
+		}
+
+		lead.ProcessingStatus = enums.ProcessingReceived
+
+		if _, err := tx.ExecContext(ctx, "UPDATE leads SET processing_status = ?, updated_at = ? WHERE id = ?", lead.ProcessingStatus, lead.UpdatedAt, id); err != nil {
+			return err
+		}
+
+		return audit(ctx, tx, id, actor, AUDIT_REVIEW, "approved")
+	})
+}
+
+// Reject marks a quarantined lead as spam, it stays quarantined so nothing
+// is ever sent for it
+func (s *SQLiteStore) Reject(ctx context.Context, id string, actor string) (*Lead, error) {
+	return s.update(ctx, id, func(tx *sql.Tx, lead *Lead) error {
+		if lead.ProcessingStatus != enums.ProcessingQuarantined || lead.State == enums.LeadSpam {
+			return ErrNotQuarantined
+		}
+
+		if !CanTransition(lead.State, enums.LeadSpam) {
+			return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, lead.State, enums.LeadSpam)
+		}
+
+		from := lead.State
+		lead.State = enums.LeadSpam
+
+		if _, err := tx.ExecContext(ctx, "UPDATE leads SET state = ?, updated_at = ? WHERE id = ?", lead.State, lead.UpdatedAt, id); err != nil {
+			return err
+		}
+
+		if err := audit(ctx, tx, id, actor, AUDIT_STATE, fmt.Sprintf("%s → %s", from, lead.State)); err != nil {
+			return err
+		}
+
+		return audit(ctx, tx, id, actor, AUDIT_REVIEW, "rejected")
+	})
+}
+
+// update runs change against the lead read in the same transaction
+func (s *SQLiteStore) update(ctx context.Context, id string, change func(tx *sql.Tx, lead *Lead) error) (*Lead, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	lead, err := scanLead(tx.QueryRowContext(ctx, "SELECT "+leadColumns+" FROM leads WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lead.UpdatedAt = time.Now().UTC()
+	if err := change(tx, lead); err != nil {
+		return nil, err
+	}
+
+	return lead, tx.Commit()
+}
+
+func (s *SQLiteStore) AddNote(ctx context.Context, leadId string, author string, body string) (*Note, error) {
+	note := &Note{
+		Id:        uuid.NewString(),
+		LeadId:    leadId,
+		Author:    author,
+		Body:      body,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	_, err := s.update(ctx, leadId, func(tx *sql.Tx, lead *Lead) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO notes (id, lead_id, author, body, created_at) VALUES (?, ?, ?, ?, ?)",
+			note.Id, note.LeadId, note.Author, note.Body, note.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		return audit(ctx, tx, leadId, author, AUDIT_NOTE, note.Id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return note, nil
+}
+
+// Notes are oldest first
+func (s *SQLiteStore) Notes(ctx context.Context, leadId string) ([]Note, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, lead_id, author, body, created_at FROM notes WHERE lead_id = ? ORDER BY created_at, id", leadId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []Note{}
+	for rows.Next() {
+		var note Note
+		if err := rows.Scan(&note.Id, &note.LeadId, &note.Author, &note.Body, &note.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
+
+// Audit records an action taken outside a lead update, e.g. an export
+func (s *SQLiteStore) Audit(ctx context.Context, leadId string, actor string, action string, detail string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := audit(ctx, tx, leadId, actor, action, detail); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AuditLog is the lead's history, oldest first
+func (s *SQLiteStore) AuditLog(ctx context.Context, leadId string) ([]AuditEntry, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, lead_id, actor, action, detail, created_at FROM audit_log WHERE lead_id = ? ORDER BY id", leadId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var entry AuditEntry
+		if err := rows.Scan(&entry.Id, &entry.LeadId, &entry.Actor, &entry.Action, &entry.Detail, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func audit(ctx context.Context, tx *sql.Tx, leadId string, actor string, action string, detail string) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO audit_log (lead_id, actor, action, detail, created_at) VALUES (?, ?, ?, ?, ?)",
+		leadId, actor, action, detail, time.Now().UTC())
+
+	return err
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+
+	return value
+}
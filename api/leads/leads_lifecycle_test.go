@@ -0,0 +1,145 @@
+package leads
+
+import (
+	"context"
+	"skulpture/landing/database/databasetest"
+	enums "skulpture/landing/enums"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteStoreLifecycle(t *testing.T) {
+	store := NewSQLiteStore(databasetest.Open(t))
+	ctx := context.Background()
+
+	lead := &Lead{
+		Id:               "9b2f8c4e-0000-4000-8000-000000000400",
+		Email:            "jane@example.com",
+		FirstName:        "Jane",
+		LastName:         "Doe",
+		Enquiry:          "Hello world",
+		Environment:      string(enums.Test),
+		ProcessingStatus: enums.ProcessingConfirmed,
+	}
+	assert.NoError(t, store.Create(ctx, lead))
+	assert.Equal(t, enums.LeadNew, lead.State)
+
+	tests := []struct {
+		to       enums.LeadState
+		expected enums.LeadState
+		err      error
+	}{
+		{enums.LeadContacted, enums.LeadNew, ErrInvalidTransition},
+		{enums.LeadTriaged, enums.LeadTriaged, nil},
+		{enums.LeadContacted, enums.LeadContacted, nil},
+		{"archived", enums.LeadContacted, ErrInvalidTransition},
+		{enums.LeadLost, enums.LeadLost, nil},
+		{enums.LeadContacted, enums.LeadContacted, nil},
+		{enums.LeadWon, enums.LeadWon, nil},
+		{enums.LeadSpam, enums.LeadWon, ErrInvalidTransition},
+	}
+
+	contacted := &Lead{Id: "9b2f8c4e-0000-4000-8000-000000000401", Email: "joe@example.com", Environment: string(enums.Test), State: enums.LeadContacted}
+	assert.NoError(t, store.Create(ctx, contacted))
+	_, err := store.Transition(ctx, contacted.Id, enums.LeadSpam, "jane@skulpture.xyz")
+	assert.NoError(t, err, "contacted can turn out to be spam")
+
+	for _, test := range tests {
+		_, err := store.Transition(ctx, lead.Id, test.to, "jane@skulpture.xyz")
+		if test.err != nil {
+			assert.ErrorIs(t, err, test.err, test.to)
+		} else {
+			assert.NoError(t, err, test.to)
+		}
+
+		stored, _ := store.Get(ctx, lead.Id)
+		assert.Equal(t, test.expected, stored.State, test.to)
+	}
+
+	_, err = store.Transition(ctx, "missing", enums.LeadTriaged, "jane@skulpture.xyz")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assigned, err := store.Assign(ctx, lead.Id, "joe@skulpture.xyz", "jane@skulpture.xyz")
+	assert.NoError(t, err)
+	assert.Equal(t, "joe@skulpture.xyz", assigned.Assignee)
+
+	_, err = store.Assign(ctx, lead.Id, "joe@skulpture.xyz", "jane@skulpture.xyz")
+	assert.NoError(t, err, "unchanged assignee isn't audited")
+
+	won := enums.LeadWon
+	sam := "sam@skulpture.xyz"
+	_, err = store.Update(ctx, lead.Id, &won, &sam, "jane@skulpture.xyz")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	stored, _ := store.Get(ctx, lead.Id)
+	assert.Equal(t, "joe@skulpture.xyz", stored.Assignee, "neither changes when one fails")
+
+	note, err := store.AddNote(ctx, lead.Id, "joe@skulpture.xyz", "Called, sending a proposal")
+	assert.NoError(t, err)
+
+	notes, err := store.Notes(ctx, lead.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Called, sending a proposal"}, []string{notes[0].Body})
+
+	_, err = store.AddNote(ctx, "missing", "joe@skulpture.xyz", "Hello")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	entries, err := store.AuditLog(ctx, lead.Id)
+	assert.NoError(t, err)
+
+	actions := [][3]string{}
+	for _, entry := range entries {
+		actions = append(actions, [3]string{entry.Actor, entry.Action, entry.Detail})
+	}
+	assert.Equal(t, [][3]string{
+		{"jane@skulpture.xyz", AUDIT_STATE, "new → triaged"},
+		{"jane@skulpture.xyz", AUDIT_STATE, "triaged → contacted"},
+		{"jane@skulpture.xyz", AUDIT_STATE, "contacted → lost"},
+		{"jane@skulpture.xyz", AUDIT_STATE, "lost → contacted"},
+		{"jane@skulpture.xyz", AUDIT_STATE, "contacted → won"},
+		{"jane@skulpture.xyz", AUDIT_ASSIGN, "(none) → joe@skulpture.xyz"},
+		{"joe@skulpture.xyz", AUDIT_NOTE, note.Id},
+	}, actions)
+}
+
+func TestSQLiteStoreReview(t *testing.T) {
+	store := NewSQLiteStore(databasetest.Open(t))
+	ctx := context.Background()
+
+	for _, id := range []string{"9b2f8c4e-0000-4000-8000-000000000410", "9b2f8c4e-0000-4000-8000-000000000411"} {
+		assert.NoError(t, store.Create(ctx, &Lead{
+			Id:               id,
+			Email:            "jane@example.com",
+			FirstName:        "Jane",
+			LastName:         "Doe",
+			Enquiry:          "Hello world",
+			Environment:      string(enums.Test),
+			ProcessingStatus: enums.ProcessingQuarantined,
+		}))
+	}
+
+	approved, err := store.Approve(ctx, "9b2f8c4e-0000-4000-8000-000000000410", "jane@skulpture.xyz")
+	assert.NoError(t, err)
+	assert.Equal(t, enums.ProcessingReceived, approved.ProcessingStatus)
+
+	_, err = store.Approve(ctx, "9b2f8c4e-0000-4000-8000-000000000410", "jane@skulpture.xyz")
+	assert.ErrorIs(t, err, ErrNotQuarantined)
+	_, err = store.Reject(ctx, "9b2f8c4e-0000-4000-8000-000000000410", "jane@skulpture.xyz")
+	assert.ErrorIs(t, err, ErrNotQuarantined)
+
+	rejected, err := store.Reject(ctx, "9b2f8c4e-0000-4000-8000-000000000411", "jane@skulpture.xyz")
+	assert.NoError(t, err)
+	assert.Equal(t, enums.LeadSpam, rejected.State)
+	assert.Equal(t, enums.ProcessingQuarantined, rejected.ProcessingStatus)
+
+	_, err = store.Approve(ctx, "9b2f8c4e-0000-4000-8000-000000000411", "jane@skulpture.xyz")
+	assert.ErrorIs(t, err, ErrNotQuarantined)
+	_, err = store.Approve(ctx, "missing", "jane@skulpture.xyz")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	entries, err := store.AuditLog(ctx, "9b2f8c4e-0000-4000-8000-000000000411")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, AUDIT_REVIEW, entries[1].Action)
+	assert.Equal(t, "rejected", entries[1].Detail)
+}
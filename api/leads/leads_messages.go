@@ -0,0 +1,138 @@
+package leads
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrDuplicateMessage = errors.New("message already stored")
+
+// Message is an email received about a lead, e.g. a reply to the confirmation
+type Message struct {
+	// Id is the inbound provider's message id
+	Id          string
+	LeadId      string
+	FromEmail   string
+	FromName    string
+	Subject     string
+	TextBody    string
+	HtmlBody    string
+	Attachments []Attachment
+	ReceivedAt  time.Time
+	CreatedAt   time.Time
+}
+
+// AddMessage stores a message and its attachments against an existing lead,
+// returning ErrDuplicateMessage when it was stored before
+func (s *SQLiteStore) AddMessage(ctx context.Context, message *Message) error {
+	message.CreatedAt = time.Now().UTC()
+	if message.ReceivedAt.IsZero() {
+		message.ReceivedAt = message.CreatedAt
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM leads WHERE id = ?)", message.LeadId).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO messages (
+			id, lead_id, from_email, from_name, subject, text_body, html_body, received_at, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`,
+		message.Id, message.LeadId, message.FromEmail, message.FromName, message.Subject, message.TextBody, message.HtmlBody,
+		message.ReceivedAt.UTC(), message.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrDuplicateMessage
+	}
+
+	for _, attachment := range message.Attachments {
+		_, err = tx.ExecContext(ctx, `INSERT INTO attachments (
+				id, lead_id, message_id, backend, filename, content_type, size, link, created_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			attachment.Id, message.LeadId, message.Id, attachment.Backend, attachment.Filename, attachment.ContentType, attachment.Size, attachment.Link, message.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Messages are the lead's messages, oldest first
+func (s *SQLiteStore) Messages(ctx context.Context, leadId string) ([]Message, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT
+			id, lead_id, from_email, from_name, subject, text_body, html_body, received_at, created_at
+		FROM messages WHERE lead_id = ? ORDER BY received_at`, leadId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []Message{}
+	for rows.Next() {
+		var message Message
+		if err := rows.Scan(
+			&message.Id, &message.LeadId, &message.FromEmail, &message.FromName, &message.Subject, &message.TextBody, &message.HtmlBody,
+			&message.ReceivedAt, &message.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range messages {
+		messages[i].Attachments, err = s.messageAttachments(ctx, messages[i].Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return messages, nil
+}
+
+func (s *SQLiteStore) messageAttachments(ctx context.Context, messageId string) ([]Attachment, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, backend, filename, content_type, size, link
+		FROM attachments WHERE message_id = ? ORDER BY filename`, messageId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAttachments(rows)
+}
+
+func scanAttachments(rows *sql.Rows) ([]Attachment, error) {
+	attachments := []Attachment{}
+	for rows.Next() {
+		var attachment Attachment
+		if err := rows.Scan(&attachment.Id, &attachment.Backend, &attachment.Filename, &attachment.ContentType, &attachment.Size, &attachment.Link); err != nil {
+			return nil, err
+		}
+
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
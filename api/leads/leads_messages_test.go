@@ -0,0 +1,57 @@
+package leads
+
+import (
+	"context"
+	"skulpture/landing/database/databasetest"
+	enums "skulpture/landing/enums"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteStoreMessages(t *testing.T) {
+	store := NewSQLiteStore(databasetest.Open(t))
+	ctx := context.Background()
+
+	lead := &Lead{
+		Id:               "9b2f8c4e-0000-4000-8000-000000000003",
+		Email:            "jane@example.com",
+		FirstName:        "Jane",
+		LastName:         "Doe",
+		Enquiry:          "Hello world",
+		Environment:      string(enums.Test),
+		ProcessingStatus: enums.ProcessingConfirmed,
+		Attachments: []Attachment{
+			{Id: "brief.pdf", Backend: "local", Filename: "brief.pdf", Link: "file:///brief.pdf"},
+		},
+	}
+	assert.NoError(t, store.Create(ctx, lead))
+
+	message := &Message{
+		Id:         "inbound-1",
+		LeadId:     lead.Id,
+		FromEmail:  "jane@example.com",
+		FromName:   "Jane Doe",
+		Subject:    "Re: We've received your enquiry",
+		TextBody:   "Here are the drawings",
+		ReceivedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Attachments: []Attachment{
+			{Id: "drawings.pdf", Backend: "local", Filename: "drawings.pdf", Size: 10, Link: "file:///drawings.pdf"},
+		},
+	}
+	assert.NoError(t, store.AddMessage(ctx, message))
+	assert.ErrorIs(t, store.AddMessage(ctx, message), ErrDuplicateMessage)
+	assert.ErrorIs(t, store.AddMessage(ctx, &Message{Id: "inbound-2", LeadId: "missing"}), ErrNotFound)
+
+	messages, err := store.Messages(ctx, lead.Id)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "Here are the drawings", messages[0].TextBody)
+	assert.WithinDuration(t, message.ReceivedAt, messages[0].ReceivedAt, 0)
+	assert.Equal(t, message.Attachments, messages[0].Attachments)
+
+	stored, err := store.Get(ctx, lead.Id)
+	assert.NoError(t, err)
+	assert.Len(t, stored.Attachments, 1, "reply attachments aren't the enquiry's")
+}
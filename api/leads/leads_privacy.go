@@ -0,0 +1,65 @@
+package leads
+
+import (
+	"context"
+)
+
+// LeadsByEmail is every lead submitted with the address, ignoring case,
+// oldest first
+func (s *SQLiteStore) LeadsByEmail(ctx context.Context, email string) ([]Lead, error) {
+	return s.leadsWhere(ctx, "email = ? COLLATE NOCASE", email)
+}
+
+// leadsWhere is the leads matching the condition with their attachments,
+// oldest first
+func (s *SQLiteStore) leadsWhere(ctx context.Context, where string, args ...any) ([]Lead, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+leadColumns+" FROM leads WHERE "+where+" ORDER BY created_at, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	leads := []Lead{}
+	for rows.Next() {
+		lead, err := scanLead(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		leads = append(leads, *lead)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range leads {
+		if leads[i].Attachments, err = s.attachments(ctx, leads[i].Id); err != nil {
+			return nil, err
+		}
+	}
+
+	return leads, nil
+}
+
+// Erase deletes the leads with their attachments, messages and notes. The
+// audit log keeps a record of the erasure against each id, with no personal
+// data in it
+func (s *SQLiteStore) Erase(ctx context.Context, ids []string, actor string, detail string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM leads WHERE id = ?", id); err != nil {
+			return err
+		}
+
+		if err := audit(ctx, tx, id, actor, AUDIT_ERASE, detail); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,88 @@
+package leads
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// LeadsCreatedBefore is every lead from the environment older than before,
+// oldest first
+func (s *SQLiteStore) LeadsCreatedBefore(ctx context.Context, environment string, before time.Time) ([]Lead, error) {
+	return s.leadsWhere(ctx, "environment = ? AND created_at < ?", environment, before.UTC())
+}
+
+// RemoveAttachments forgets attachments whose files were removed from the
+// backend, noting how many against each lead they belonged to
+func (s *SQLiteStore) RemoveAttachments(ctx context.Context, backend string, ids []string, actor string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	removed := map[string]int{}
+	leadIds := []string{}
+	for _, id := range ids {
+		var leadId string
+		err := tx.QueryRowContext(ctx, "DELETE FROM attachments WHERE backend = ? AND id = ? RETURNING lead_id", backend, id).Scan(&leadId)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if removed[leadId] == 0 {
+			leadIds = append(leadIds, leadId)
+		}
+		removed[leadId]++
+	}
+
+	for _, leadId := range leadIds {
+		if err := audit(ctx, tx, leadId, actor, AUDIT_RETENTION, fmt.Sprintf("%d attachments removed", removed[leadId])); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Existing is the ids that are leads, of those given
+func (s *SQLiteStore) Existing(ctx context.Context, ids []string) (map[string]bool, error) {
+	existing := map[string]bool{}
+
+	// within SQLite's limit on parameters
+	for chunk := range slices.Chunk(ids, 500) {
+		args := make([]any, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+
+		rows, err := s.db.QueryContext(ctx, "SELECT id FROM leads WHERE id IN (?"+strings.Repeat(", ?", len(chunk)-1)+")", args...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+
+				return nil, err
+			}
+
+			existing[id] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return existing, nil
+}
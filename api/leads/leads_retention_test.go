@@ -0,0 +1,53 @@
+package leads
+
+import (
+	"context"
+	"skulpture/landing/database/databasetest"
+	enums "skulpture/landing/enums"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteStoreRetention(t *testing.T) {
+	store := NewSQLiteStore(databasetest.Open(t))
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	for _, lead := range []*Lead{
+		{Id: "old", Environment: string(enums.Development), CreatedAt: now.Add(-10 * 24 * time.Hour)},
+		{Id: "recent", Environment: string(enums.Development), CreatedAt: now.Add(-time.Hour)},
+		{Id: "production", Environment: string(enums.Production), CreatedAt: now.Add(-10 * 24 * time.Hour)},
+	} {
+		lead.Email = "jane@example.com"
+		lead.Attachments = []Attachment{
+			{Id: lead.Id + "/brief.pdf", Backend: string(enums.StorageLocal), Filename: "brief.pdf"},
+			{Id: lead.Id + "/photo.jpg", Backend: string(enums.StorageLocal), Filename: "photo.jpg"},
+		}
+		if err := store.Create(ctx, lead); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, err := store.LeadsCreatedBefore(ctx, string(enums.Development), now.Add(-7*24*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, "old", found[0].Id)
+	assert.Len(t, found[0].Attachments, 2)
+
+	assert.NoError(t, store.RemoveAttachments(ctx, string(enums.StorageLocal), []string{"old/brief.pdf", "old/photo.jpg", "unknown"}, "retention"))
+
+	lead, err := store.Get(ctx, "old")
+	assert.NoError(t, err)
+	assert.Empty(t, lead.Attachments)
+
+	entries, err := store.AuditLog(ctx, "old")
+	assert.NoError(t, err)
+	assert.Equal(t, AUDIT_RETENTION, entries[len(entries)-1].Action)
+	assert.Equal(t, "2 attachments removed", entries[len(entries)-1].Detail)
+
+	existing, err := store.Existing(ctx, []string{"old", "missing", "production"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"old": true, "production": true}, existing)
+}
@@ -0,0 +1,103 @@
+package leads
+
+import (
+	"context"
+	"fmt"
+	enums "skulpture/landing/enums"
+	"strings"
+	"time"
+)
+
+const MAX_SEARCH_LIMIT = 100
+
+type SearchQuery struct {
+	// Text is matched against names, email and enquiry, every term must match
+	Text   string
+	Status enums.ProcessingStatus
+	State  enums.LeadState
+	// Assignee filters to a staff member's leads
+	Assignee string
+	// From and To bound created_at, either may be zero
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+type SearchResult struct {
+	Leads []Lead
+	// Total matching leads, ignoring Limit and Offset
+	Total int
+}
+
+// Search lists leads newest first. Attachments aren't loaded, see Get
+func (s *SQLiteStore) Search(ctx context.Context, query SearchQuery) (*SearchResult, error) {
+	conditions := []string{"1 = 1"}
+	args := []any{}
+
+	if match := ftsQuery(query.Text); match != "" {
+		conditions = append(conditions, "id IN (SELECT lead_id FROM leads_search WHERE leads_search MATCH ?)")
+		args = append(args, match)
+	}
+	if query.Status != "" {
+		conditions = append(conditions, "processing_status = ?")
+		args = append(args, query.Status)
+	}
+	if query.State != "" {
+		conditions = append(conditions, "state = ?")
+		args = append(args, query.State)
+	}
+	if query.Assignee != "" {
+		conditions = append(conditions, "assignee = ?")
+		args = append(args, query.Assignee)
+	}
+	if !query.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, query.From.UTC())
+	}
+	if !query.To.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, query.To.UTC())
+	}
+
+	where := strings.Join(conditions, " AND ")
+
+	result := &SearchResult{Leads: []Lead{}}
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM leads WHERE "+where, args...).Scan(&result.Total); err != nil {
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit <= 0 || limit > MAX_SEARCH_LIMIT {
+		limit = MAX_SEARCH_LIMIT
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM leads WHERE %s ORDER BY created_at DESC, id LIMIT ? OFFSET ?", leadColumns, where),
+		append(args, limit, max(query.Offset, 0))...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		lead, err := scanLead(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Leads = append(result.Leads, *lead)
+	}
+
+	return result, rows.Err()
+}
+
+// ftsQuery quotes each term so input can't use FTS5 syntax, terms match as
+// prefixes e.g. "jan" matches Jane
+func ftsQuery(text string) string {
+	terms := []string{}
+	for _, term := range strings.Fields(text) {
+		terms = append(terms, fmt.Sprintf(`"%s"*`, strings.ReplaceAll(term, `"`, `""`)))
+	}
+
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,71 @@
+package leads
+
+import (
+	"context"
+	"fmt"
+	"skulpture/landing/database/databasetest"
+	enums "skulpture/landing/enums"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteStoreSearch(t *testing.T) {
+	store := NewSQLiteStore(databasetest.Open(t))
+	ctx := context.Background()
+
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	fixtures := []struct {
+		firstName, lastName, email, enquiry string
+		status                              enums.ProcessingStatus
+	}{
+		{"Jane", "Doe", "jane@example.com", "A new website for our café", enums.ProcessingConfirmed},
+		{"José", "Álvarez", "jose@example.es", "Sculpture commission", enums.ProcessingConfirmed},
+		{"Joe", "Bloggs", "joe@bloggs.co.uk", "Website redesign", enums.ProcessingFailed},
+		{"Spam", "Bot", "bot@spam.example", `Buy "cheap" OR NEAR(things)`, enums.ProcessingQuarantined},
+	}
+	for i, fixture := range fixtures {
+		assert.NoError(t, store.Create(ctx, &Lead{
+			Id:               fmt.Sprintf("9b2f8c4e-0000-4000-8000-00000000010%d", i),
+			FirstName:        fixture.firstName,
+			LastName:         fixture.lastName,
+			Email:            fixture.email,
+			Enquiry:          fixture.enquiry,
+			Environment:      string(enums.Test),
+			ProcessingStatus: fixture.status,
+			CreatedAt:        created.AddDate(0, 0, i),
+		}))
+	}
+
+	tests := []struct {
+		name     string
+		query    SearchQuery
+		expected []string
+		total    int
+	}{
+		{"everything newest first", SearchQuery{}, []string{"Spam", "Joe", "José", "Jane"}, 4},
+		{"prefix", SearchQuery{Text: "web"}, []string{"Joe", "Jane"}, 2},
+		{"every term", SearchQuery{Text: "website cafe"}, []string{"Jane"}, 1},
+		{"diacritics", SearchQuery{Text: "alvarez"}, []string{"José"}, 1},
+		{"email", SearchQuery{Text: "jane@example.com"}, []string{"Jane"}, 1},
+		{"syntax is literal", SearchQuery{Text: `"cheap" OR NEAR(`}, []string{"Spam"}, 1},
+		{"status", SearchQuery{Status: enums.ProcessingConfirmed}, []string{"José", "Jane"}, 2},
+		{"dates", SearchQuery{From: created.AddDate(0, 0, 1), To: created.AddDate(0, 0, 3)}, []string{"Joe", "José"}, 2},
+		{"page", SearchQuery{Limit: 1, Offset: 1}, []string{"Joe"}, 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := store.Search(ctx, test.query)
+			assert.NoError(t, err)
+			assert.Equal(t, test.total, result.Total)
+
+			names := []string{}
+			for _, lead := range result.Leads {
+				names = append(names, lead.FirstName)
+			}
+			assert.Equal(t, test.expected, names)
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package leads
+
+import (
+	"context"
+	"fmt"
+	enums "skulpture/landing/enums"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// SheetsStore appends leads as rows for sales to work from. Columns J to L
+// are written back as the lead's state, assignee and last change
+type SheetsStore struct {
+	service       *sheets.Service
+	spreadsheetId string
+	sheetName     string
+}
+
+func NewSheetsStore(service *sheets.Service, spreadsheetId string, sheetName string) *SheetsStore {
+	return &SheetsStore{
+		service:       service,
+		spreadsheetId: spreadsheetId,
+		sheetName:     sheetName,
+	}
+}
+
+func (s *SheetsStore) Create(ctx context.Context, lead *Lead) error {
+	sheetRange := s.sheetRange(lead, "A1")
+
+	_, err := s.service.
+		Spreadsheets.
+		Values.
+		Append(s.spreadsheetId, sheetRange, &sheets.ValueRange{
+			Values: [][]interface{}{
+				{
+					lead.Email,
+					lead.Id,
+					lead.FirstName,
+					lead.LastName,
+					lead.Mobile,
+					lead.EnquiryWithAttachments(),
+					strings.Join(lead.AttachmentLinks(), "\n"),
+					lead.Country,
+					string(lead.Policy),
+				},
+			},
+		}).
+		ValueInputOption("RAW").
+		InsertDataOption("INSERT_ROWS").
+		Context(ctx).
+		Do()
+
+	return err
+}
+
+// SetProcessingStatus is a no-op, the sheet only mirrors what was submitted
+func (s *SheetsStore) SetProcessingStatus(ctx context.Context, id string, status enums.ProcessingStatus) error {
+	return nil
+}
+
+// SetLifecycle writes the lead's state and assignee to its row, found by the
+// lead id in column B
+func (s *SheetsStore) SetLifecycle(ctx context.Context, lead *Lead) error {
+	ids, err := s.service.
+		Spreadsheets.
+		Values.
+		Get(s.spreadsheetId, s.sheetRange(lead, "B:B")).
+		MajorDimension("COLUMNS").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return err
+	}
+
+	row := 0
+	if len(ids.Values) > 0 {
+		for i, id := range ids.Values[0] {
+			if id == lead.Id {
+				row = i + 1
+
+				break
+			}
+		}
+	}
+
+	if row == 0 {
+		return fmt.Errorf("%w: no row for %s in sheet", ErrNotFound, lead.Id)
+	}
+
+	_, err = s.service.
+		Spreadsheets.
+		Values.
+		Update(s.spreadsheetId, s.sheetRange(lead, fmt.Sprintf("J%d:L%d", row, row)), &sheets.ValueRange{
+			Values: [][]interface{}{
+				{string(lead.State), lead.Assignee, lead.UpdatedAt.UTC().Format(time.RFC3339)},
+			},
+		}).
+		ValueInputOption("RAW").
+		Context(ctx).
+		Do()
+
+	return err
+}
+
+// SheetRow is a row of the spreadsheet, Number is as shown in the sheet
+type SheetRow struct {
+	Tab    string
+	Number int
+	Values []string
+}
+
+// RowsByEmail searches every tab for rows with the email in column A,
+// including rows appended before leads were kept in the database
+func (s *SheetsStore) RowsByEmail(ctx context.Context, email string) ([]SheetRow, error) {
+	return s.findRows(ctx, func(row []interface{}) bool {
+		return len(row) > 0 && strings.EqualFold(strings.TrimSpace(fmt.Sprint(row[0])), email)
+	})
+}
+
+// RowsByLead searches every tab for rows with one of the lead ids in
+// column B
+func (s *SheetsStore) RowsByLead(ctx context.Context, ids []string) ([]SheetRow, error) {
+	return s.findRows(ctx, func(row []interface{}) bool {
+		return len(row) > 1 && slices.Contains(ids, strings.TrimSpace(fmt.Sprint(row[1])))
+	})
+}
+
+// LeadIds is every lead id in column B, including leads appended before
+// they were kept in the database
+func (s *SheetsStore) LeadIds(ctx context.Context) ([]string, error) {
+	rows, err := s.findRows(ctx, func(row []interface{}) bool {
+		return len(row) > 1
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, strings.TrimSpace(row.Values[1]))
+	}
+
+	return ids, nil
+}
+
+// findRows reads every tab for the rows matching
+func (s *SheetsStore) findRows(ctx context.Context, match func(row []interface{}) bool) ([]SheetRow, error) {
+	spreadsheet, err := s.service.
+		Spreadsheets.
+		Get(s.spreadsheetId).
+		Fields("sheets.properties.title").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, err
+	}
+
+	tabs := []string{}
+	ranges := []string{}
+	for _, sheet := range spreadsheet.Sheets {
+		tabs = append(tabs, sheet.Properties.Title)
+		ranges = append(ranges, quoteTab(sheet.Properties.Title))
+	}
+
+	if len(ranges) == 0 {
+		return []SheetRow{}, nil
+	}
+
+	values, err := s.service.
+		Spreadsheets.
+		Values.
+		BatchGet(s.spreadsheetId).
+		Ranges(ranges...).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []SheetRow{}
+	for i, valueRange := range values.ValueRanges {
+		for n, row := range valueRange.Values {
+			if !match(row) {
+				continue
+			}
+
+			found := SheetRow{Tab: tabs[i], Number: n + 1, Values: make([]string, 0, len(row))}
+			for _, value := range row {
+				found.Values = append(found.Values, fmt.Sprint(value))
+			}
+
+			rows = append(rows, found)
+		}
+	}
+
+	return rows, nil
+}
+
+// ClearRows blanks the rows rather than deleting them, so row numbers
+// held elsewhere stay valid
+func (s *SheetsStore) ClearRows(ctx context.Context, rows []SheetRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	ranges := []string{}
+	for _, row := range rows {
+		ranges = append(ranges, fmt.Sprintf("%s!%d:%d", quoteTab(row.Tab), row.Number, row.Number))
+	}
+
+	_, err := s.service.
+		Spreadsheets.
+		Values.
+		BatchClear(s.spreadsheetId, &sheets.BatchClearValuesRequest{Ranges: ranges}).
+		Context(ctx).
+		Do()
+
+	return err
+}
+
+// sheetRange is a range in the lead's tab
+func (s *SheetsStore) sheetRange(lead *Lead, cells string) string {
+	sheetName := s.sheetName
+	if lead.Sheet != "" {
+		sheetName = lead.Sheet
+	}
+
+	return quoteTab(sheetName) + "!" + cells
+}
+
+// quoteTab quotes a tab name so tabs with spaces are valid A1 notation
+func quoteTab(name string) string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(name, "'", "''"))
+}
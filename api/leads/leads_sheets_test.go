@@ -0,0 +1,113 @@
+package leads
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	enums "skulpture/landing/enums"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestSheetsStoreSetLifecycle(t *testing.T) {
+	var updatedRange string
+	var updated sheets.ValueRange
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			assert.Equal(t, "/v4/spreadsheets/spreadsheet/values/'Sales team'!B:B", r.URL.Path)
+			json.NewEncoder(w).Encode(sheets.ValueRange{
+				MajorDimension: "COLUMNS",
+				Values:         [][]interface{}{{"Lead", "other-lead", "9b2f8c4e-lead"}},
+			})
+		case http.MethodPut:
+			updatedRange = r.URL.Path
+			json.NewDecoder(r.Body).Decode(&updated)
+			json.NewEncoder(w).Encode(sheets.UpdateValuesResponse{})
+		}
+	}))
+	defer server.Close()
+
+	service, err := sheets.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewSheetsStore(service, "spreadsheet", "Sheet1")
+	lead := &Lead{
+		Id:        "9b2f8c4e-lead",
+		Sheet:     "Sales team",
+		State:     enums.LeadContacted,
+		Assignee:  "joe@skulpture.xyz",
+		UpdatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	assert.NoError(t, store.SetLifecycle(context.Background(), lead))
+	assert.Equal(t, "/v4/spreadsheets/spreadsheet/values/'Sales team'!J3:L3", updatedRange)
+	assert.Equal(t, [][]interface{}{{"contacted", "joe@skulpture.xyz", "2026-01-02T03:04:05Z"}}, updated.Values)
+
+	lead.Id = "missing"
+	assert.ErrorIs(t, store.SetLifecycle(context.Background(), lead), ErrNotFound)
+}
+
+func TestSheetsStoreRowsByEmail(t *testing.T) {
+	var cleared sheets.BatchClearValuesRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4/spreadsheets/spreadsheet":
+			json.NewEncoder(w).Encode(sheets.Spreadsheet{Sheets: []*sheets.Sheet{
+				{Properties: &sheets.SheetProperties{Title: "Sheet1"}},
+				{Properties: &sheets.SheetProperties{Title: "Sales team"}},
+			}})
+		case "/v4/spreadsheets/spreadsheet/values:batchGet":
+			assert.Equal(t, []string{"'Sheet1'", "'Sales team'"}, r.URL.Query()["ranges"])
+			json.NewEncoder(w).Encode(sheets.BatchGetValuesResponse{ValueRanges: []*sheets.ValueRange{
+				{Values: [][]interface{}{{"Email", "Lead"}, {"jane@example.com", "lead-1"}, {"joe@example.com", "lead-2"}}},
+				{Values: [][]interface{}{{}, {" Jane@Example.com", "lead-3", "Jane"}}},
+			}})
+		case "/v4/spreadsheets/spreadsheet/values:batchClear":
+			json.NewDecoder(r.Body).Decode(&cleared)
+			json.NewEncoder(w).Encode(sheets.BatchClearValuesResponse{})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	service, err := sheets.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewSheetsStore(service, "spreadsheet", "Sheet1")
+
+	rows, err := store.RowsByEmail(context.Background(), "jane@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []SheetRow{
+		{Tab: "Sheet1", Number: 2, Values: []string{"jane@example.com", "lead-1"}},
+		{Tab: "Sales team", Number: 2, Values: []string{" Jane@Example.com", "lead-3", "Jane"}},
+	}, rows)
+
+	rows, err = store.RowsByLead(context.Background(), []string{"lead-2", "lead-3"})
+	assert.NoError(t, err)
+	assert.Equal(t, []SheetRow{
+		{Tab: "Sheet1", Number: 3, Values: []string{"joe@example.com", "lead-2"}},
+		{Tab: "Sales team", Number: 2, Values: []string{" Jane@Example.com", "lead-3", "Jane"}},
+	}, rows)
+
+	ids, err := store.LeadIds(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Lead", "lead-1", "lead-2", "lead-3"}, ids)
+
+	rows, err = store.RowsByEmail(context.Background(), "jane@example.com")
+	assert.NoError(t, err)
+	assert.NoError(t, store.ClearRows(context.Background(), rows))
+	assert.Equal(t, []string{"'Sheet1'!2:2", "'Sales team'!2:2"}, cleared.Ranges)
+}
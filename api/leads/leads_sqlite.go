@@ -0,0 +1,131 @@
+package leads
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	enums "skulpture/landing/enums"
+	"time"
+)
+
+// SQLiteStore is the source of truth for leads
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{
+		db: db,
+	}
+}
+
+func (s *SQLiteStore) Create(ctx context.Context, lead *Lead) error {
+	now := time.Now().UTC()
+	if lead.CreatedAt.IsZero() {
+		lead.CreatedAt = now
+	}
+	lead.UpdatedAt = now
+	if lead.State == "" {
+		lead.State = enums.LeadNew
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO leads (
+			id, email, first_name, last_name, mobile, enquiry, category, country, ip_address, policy,
+			environment, processing_status, route, sheet, state, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		lead.Id, lead.Email, lead.FirstName, lead.LastName, lead.Mobile, lead.Enquiry, lead.Category, lead.Country, lead.IpAddress, lead.Policy,
+		lead.Environment, lead.ProcessingStatus, lead.Route, lead.Sheet, lead.State, lead.CreatedAt, lead.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range lead.Attachments {
+		_, err = tx.ExecContext(ctx, `INSERT INTO attachments (
+				id, lead_id, backend, filename, content_type, size, link, created_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			attachment.Id, lead.Id, attachment.Backend, attachment.Filename, attachment.ContentType, attachment.Size, attachment.Link, lead.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) SetProcessingStatus(ctx context.Context, id string, status enums.ProcessingStatus) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE leads SET processing_status = ?, updated_at = ? WHERE id = ?", status, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(res)
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, id string) (*Lead, error) {
+	lead, err := scanLead(s.db.QueryRowContext(ctx, "SELECT "+leadColumns+" FROM leads WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lead.Attachments, err = s.attachments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return lead, nil
+}
+
+// leadColumns are read by scanLead
+const leadColumns = `id, email, first_name, last_name, mobile, enquiry, category, country, ip_address, policy,
+	environment, processing_status, route, sheet, state, assignee,
+	confirmation_message_id, email_status, email_status_at, created_at, updated_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLead(row scanner) (*Lead, error) {
+	lead := &Lead{}
+	err := row.Scan(
+		&lead.Id, &lead.Email, &lead.FirstName, &lead.LastName, &lead.Mobile, &lead.Enquiry, &lead.Category, &lead.Country, &lead.IpAddress, &lead.Policy,
+		&lead.Environment, &lead.ProcessingStatus, &lead.Route, &lead.Sheet, &lead.State, &lead.Assignee,
+		&lead.ConfirmationMessageId, &lead.EmailStatus, &lead.EmailStatusAt, &lead.CreatedAt, &lead.UpdatedAt,
+	)
+
+	return lead, err
+}
+
+func (s *SQLiteStore) attachments(ctx context.Context, leadId string) ([]Attachment, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, backend, filename, content_type, size, link
+		FROM attachments WHERE lead_id = ? AND message_id IS NULL ORDER BY created_at, filename`, leadId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAttachments(rows)
+}
+
+func expectAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package leads
+
+import (
+	"context"
+	"skulpture/landing/database/databasetest"
+	enums "skulpture/landing/enums"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	store := NewSQLiteStore(databasetest.Open(t))
+	ctx := context.Background()
+
+	lead := &Lead{
+		Id:               "9b2f8c4e-0000-4000-8000-000000000001",
+		Email:            "test@example.com",
+		FirstName:        "Test",
+		LastName:         "123",
+		Enquiry:          "Hello world",
+		Category:         "sales",
+		Country:          "NZ",
+		IpAddress:        "203.0.113.7",
+		Policy:           enums.PolicyAllow,
+		Environment:      string(enums.Test),
+		ProcessingStatus: enums.ProcessingReceived,
+		Route:            "briefs",
+		Attachments: []Attachment{
+			{Id: "file-1", Backend: string(enums.StorageLocal), Filename: "brief.pdf", Size: 4, Link: "file:///brief.pdf"},
+		},
+	}
+
+	assert.NoError(t, store.Create(ctx, lead))
+	assert.Error(t, store.Create(ctx, lead), "lead ids are unique")
+
+	assert.NoError(t, store.SetProcessingStatus(ctx, lead.Id, enums.ProcessingConfirmed))
+	assert.ErrorIs(t, store.SetProcessingStatus(ctx, "missing", enums.ProcessingConfirmed), ErrNotFound)
+
+	stored, err := store.Get(ctx, lead.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, lead.Email, stored.Email)
+	assert.Equal(t, lead.IpAddress, stored.IpAddress)
+	assert.Equal(t, lead.Category, stored.Category)
+	assert.Equal(t, lead.Route, stored.Route)
+	assert.Equal(t, enums.ProcessingConfirmed, stored.ProcessingStatus)
+	assert.Equal(t, lead.Attachments, stored.Attachments)
+	assert.WithinDuration(t, lead.CreatedAt, stored.CreatedAt, 0)
+	assert.Equal(t, "Hello world\nAttached files:\n- file:///brief.pdf", stored.EnquiryWithAttachments())
+
+	_, err = store.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
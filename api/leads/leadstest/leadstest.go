@@ -0,0 +1,110 @@
+// Package leadstest has the fakes and fixture shared by the tests of the jobs
+// that work across the database, stored files and the sheet
+package leadstest
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"skulpture/landing/database/databasetest"
+	"skulpture/landing/leads"
+	"skulpture/landing/storage"
+	"strings"
+	"testing"
+)
+
+// Sheets is a spreadsheet holding Rows for any lead or email, and Ids
+type Sheets struct {
+	Rows    []leads.SheetRow
+	Cleared []leads.SheetRow
+	Ids     []string
+	// Err is returned when reading the sheet
+	Err error
+}
+
+func (f *Sheets) RowsByLead(ctx context.Context, ids []string) ([]leads.SheetRow, error) {
+	return f.Rows, f.Err
+}
+
+func (f *Sheets) RowsByEmail(ctx context.Context, email string) ([]leads.SheetRow, error) {
+	return f.Rows, f.Err
+}
+
+func (f *Sheets) LeadIds(ctx context.Context) ([]string, error) {
+	return f.Ids, f.Err
+}
+
+func (f *Sheets) ClearRows(ctx context.Context, rows []leads.SheetRow) error {
+	f.Cleared = append(f.Cleared, rows...)
+
+	return nil
+}
+
+// Webhooks records the values forgotten
+type Webhooks struct {
+	Forgotten []string
+}
+
+func (f *Webhooks) Forget(ctx context.Context, value string) (int64, error) {
+	f.Forgotten = append(f.Forgotten, value)
+
+	return 1, nil
+}
+
+// Fixture is an empty database and local file store, tests add their leads
+type Fixture struct {
+	DB       *sql.DB
+	Store    *leads.SQLiteStore
+	Files    *storage.LocalStore
+	Sheets   *Sheets
+	Webhooks *Webhooks
+}
+
+func New(t testing.TB) *Fixture {
+	t.Helper()
+
+	db := databasetest.Open(t)
+
+	files, err := storage.NewLocalStore(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Fixture{
+		DB:       db,
+		Store:    leads.NewSQLiteStore(db),
+		Files:    files,
+		Sheets:   &Sheets{},
+		Webhooks: &Webhooks{},
+	}
+}
+
+// Put stores a file with the metadata, returning its id
+func (f *Fixture) Put(t testing.TB, filename string, body string, metadata map[string]string) string {
+	t.Helper()
+
+	stored, err := f.Files.Put(context.Background(), storage.Object{
+		Filename: filename,
+		Metadata: metadata,
+		Body:     strings.NewReader(body),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return stored.Id
+}
+
+// Exists is whether the file can still be opened
+func (f *Fixture) Exists(t testing.TB, id string) bool {
+	t.Helper()
+
+	reader, err := f.Files.Open(context.Background(), id)
+	if err != nil {
+		return false
+	}
+	io.Copy(io.Discard, reader)
+	reader.Close()
+
+	return true
+}
@@ -0,0 +1,195 @@
+package mailevents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	enums "skulpture/landing/enums"
+	"skulpture/landing/leads"
+	"time"
+
+	"github.com/mrz1836/postmark"
+)
+
+// METADATA_LEAD is the message metadata key holding the lead id, so events
+// can be matched when the message id isn't known, e.g. a failed update
+const METADATA_LEAD = "lead"
+
+// Postmark webhook payloads are small, anything bigger isn't from Postmark
+const MAX_BODY_SIZE = 1 << 20
+
+// Postmark's RecordType values
+// see: https://postmarkapp.com/developer/webhooks/webhooks-overview
+const (
+	RECORD_DELIVERY            = "Delivery"
+	RECORD_OPEN                = "Open"
+	RECORD_BOUNCE              = "Bounce"
+	RECORD_SPAM_COMPLAINT      = "SpamComplaint"
+	RECORD_SUBSCRIPTION_CHANGE = "SubscriptionChange"
+)
+
+const BOUNCE_HARD = "HardBounce"
+
+type Recorder interface {
+	SetEmailStatus(ctx context.Context, messageId string, leadId string, status enums.EmailStatus, at time.Time) error
+	Suppress(ctx context.Context, email string, reason string, messageId string) error
+	Unsuppress(ctx context.Context, email string) error
+}
+
+// Event is what a Postmark webhook means for the lead it refers to
+type Event struct {
+	RecordType string
+	MessageId  string
+	LeadId     string
+	Recipient  string
+	Status     enums.EmailStatus
+	At         time.Time
+	// Suppress is set when the recipient shouldn't be emailed again
+	Suppress bool
+	// Unsuppress is set when the recipient was reactivated in Postmark
+	Unsuppress bool
+	Reason     string
+}
+
+type subscriptionChangeEvent struct {
+	postmark.BaseEvent
+	Recipient         string
+	ChangedAt         time.Time
+	SuppressSending   bool
+	SuppressionReason string
+}
+
+// Parse reads a Postmark webhook payload. Record types without a status
+// are returned with an empty Status
+func Parse(body []byte) (*Event, error) {
+	var base postmark.BaseEvent
+	if err := json.Unmarshal(body, &base); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+
+	event := &Event{
+		RecordType: base.RecordType,
+		MessageId:  base.MessageID,
+	}
+	if lead, ok := base.Metadata[METADATA_LEAD].(string); ok {
+		event.LeadId = lead
+	}
+
+	var err error
+	switch base.RecordType {
+	case RECORD_DELIVERY:
+		var delivery postmark.DeliveryEvent
+		err = json.Unmarshal(body, &delivery)
+		event.Recipient, event.At, event.Status = delivery.Recipient, delivery.DeliveredAt, enums.EmailDelivered
+	case RECORD_OPEN:
+		var open postmark.OpenEvent
+		err = json.Unmarshal(body, &open)
+		event.Recipient, event.At, event.Status = open.Recipient, open.ReceivedAt, enums.EmailOpened
+	case RECORD_BOUNCE:
+		var bounce postmark.BounceEvent
+		err = json.Unmarshal(body, &bounce)
+		event.Recipient, event.At = bounce.Email, bounce.BouncedAt
+
+		// soft bounces are retried by Postmark and may still be delivered
+		event.Status = enums.EmailSoftBounced
+		if bounce.Type == BOUNCE_HARD {
+			event.Status = enums.EmailBounced
+			event.Suppress = true
+			event.Reason = fmt.Sprintf("hard bounce: %s", bounce.Description)
+		}
+	case RECORD_SPAM_COMPLAINT:
+		var complaint postmark.SpamComplaintEvent
+		err = json.Unmarshal(body, &complaint)
+		event.Recipient, event.At, event.Status = complaint.Email, complaint.BouncedAt, enums.EmailSpamComplaint
+		event.Suppress = true
+		event.Reason = "spam complaint"
+	case RECORD_SUBSCRIPTION_CHANGE:
+		var change subscriptionChangeEvent
+		err = json.Unmarshal(body, &change)
+		event.Recipient, event.At = change.Recipient, change.ChangedAt
+		if change.SuppressSending {
+			event.Suppress = true
+			event.Reason = fmt.Sprintf("suppressed by postmark: %s", change.SuppressionReason)
+		} else {
+			event.Unsuppress = true
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s event: %w", base.RecordType, err)
+	}
+
+	return event, nil
+}
+
+// Handler receives Postmark's delivery, open, bounce and spam complaint
+// webhooks. Events for messages we don't know about are acknowledged so
+// Postmark doesn't retry them, storage errors are retried
+func Handler(recorder Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MAX_BODY_SIZE))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+
+			return
+		}
+
+		event, err := Parse(body)
+		if err != nil {
+			slog.WarnContext(r.Context(), "warning", "postmark webhook", err.Error())
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := Record(r.Context(), recorder, event); err != nil {
+			slog.ErrorContext(r.Context(), "error", "postmark webhook", err.Error(), "type", event.RecordType, "message id", event.MessageId)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// Record applies an event, (un)suppressing the recipient before the lead's status
+// is updated since the lead may not be ours, e.g. a support notification
+func Record(ctx context.Context, recorder Recorder, event *Event) error {
+	if event.Suppress && event.Recipient != "" {
+		if err := recorder.Suppress(ctx, event.Recipient, event.Reason, event.MessageId); err != nil {
+			return err
+		}
+
+		slog.WarnContext(ctx, "suppressed", "email", event.Recipient, "reason", event.Reason)
+	}
+
+	if event.Unsuppress && event.Recipient != "" {
+		err := recorder.Unsuppress(ctx, event.Recipient)
+		if err != nil && !errors.Is(err, leads.ErrNotSuppressed) {
+			return err
+		}
+
+		if err == nil {
+			slog.InfoContext(ctx, "unsuppressed", "email", event.Recipient)
+		}
+	}
+
+	if event.Status == enums.EmailNone {
+		slog.DebugContext(ctx, "ignored", "postmark webhook", event.RecordType, "message id", event.MessageId)
+
+		return nil
+	}
+
+	err := recorder.SetEmailStatus(ctx, event.MessageId, event.LeadId, event.Status, event.At)
+	if errors.Is(err, leads.ErrNotFound) {
+		slog.DebugContext(ctx, "ignored", "postmark webhook", "unknown message", "message id", event.MessageId, "lead", event.LeadId)
+
+		return nil
+	}
+
+	return err
+}
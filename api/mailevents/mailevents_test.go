@@ -0,0 +1,210 @@
+package mailevents
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	enums "skulpture/landing/enums"
+	"skulpture/landing/leads"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type statusUpdate struct {
+	messageId string
+	leadId    string
+	status    enums.EmailStatus
+	at        time.Time
+}
+
+type fakeRecorder struct {
+	updates      []statusUpdate
+	suppressions map[string]string
+	err          error
+}
+
+func (f *fakeRecorder) SetEmailStatus(ctx context.Context, messageId string, leadId string, status enums.EmailStatus, at time.Time) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	f.updates = append(f.updates, statusUpdate{messageId, leadId, status, at})
+
+	return nil
+}
+
+func (f *fakeRecorder) Suppress(ctx context.Context, email string, reason string, messageId string) error {
+	if f.suppressions == nil {
+		f.suppressions = map[string]string{}
+	}
+	f.suppressions[email] = reason
+
+	return nil
+}
+
+func (f *fakeRecorder) Unsuppress(ctx context.Context, email string) error {
+	if _, ok := f.suppressions[email]; !ok {
+		return leads.ErrNotSuppressed
+	}
+	delete(f.suppressions, email)
+
+	return nil
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected Event
+	}{
+		{
+			name: "delivery",
+			body: `{"RecordType":"Delivery","MessageID":"m-1","Recipient":"jane@example.com","DeliveredAt":"2026-01-02T03:04:05Z","Metadata":{"lead":"lead-1"}}`,
+			expected: Event{
+				RecordType: RECORD_DELIVERY, MessageId: "m-1", LeadId: "lead-1", Recipient: "jane@example.com",
+				Status: enums.EmailDelivered, At: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+		},
+		{
+			name: "open",
+			body: `{"RecordType":"Open","MessageID":"m-1","Recipient":"jane@example.com","ReceivedAt":"2026-01-02T03:04:05Z","FirstOpen":true}`,
+			expected: Event{
+				RecordType: RECORD_OPEN, MessageId: "m-1", Recipient: "jane@example.com",
+				Status: enums.EmailOpened, At: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+		},
+		{
+			name: "hard bounce",
+			body: `{"RecordType":"Bounce","MessageID":"m-1","Type":"HardBounce","TypeCode":1,"Email":"jane@example.com","BouncedAt":"2026-01-02T03:04:05Z","Description":"Unknown user"}`,
+			expected: Event{
+				RecordType: RECORD_BOUNCE, MessageId: "m-1", Recipient: "jane@example.com",
+				Status: enums.EmailBounced, At: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+				Suppress: true, Reason: "hard bounce: Unknown user",
+			},
+		},
+		{
+			name: "soft bounce",
+			body: `{"RecordType":"Bounce","MessageID":"m-1","Type":"SoftBounce","TypeCode":4096,"Email":"jane@example.com","BouncedAt":"2026-01-02T03:04:05Z"}`,
+			expected: Event{
+				RecordType: RECORD_BOUNCE, MessageId: "m-1", Recipient: "jane@example.com",
+				Status: enums.EmailSoftBounced, At: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+		},
+		{
+			name: "spam complaint",
+			body: `{"RecordType":"SpamComplaint","MessageID":"m-1","Email":"jane@example.com","BouncedAt":"2026-01-02T03:04:05Z"}`,
+			expected: Event{
+				RecordType: RECORD_SPAM_COMPLAINT, MessageId: "m-1", Recipient: "jane@example.com",
+				Status: enums.EmailSpamComplaint, At: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+				Suppress: true, Reason: "spam complaint",
+			},
+		},
+		{
+			name: "subscription suppressed",
+			body: `{"RecordType":"SubscriptionChange","MessageID":"m-1","Recipient":"jane@example.com","ChangedAt":"2026-01-02T03:04:05Z","SuppressSending":true,"SuppressionReason":"ManualSuppression"}`,
+			expected: Event{
+				RecordType: RECORD_SUBSCRIPTION_CHANGE, MessageId: "m-1", Recipient: "jane@example.com",
+				At: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Suppress: true, Reason: "suppressed by postmark: ManualSuppression",
+			},
+		},
+		{
+			name: "subscription reactivated",
+			body: `{"RecordType":"SubscriptionChange","MessageID":"m-1","Recipient":"jane@example.com","ChangedAt":"2026-01-02T03:04:05Z","SuppressSending":false}`,
+			expected: Event{
+				RecordType: RECORD_SUBSCRIPTION_CHANGE, MessageId: "m-1", Recipient: "jane@example.com",
+				At: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Unsuppress: true,
+			},
+		},
+		{
+			name: "click is ignored",
+			body: `{"RecordType":"Click","MessageID":"m-1"}`,
+			expected: Event{
+				RecordType: "Click", MessageId: "m-1",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			event, err := Parse([]byte(test.body))
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, *event)
+		})
+	}
+
+	_, err := Parse([]byte(`{"RecordType":`))
+	assert.Error(t, err)
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		recorderErr  error
+		status       int
+		updates      int
+		suppressed   map[string]string
+		suppressions map[string]string
+	}{
+		{
+			name:    "delivery",
+			body:    `{"RecordType":"Delivery","MessageID":"m-1","Recipient":"jane@example.com"}`,
+			status:  http.StatusOK,
+			updates: 1,
+		},
+		{
+			name:         "hard bounce suppresses the recipient",
+			body:         `{"RecordType":"Bounce","MessageID":"m-1","Type":"HardBounce","Email":"jane@example.com","Description":"Unknown user"}`,
+			status:       http.StatusOK,
+			updates:      1,
+			suppressions: map[string]string{"jane@example.com": "hard bounce: Unknown user"},
+		},
+		{
+			name:         "unknown messages are acknowledged",
+			body:         `{"RecordType":"SpamComplaint","MessageID":"m-2","Email":"joe@example.com"}`,
+			recorderErr:  leads.ErrNotFound,
+			status:       http.StatusOK,
+			suppressions: map[string]string{"joe@example.com": "spam complaint"},
+		},
+		{
+			name:         "reactivation removes the suppression",
+			body:         `{"RecordType":"SubscriptionChange","MessageID":"m-1","Recipient":"jane@example.com","SuppressSending":false}`,
+			status:       http.StatusOK,
+			suppressed:   map[string]string{"jane@example.com": "hard bounce: Unknown user", "joe@example.com": "spam complaint"},
+			suppressions: map[string]string{"joe@example.com": "spam complaint"},
+		},
+		{
+			name:   "reactivating an address that isn't suppressed",
+			body:   `{"RecordType":"SubscriptionChange","MessageID":"m-1","Recipient":"jane@example.com","SuppressSending":false}`,
+			status: http.StatusOK,
+		},
+		{
+			name:        "storage errors are retried",
+			body:        `{"RecordType":"Open","MessageID":"m-1","Recipient":"jane@example.com"}`,
+			recorderErr: errors.New("database is locked"),
+			status:      http.StatusInternalServerError,
+		},
+		{
+			name:   "invalid json",
+			body:   `not json`,
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := &fakeRecorder{err: test.recorderErr, suppressions: test.suppressed}
+
+			res := httptest.NewRecorder()
+			Handler(recorder)(res, httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/postmark", strings.NewReader(test.body)))
+
+			assert.Equal(t, test.status, res.Code)
+			assert.Len(t, recorder.updates, test.updates)
+			assert.Equal(t, test.suppressions, recorder.suppressions)
+		})
+	}
+}
@@ -1,15 +1,50 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"mime/multipart"
+	"net"
 	"net/http"
+	"net/mail"
 	"os"
+	"os/signal"
+	"skulpture/landing/admin"
+	"skulpture/landing/admission"
+	"skulpture/landing/auth"
+	"skulpture/landing/chat"
+	"skulpture/landing/dashboard"
+	"skulpture/landing/database"
+	"skulpture/landing/emails"
 	enums "skulpture/landing/enums"
+	"skulpture/landing/events"
+	"skulpture/landing/geo"
+	"skulpture/landing/inbound"
+	"skulpture/landing/leads"
+	"skulpture/landing/mailevents"
+	"skulpture/landing/notify"
+	"skulpture/landing/orphans"
+	"skulpture/landing/privacy"
+	"skulpture/landing/quota"
+	"skulpture/landing/retention"
+	"skulpture/landing/routing"
+	"skulpture/landing/statuslink"
+	"skulpture/landing/storage"
+	"skulpture/landing/tlsconfig"
+	"skulpture/landing/turnstile"
+	"skulpture/landing/webhooks"
+	"skulpture/landing/worker"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/agoda-com/opentelemetry-go/otelslog"
@@ -33,8 +68,11 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace/noop"
@@ -44,8 +82,36 @@ import (
 
 var validate *validator.Validate
 var driveService *drive.Service
-var postmarkClient *postmark.Client
+var attachmentStore storage.Store
+var notifier notify.Notifier
 var sheetsService *sheets.Service
+var db *sql.DB
+var leadDatabase *leads.SQLiteStore
+
+// leadMirror is the sheet when LEAD_STORE is dual, nil otherwise
+var leadMirror leads.Mirror
+var leadStore leads.Store
+var admissionController *admission.Controller
+var workerPool *worker.Pool
+var router *routing.Router
+var chatNotifier *chat.Notifier
+var webhookDispatcher *webhooks.Dispatcher
+var eventPublisher *events.AsyncPublisher
+var eventEmitter *events.Emitter
+var adminAuthenticators []auth.Authenticator
+
+// retentionJob is nil when there's no policy for GO_ENV
+var retentionJob *retention.Job
+
+// storageQuota is nil when the storage backend has no limit
+var storageQuota *quota.Monitor
+
+// orphanReconciler is nil when the storage backend can't list files
+var orphanReconciler *orphans.Reconciler
+
+// statusLinks is nil when STATUS_LINK_KEYS isn't set
+var statusLinks *statuslink.Signer
+var privacyService *privacy.Service
 
 const MAX_REQUEST_SIZE = 20 << 20 // 20 MB
 const MAX_UPLOAD_SIZE = 15 << 20  // 15 MB
@@ -65,33 +131,302 @@ var (
 	OTEL_EXPORTER_OTLP_ENDPOINT = ferrite.
 					String("OTEL_EXPORTER_OTLP_ENDPOINT", "OpenTelemetry exporter endpoint").
 					Required()
+	NOTIFIER = ferrite.
+			Enum("NOTIFIER", "How emails are sent, smtp renders the local templates").
+			WithMembers(string(enums.NotifierPostmark), string(enums.NotifierSMTP)).
+			WithDefault(string(enums.NotifierPostmark)).
+			Required()
 	POSTMARK_TEMPLATE = ferrite.Signed[int]("POSTMARK_TEMPLATE", "Postmark template").
-				Required()
+				Required(ferrite.RelevantWhen(NOTIFIER, string(enums.NotifierPostmark)))
 	POSTMARK_FROM = ferrite.String("POSTMARK_FROM", "Postmark from").
 			WithDefault("hey@skulpture.xyz").
 			Required()
-	POSTMARK_SUPPORT_EMAIL = ferrite.String("POSTMARK_ADMIN_EMAIL", "Support email").
+	POSTMARK_SUPPORT_EMAIL = ferrite.String("POSTMARK_ADMIN_EMAIL", "Support email, used when no routing rule sets recipients").
+				Optional()
+	POSTMARK_WEBHOOK_USERNAME = ferrite.
+					String("POSTMARK_WEBHOOK_USERNAME", "Basic auth username Postmark's webhooks are configured with").
+					WithDefault("postmark").
+					Required()
+	POSTMARK_WEBHOOK_PASSWORD = ferrite.
+					String("POSTMARK_WEBHOOK_PASSWORD", "Basic auth password for Postmark's webhooks, /api/v1/webhooks/postmark is served when set").
+					WithSensitiveContent().
+					Optional()
+	ADMIN_OIDC_ISSUER = ferrite.
+				URL("ADMIN_OIDC_ISSUER", "OpenID Connect issuer whose tokens are accepted by the admin API, API keys only when not set").
+				Optional()
+	ADMIN_OIDC_AUDIENCE = ferrite.
+				String("ADMIN_OIDC_AUDIENCE", "Client id admin API tokens must be issued for").
+				Optional()
+	ADMIN_OIDC_ALLOWED_EMAILS = ferrite.
+					String("ADMIN_OIDC_ALLOWED_EMAILS", "Comma separated emails or @domains allowed to use the admin API, e.g. @skulpture.xyz, required with ADMIN_OIDC_ISSUER").
+					Optional()
+	STATUS_LINK_KEYS = ferrite.
+				String("STATUS_LINK_KEYS", "Comma separated id=secret keys signing enquiry status links, the first signs, status links are left out when not set").
+				WithSensitiveContent().
+				Optional()
+	STATUS_LINK_BASE_URL = ferrite.
+				URL("STATUS_LINK_BASE_URL", "Public URL of this API status links point to, e.g. https://api.skulpture.xyz").
+				Optional()
+	STATUS_LINK_TTL = ferrite.
+			Duration("STATUS_LINK_TTL", "How long status links are valid for").
+			WithMinimum(time.Hour).
+			WithDefault(90 * 24 * time.Hour).
+			Required()
+	RETENTION_POLICIES = ferrite.
+				String("RETENTION_POLICIES", "Comma separated environment[:attachments|leads]=age retention policies, e.g. development=7d,production:attachments=730d, nothing is purged when GO_ENV has none").
+				Optional()
+	RETENTION_ACTION = ferrite.
+				Enum("RETENTION_ACTION", "What happens to attachments past their retention, leads are always deleted").
+				WithMembers(string(enums.RetentionDelete), string(enums.RetentionArchive)).
+				WithDefault(string(enums.RetentionDelete)).
+				Required()
+	RETENTION_DRY_RUN = ferrite.
+				Bool("RETENTION_DRY_RUN", "Log what the retention policy would remove without removing it").
+				WithDefault(false).
+				Required()
+	RETENTION_INTERVAL = ferrite.
+				Duration("RETENTION_INTERVAL", "How often the retention policy is applied, by one replica at a time").
+				WithMinimum(time.Minute).
+				WithDefault(24 * time.Hour).
+				Required()
+	ORPHAN_ACTION = ferrite.
+			Enum("ORPHAN_ACTION", "What happens to stored files whose enquiry was never recorded, delete requires LEAD_STORE dual so the sheet is checked too").
+			WithMembers(string(enums.OrphanReport), string(enums.OrphanDelete)).
+			WithDefault(string(enums.OrphanReport)).
+			Required()
+	ORPHAN_GRACE_PERIOD = ferrite.
+				Duration("ORPHAN_GRACE_PERIOD", "How old a file must be before it can be an orphan").
+				WithMinimum(time.Hour).
+				WithDefault(24 * time.Hour).
+				Required()
+	ORPHAN_RECONCILE_INTERVAL = ferrite.
+					Duration("ORPHAN_RECONCILE_INTERVAL", "How often stored files are checked for orphans, by one replica at a time").
+					WithMinimum(time.Minute).
+					WithDefault(6 * time.Hour).
+					Required()
+	INBOUND_REPLY_ADDRESS = ferrite.
+				String("INBOUND_REPLY_ADDRESS", "Postmark inbound address confirmations are replied to, plus addressed with the lead id").
+				Optional()
+	CHAT_WEBHOOK_URLS = ferrite.
+				String("CHAT_WEBHOOK_URLS", "Comma separated Slack or Discord incoming webhook URLs for new leads").
+				WithSensitiveContent().
+				Optional()
+	WEBHOOKS_FILE = ferrite.
+			String("WEBHOOKS_FILE", "YAML file of signed webhook subscriptions for enquiry events").
+			Optional()
+	WEBHOOKS = ferrite.
+			String("WEBHOOKS", "Inline YAML webhook subscriptions, used when WEBHOOKS_FILE is not set").
+			WithSensitiveContent().
+			Optional()
+	WEBHOOK_MAX_ATTEMPTS = ferrite.
+				Signed[int]("WEBHOOK_MAX_ATTEMPTS", "Attempts before a webhook delivery is marked failed").
+				WithMinimum(1).
+				WithDefault(webhooks.MAX_ATTEMPTS).
+				Required()
+	WEBHOOK_POLL_INTERVAL = ferrite.
+				Duration("WEBHOOK_POLL_INTERVAL", "How often due webhook retries are checked for").
+				WithDefault(10 * time.Second).
+				Required()
+	CLOUDEVENTS_PUBLISHER = ferrite.
+				Enum("CLOUDEVENTS_PUBLISHER", "Where enquiry lifecycle CloudEvents are published").
+				WithMembers(string(enums.EventPublisherNone), string(enums.EventPublisherStdout), string(enums.EventPublisherHTTP)).
+				WithDefault(string(enums.EventPublisherNone)).
+				Required()
+	CLOUDEVENTS_URL = ferrite.
+			URL("CLOUDEVENTS_URL", "Endpoint CloudEvents are POSTed to in structured mode").
+			Required(ferrite.RelevantWhen(CLOUDEVENTS_PUBLISHER, string(enums.EventPublisherHTTP)))
+	ROUTING_RULES_FILE = ferrite.
+				String("ROUTING_RULES_FILE", "YAML file of rules routing enquiries to recipients, sheet tabs and storage folders").
 				Optional()
+	ROUTING_RULES = ferrite.
+			String("ROUTING_RULES", "Inline YAML routing rules, used when ROUTING_RULES_FILE is not set").
+			Optional()
+	SUPPORT_EMBED_MAX_SIZE = ferrite.
+				Signed[int64]("SUPPORT_EMBED_MAX_SIZE", "Attachments up to this many bytes are attached to the support email as well as linked, 0 disables").
+				WithMinimum(0).
+				WithMaximum(notify.MAX_MESSAGE_SIZE).
+				WithDefault(2 << 20).
+				Required()
 	POSTMARK_SERVER_TOKEN = ferrite.
 				String("POSTMARK_SERVER_TOKEN", "Postmark server token").
-				Required()
+				Required(ferrite.RelevantWhen(NOTIFIER, string(enums.NotifierPostmark)))
 	POSTMARK_ACCOUNT_TOKEN = ferrite.
 				String("POSTMARK_ACCOUNT_TOKEN", "Postmark account token").
-				Required()
+				Required(ferrite.RelevantWhen(NOTIFIER, string(enums.NotifierPostmark)))
+	SMTP_HOST = ferrite.
+			String("SMTP_HOST", "SMTP host").
+			Required(ferrite.RelevantWhen(NOTIFIER, string(enums.NotifierSMTP)))
+	SMTP_PORT = ferrite.
+			NetworkPort("SMTP_PORT", "SMTP port").
+			WithDefault("587").
+			Required(ferrite.RelevantWhen(NOTIFIER, string(enums.NotifierSMTP)))
+	SMTP_USERNAME = ferrite.
+			String("SMTP_USERNAME", "SMTP username, authenticates when set").
+			Optional(ferrite.RelevantWhen(NOTIFIER, string(enums.NotifierSMTP)))
+	SMTP_PASSWORD = ferrite.
+			String("SMTP_PASSWORD", "SMTP password").
+			WithSensitiveContent().
+			Optional(ferrite.RelevantWhen(NOTIFIER, string(enums.NotifierSMTP)))
+	SMTP_SECURITY = ferrite.
+			Enum("SMTP_SECURITY", "SMTP transport security, tls is implicit TLS e.g. port 465").
+			WithMembers(string(enums.SMTPSecurityNone), string(enums.SMTPSecurityStartTLS), string(enums.SMTPSecurityTLS)).
+			WithDefault(string(enums.SMTPSecurityStartTLS)).
+			Required(ferrite.RelevantWhen(NOTIFIER, string(enums.NotifierSMTP)))
+	STORAGE_BACKEND = ferrite.
+			Enum("STORAGE_BACKEND", "Where attachments are stored").
+			WithMembers(string(enums.StorageGoogleDrive), string(enums.StorageLocal), string(enums.StorageS3)).
+			WithDefault(string(enums.StorageGoogleDrive)).
+			Required()
 	GDRIVE_FOLDER_ID = ferrite.
 				String("GDRIVE_FOLDER_ID", "Google drive folder id").
+				Required(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageGoogleDrive)))
+	GDRIVE_ARCHIVE_FOLDER_ID = ferrite.
+					String("GDRIVE_ARCHIVE_FOLDER_ID", "Google drive folder files are moved to when RETENTION_ACTION is archive").
+					Optional(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageGoogleDrive)))
+	STORAGE_QUOTA_CACHE_TTL = ferrite.
+				Duration("STORAGE_QUOTA_CACHE_TTL", "How long a Google drive or S3 storage quota reading is used before it's fetched again").
+				WithMinimum(time.Second).
+				WithDefault(5 * time.Minute).
 				Required()
+	STORAGE_QUOTA_WARN_THRESHOLDS = ferrite.
+					String("STORAGE_QUOTA_WARN_THRESHOLDS", "Comma separated percentages of the storage quota that alert CHAT_WEBHOOK_URLS as they're crossed").
+					WithDefault("80,95").
+					Required()
+	STORAGE_LOCAL_PATH = ferrite.
+				String("STORAGE_LOCAL_PATH", "Directory for attachments").
+				WithDefault("attachments").
+				Required(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageLocal)))
+	STORAGE_LOCAL_BASE_URL = ferrite.
+				String("STORAGE_LOCAL_BASE_URL", "Base URL serving STORAGE_LOCAL_PATH, links are file:// URLs otherwise").
+				Optional(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageLocal)))
+	S3_ENDPOINT = ferrite.
+			String("S3_ENDPOINT", "S3 compatible endpoint host, e.g. <account>.r2.cloudflarestorage.com or storage.googleapis.com").
+			Required(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageS3)))
+	S3_REGION = ferrite.
+			String("S3_REGION", "S3 region").
+			WithDefault("auto").
+			Required(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageS3)))
+	S3_BUCKET = ferrite.
+			String("S3_BUCKET", "S3 bucket").
+			Required(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageS3)))
+	S3_ACCESS_KEY_ID = ferrite.
+				String("S3_ACCESS_KEY_ID", "S3 access key id").
+				Required(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageS3)))
+	S3_SECRET_ACCESS_KEY = ferrite.
+				String("S3_SECRET_ACCESS_KEY", "S3 secret access key").
+				Required(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageS3)))
+	S3_USE_SSL = ferrite.
+			Bool("S3_USE_SSL", "Connect to S3 over HTTPS").
+			WithDefault(true).
+			Required(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageS3)))
+	S3_PREFIX = ferrite.
+			String("S3_PREFIX", "Key prefix for attachments").
+			Optional(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageS3)))
+	S3_PUBLIC_URL = ferrite.
+			String("S3_PUBLIC_URL", "Public base URL of the bucket, S3_LINK_URL is used otherwise").
+			Optional(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageS3)))
+	S3_LINK_URL = ferrite.
+			String("S3_LINK_URL", "Base URL of this API's /attachments route for private buckets, e.g. https://landing-api-master.skulpture.xyz/attachments, which admins follow to a presigned link").
+			Optional(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageS3)))
+	S3_LINK_EXPIRY = ferrite.
+			Duration("S3_LINK_EXPIRY", "How long presigned links are valid for once S3_LINK_URL redirects to them, at most 7 days").
+			WithMaximum(storage.MAX_LINK_EXPIRY).
+			WithDefault(15 * time.Minute).
+			Required(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageS3)))
+	S3_ARCHIVE_PREFIX = ferrite.
+				String("S3_ARCHIVE_PREFIX", "Key prefix objects are moved under when RETENTION_ACTION is archive").
+				Optional(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageS3)))
+	S3_QUOTA_LIMIT = ferrite.
+			Signed[int64]("S3_QUOTA_LIMIT", "Bytes that may be stored under S3_PREFIX, uploads that don't fit are refused, unlimited when not set").
+			WithMinimum(1).
+			Optional(ferrite.RelevantWhen(STORAGE_BACKEND, string(enums.StorageS3)))
+	DATABASE_PATH = ferrite.
+			String("DATABASE_PATH", "SQLite database path").
+			WithDefault("landing.db").
+			Required()
+	LEAD_STORE = ferrite.
+			Enum("LEAD_STORE", "Where leads are recorded, dual mirrors the database to Google sheets").
+			WithMembers(string(enums.LeadStoreSQLite), string(enums.LeadStoreDual)).
+			WithDefault(string(enums.LeadStoreDual)).
+			Required()
 	GSHEETS_SPREADSHEET_ID = ferrite.String("GSHEETS_SPREADSHEET_ID", "Google sheets spreadsheet id").
-				Required()
+				Required(ferrite.RelevantWhen(LEAD_STORE, string(enums.LeadStoreDual)))
 	GSHEETS_SHEET_NAME = ferrite.
 				String("GSHEETS_SHEET_NAME", "Google sheets sheet name").
 				WithDefault("Sheet1").
-				Required()
+				Required(ferrite.RelevantWhen(LEAD_STORE, string(enums.LeadStoreDual)))
 	GO_ENV = ferrite.
 		Enum("GO_ENV", "Golang environment").
 		WithMembers(string(enums.Production), string(enums.Development), string(enums.Test)).
 		WithDefault(string(enums.Development)).
 		Required()
+	GEO_DEFAULT_POLICY = ferrite.
+				Enum("GEO_DEFAULT_POLICY", "Policy for countries without a rule").
+				WithMembers(string(enums.PolicyAllow), string(enums.PolicyChallenge), string(enums.PolicyQuarantine), string(enums.PolicyBlock)).
+				WithDefault(string(enums.PolicyAllow)).
+				Required()
+	GEO_COUNTRY_POLICIES = ferrite.
+				String("GEO_COUNTRY_POLICIES", "Per country policies from CF-IPCountry, e.g. CN=block,T1=challenge,XX=quarantine").
+				Optional()
+	CLOUDFLARE_TURNSTILE_SECRET = ferrite.
+					String("CLOUDFLARE_TURNSTILE_SECRET", "Cloudflare Turnstile secret for challenged countries").
+					Optional()
+	TLS_CERT_FILE = ferrite.
+			String("TLS_CERT_FILE", "PEM certificate path, serves HTTPS when set").
+			Optional()
+	TLS_KEY_FILE = ferrite.
+			String("TLS_KEY_FILE", "PEM private key path, serves HTTPS when set").
+			Optional()
+	TLS_RELOAD_INTERVAL = ferrite.
+				Duration("TLS_RELOAD_INTERVAL", "How often certificate files are checked for changes").
+				WithDefault(time.Minute).
+				Required()
+	LISTEN_ADDRESS = ferrite.
+			String("LISTEN_ADDRESS", "Bind address, defaults to :443 with TLS and :80 without").
+			Optional()
+	ENABLE_H2C = ferrite.
+			Bool("ENABLE_H2C", "Accept HTTP/2 without TLS (h2c) for internal traffic").
+			WithDefault(false).
+			Required()
+	ADMISSION_MAX_PARSES = ferrite.
+				Signed[int64]("ADMISSION_MAX_PARSES", "Maximum concurrent multipart form parses").
+				WithMinimum(1).
+				WithDefault(4).
+				Required()
+	ADMISSION_MAX_INFLIGHT_BYTES = ferrite.
+					Signed[int64]("ADMISSION_MAX_INFLIGHT_BYTES", "Maximum request bytes buffered or uploading at once").
+					WithMinimum(MAX_REQUEST_SIZE).
+					WithDefault(3 * MAX_REQUEST_SIZE).
+					Required()
+	// capture jobs run on the worker pool alongside other jobs, keeping this
+	// below WORKER_CONCURRENCY + WORKER_QUEUE_SIZE means an enquiry is turned
+	// away here before its files are uploaded rather than by a full queue after
+	ADMISSION_MAX_JOBS = ferrite.
+				Signed[int64]("ADMISSION_MAX_JOBS", "Maximum background capture jobs queued or running, keep below WORKER_CONCURRENCY + WORKER_QUEUE_SIZE").
+				WithMinimum(1).
+				WithDefault(32).
+				Required()
+	ADMISSION_RETRY_AFTER = ferrite.
+				Duration("ADMISSION_RETRY_AFTER", "Retry-After sent when the server is at capacity").
+				WithDefault(30 * time.Second).
+				Required()
+	WORKER_CONCURRENCY = ferrite.
+				Signed[int]("WORKER_CONCURRENCY", "Background jobs run at once").
+				WithMinimum(1).
+				WithDefault(4).
+				Required()
+	WORKER_QUEUE_SIZE = ferrite.
+				Signed[int]("WORKER_QUEUE_SIZE", "Background jobs waiting before new work is rejected").
+				WithMinimum(1).
+				WithDefault(64).
+				Required()
+	SHUTDOWN_TIMEOUT = ferrite.
+				Duration("SHUTDOWN_TIMEOUT", "Time allowed for requests and background jobs to finish on shutdown").
+				WithDefault(30 * time.Second).
+				Required()
+	CLOUDFLARE_ORIGIN_PULL_CA = ferrite.
+					String("CLOUDFLARE_ORIGIN_PULL_CA", "Cloudflare origin pull CA bundle path, requires TLS_CERT_FILE and TLS_KEY_FILE").
+					Optional()
 )
 
 func init() {
@@ -101,16 +436,36 @@ func init() {
 }
 
 func main() {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	driveService = createGoogleDriveService(ctx)
-	postmarkClient = createPostmarkClient(ctx)
-	sheetsService = createGoogleSheetsService(ctx)
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(ctx, os.Args[1:]))
+	}
+
+	attachmentStore = createAttachmentStore(ctx)
+	notifier = createNotifier(ctx)
+	db = createDatabase(ctx)
+	leadStore = createLeadStore(ctx)
+	webhookDispatcher = createWebhookDispatcher(ctx)
+	admissionController = createAdmissionController(ctx)
+	router = createRouter(ctx)
+	chatNotifier = createChatNotifier(ctx)
+	eventPublisher = createEventPublisher(ctx)
+	// the service name tells environments apart, e.g. landing-api-prod
+	eventEmitter = events.NewEmitter("/"+OTEL_SERVICE_NAME.Value(), eventPublisher)
+	adminAuthenticators = createAdminAuthenticators(ctx)
+	statusLinks = createStatusLinks(ctx)
+	privacyService = createPrivacyService(ctx)
+	retentionJob = createRetentionJob(ctx)
+	orphanReconciler = createOrphanReconciler(ctx)
 
 	r := chi.NewRouter()
 
 	cleanup := initOtel(ctx, r)
-	defer cleanup(ctx)
+
+	workerPool = worker.New("background", WORKER_CONCURRENCY.Value(), WORKER_QUEUE_SIZE.Value())
+	storageQuota = createStorageQuota(ctx)
 
 	r.Use(middleware.RequestID)
 	r.Use(middleware.Heartbeat("/ping"))
@@ -150,38 +505,239 @@ func main() {
 	}
 
 	// requests are proxied by nginx
-	middleware, err := httplimit.NewMiddleware(store, httplimit.IPKeyFunc("X-Forwarded-For"))
+	rateLimiter, err := httplimit.NewMiddleware(store, httplimit.IPKeyFunc("X-Forwarded-For"))
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "init", err.Error())
+		panic(err)
+	}
+
+	countryRules, _ := GEO_COUNTRY_POLICIES.Value()
+	geoPolicies, err := geo.ParsePolicies(countryRules, enums.CountryPolicy(GEO_DEFAULT_POLICY.Value()))
 	if err != nil {
 		slog.ErrorContext(ctx, "error", "init", err.Error())
 		panic(err)
 	}
+
+	if _, ok := CLOUDFLARE_TURNSTILE_SECRET.Value(); !ok && geoPolicies.Has(enums.PolicyChallenge) {
+		slog.WarnContext(ctx, "warning", "geo", "challenge policy configured without CLOUDFLARE_TURNSTILE_SECRET, challenged requests will be rejected")
+	}
+
 	r.Route("/api/v1", func(r chi.Router) {
-		r.Use(middleware.Handle)
+		r.With(rateLimiter.Handle, geo.Middleware(geoPolicies), admissionController.Middleware(MAX_REQUEST_SIZE)).Post("/contact", handler)
+		if statusLinks != nil {
+			r.With(rateLimiter.Handle).Get("/contact/{uuid}", statuslink.Handler(leadDatabase, statusLinks))
+		}
+
+		r.With(auth.Middleware(adminAuthenticators...)).Mount("/admin", admin.Handler(leadDatabase, leadMirror, privacyService, orphanReconciler))
 
-		r.Post("/contact", handler)
+		// not rate limited, Postmark sends events in bursts
+		if password, ok := POSTMARK_WEBHOOK_PASSWORD.Value(); ok {
+			credentials := map[string]string{POSTMARK_WEBHOOK_USERNAME.Value(): password}
+			r.With(middleware.BasicAuth("postmark", credentials)).Post("/webhooks/postmark", mailevents.Handler(leadDatabase))
+			r.With(middleware.BasicAuth("postmark", credentials)).Post("/webhooks/postmark/inbound", inboundHandler)
+		}
 	})
 
-	http.ListenAndServe(":80", r)
+	r.With(auth.BasicMiddleware(adminAuthenticators...)).Mount("/dashboard", dashboard.Handler(dashboard.Config{
+		Path:    "/dashboard",
+		Store:   leadDatabase,
+		Mirror:  leadMirror,
+		Release: releaseEnquiry,
+	}))
+
+	if _, ok := attachmentStore.(storage.Presigner); ok {
+		r.With(auth.BasicMiddleware(adminAuthenticators...)).Get("/attachments/*", attachmentHandler)
+	}
+
+	if GO_ENV.Value() != string(enums.Production) {
+		// ?lead= renders a stored lead's details, so it's for staff only
+		r.With(auth.BasicMiddleware(adminAuthenticators...)).Get("/dev/emails/{name}", emailPreviewHandler)
+	}
+
+	// the loops use the database, so they're waited for before it's closed
+	var background sync.WaitGroup
+	runInBackground := func(run func()) {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			run()
+		}()
+	}
+
+	runInBackground(func() { webhookDispatcher.Run(ctx, WEBHOOK_POLL_INTERVAL.Value()) })
+	if retentionJob != nil {
+		runInBackground(func() { retentionJob.Run(ctx, RETENTION_INTERVAL.Value()) })
+	}
+	if orphanReconciler != nil {
+		runInBackground(func() { orphanReconciler.Run(ctx, ORPHAN_RECONCILE_INTERVAL.Value()) })
+	}
+
+	server, serve := listen(ctx, r)
+	go func() {
+		if err := serve(); !errors.Is(err, http.ErrServerClosed) {
+			slog.ErrorContext(ctx, "error", "listen", err.Error())
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT.Value())
+	defer cancel()
+
+	// stop taking requests first so nothing new is queued while draining
+	serverErr := server.Shutdown(shutdownCtx)
+	poolErr := workerPool.Shutdown(shutdownCtx)
+	backgroundErr := wait(shutdownCtx, &background)
+	eventsErr := eventPublisher.Close(shutdownCtx)
+	otelErr := cleanup(shutdownCtx)
+
+	// jobs that didn't stop in time would fail on a closed database, SQLite
+	// recovers from its WAL when the process exits without closing it
+	var dbErr error
+	if poolErr == nil && backgroundErr == nil {
+		dbErr = db.Close()
+	} else {
+		slog.WarnContext(shutdownCtx, "warning", "shutdown", "jobs still running, leaving the database open")
+	}
+
+	if err := errors.Join(serverErr, poolErr, backgroundErr, eventsErr, otelErr, dbErr); err != nil {
+		slog.ErrorContext(shutdownCtx, "error", "shutdown", err.Error())
+	}
+}
+
+// wait returns ctx's error if it ends before the group is done
+func wait(ctx context.Context, group *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		group.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func listen(ctx context.Context, handler http.Handler) (*http.Server, func() error) {
+	certFile, hasCert := TLS_CERT_FILE.Value()
+	keyFile, hasKey := TLS_KEY_FILE.Value()
+	originPullCA, hasOriginPull := CLOUDFLARE_ORIGIN_PULL_CA.Value()
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(ENABLE_H2C.Value())
+
+	server := &http.Server{
+		Handler:   handler,
+		Protocols: protocols,
+	}
+
+	if !hasCert || !hasKey {
+		if hasOriginPull {
+			err := errors.New("CLOUDFLARE_ORIGIN_PULL_CA requires TLS_CERT_FILE and TLS_KEY_FILE")
+			slog.ErrorContext(ctx, "error", "init", err.Error())
+			panic(err)
+		}
+
+		server.Addr = listenAddress(":80")
+
+		slog.DebugContext(ctx, "listen", "address", server.Addr, "h2c", ENABLE_H2C.Value())
+
+		return server, server.ListenAndServe
+	}
+
+	certificates, err := tlsconfig.NewReloader(certFile, keyFile)
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "tls", err.Error())
+		panic(err)
+	}
+
+	// renewed certificates are picked up in place,
+	// no need to restart the service
+	go certificates.Watch(ctx, TLS_RELOAD_INTERVAL.Value())
+
+	tlsConfig := tlsconfig.New(certificates)
+
+	if hasOriginPull {
+		// only Cloudflare can present a certificate signed by the origin pull CA,
+		// so requests made directly to the instance address fail the handshake
+		if err := tlsconfig.RequireOriginPull(tlsConfig, originPullCA); err != nil {
+			slog.ErrorContext(ctx, "error", "tls", err.Error())
+			panic(err)
+		}
+
+		slog.DebugContext(ctx, "require authenticated origin pulls", "ca", originPullCA)
+	}
+
+	server.Addr = listenAddress(":443")
+	server.TLSConfig = tlsConfig
+
+	slog.DebugContext(ctx, "listen", "address", server.Addr, "tls", certFile)
+
+	return server, func() error {
+		return server.ListenAndServeTLS("", "")
+	}
+}
+
+func listenAddress(fallback string) string {
+	address, ok := LISTEN_ADDRESS.Value()
+	if !ok {
+		return fallback
+	}
+
+	return address
 }
 
 func handler(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, MAX_REQUEST_SIZE)
-	if err := r.ParseMultipartForm(MAX_UPLOAD_SIZE); err != nil {
+
+	if !admissionController.Parses.TryAcquire(1) {
+		admissionController.Reject(w, r, admissionController.Parses)
+
+		return
+	}
+
+	err := r.ParseMultipartForm(MAX_UPLOAD_SIZE)
+	admissionController.Parses.Release(1)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 
 		return
 	}
 	defer r.MultipartForm.RemoveAll()
 
+	decision := geo.FromContext(r.Context())
+	if decision.Policy == enums.PolicyChallenge {
+		secret, ok := CLOUDFLARE_TURNSTILE_SECRET.Value()
+		if !ok {
+			slog.ErrorContext(r.Context(), "error", "turnstile", "secret not configured", "country", decision.Country)
+			http.Error(w, "Unable to verify challenge", http.StatusForbidden)
+
+			return
+		}
+
+		err := turnstile.New(secret, turnstile.VERIFY_URL).Verify(r.Context(), r.FormValue(turnstile.FORM_FIELD), clientIp(r))
+		if err != nil {
+			slog.WarnContext(r.Context(), "challenge", "turnstile", err.Error(), "country", decision.Country)
+			http.Error(w, "Challenge verification failed", http.StatusForbidden)
+
+			return
+		}
+	}
+
 	var body struct {
-		uuid                  string
-		Email                 string `json:"email" validate:"required,email"`
-		Mobile                string `json:"mobile" validate:"omitempty,e164"`
-		FirstName             string `json:"firstName" validate:"required"`
-		LastName              string `json:"lastName" validate:"required"`
-		Enquiry               string `json:"enquiry" validate:"required"`
-		driveFiles            []*drive.File
-		driveFileWebViewLinks []string
+		uuid      string
+		Email     string `json:"email" validate:"required,email"`
+		Mobile    string `json:"mobile" validate:"omitempty,e164"`
+		FirstName string `json:"firstName" validate:"required"`
+		LastName  string `json:"lastName" validate:"required"`
+		Enquiry   string `json:"enquiry" validate:"required"`
+		Category  string `json:"category" validate:"omitempty,max=64"`
 	}
 
 	body.uuid = uuid.NewString()
@@ -190,10 +746,11 @@ func handler(w http.ResponseWriter, r *http.Request) {
 	body.FirstName = r.FormValue("firstName")
 	body.LastName = r.FormValue("lastName")
 	body.Enquiry = r.FormValue("enquiry")
+	body.Category = r.FormValue("category")
 
 	slog.DebugContext(r.Context(), "begin", "enquiry", fmt.Sprintf("%+v", body))
 
-	err := validate.Struct(body)
+	err = validate.Struct(body)
 	if err != nil {
 		validationErrs := err.(validator.ValidationErrors)
 
@@ -210,25 +767,81 @@ func handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lead := &leads.Lead{
+		Id:               body.uuid,
+		Email:            body.Email,
+		Mobile:           body.Mobile,
+		FirstName:        body.FirstName,
+		LastName:         body.LastName,
+		Enquiry:          body.Enquiry,
+		Category:         body.Category,
+		Country:          decision.Country,
+		IpAddress:        clientIp(r),
+		Policy:           decision.Policy,
+		Environment:      GO_ENV.Value(),
+		ProcessingStatus: enums.ProcessingReceived,
+	}
+
+	// reserved before uploading so files aren't left behind
+	// for an enquiry that can't be captured
+	if !admissionController.Jobs.TryAcquire(1) {
+		admissionController.Reject(w, r, admissionController.Jobs)
+
+		return
+	}
+
 	files := r.MultipartForm.File["files"]
+	embedded := []notify.Attachment{}
+
+	route := router.Route(routing.Enquiry{
+		Text:           body.Enquiry,
+		Email:          body.Email,
+		Category:       body.Category,
+		HasAttachments: len(files) > 0,
+	})
+	lead.Route = route.Rule
+	lead.Sheet = route.Sheet
+
+	emit(r.Context(), events.TYPE_RECEIVED, lead.Id, map[string]any{
+		"email":       lead.Email,
+		"category":    lead.Category,
+		"country":     lead.Country,
+		"policy":      lead.Policy,
+		"route":       lead.Route,
+		"attachments": len(files),
+	})
 
 	if len(files) > 0 {
-		about, err := driveService.About.
-			Get().
-			Fields("storageQuota").
-			Context(r.Context()).
-			Do()
-		if err != nil {
-			slog.ErrorContext(r.Context(), "error", "gdrive about", err.Error())
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if storageQuota != nil {
+			// refused up front rather than failing part way through the uploads
+			if err := storageQuota.Reserve(r.Context(), filesSize(files)); errors.Is(err, quota.ErrInsufficientStorage) {
+				admissionController.Jobs.Release(1)
 
-			return
-		}
+				slog.WarnContext(r.Context(), "warning", "storage quota", err.Error(), "email", body.Email)
+				emitFailure(r.Context(), lead.Id, "storage quota", err)
+				http.Error(w, "There isn't enough space to store your attachments right now, please send your enquiry without them", http.StatusInsufficientStorage)
+
+				return
+			} else if err != nil {
+				admissionController.Jobs.Release(1)
+
+				slog.ErrorContext(r.Context(), "error", "storage quota", err.Error())
+				http.Error(w, err.Error(), http.StatusInternalServerError)
 
-		slog.DebugContext(r.Context(), "stats", "gdrive usage", about.StorageQuota.UsageInDrive, "gdrive limit", about.StorageQuota.Limit)
+				return
+			}
+		}
 
 		uploadCtx, cancel := context.WithCancel(r.Context())
-		driveFiles, err := iter.MapErr(files, func(fileHeader **multipart.FileHeader) (*drive.File, error) {
+		defer cancel()
+
+		// small files are kept in memory for the support email,
+		// the form's temporary files are gone by the time it's sent
+		embedMaxSize := SUPPORT_EMBED_MAX_SIZE.Value()
+		notifySupport := len(route.Recipients) > 0
+		contents := make([][]byte, len(files))
+
+		attachments, err := iter.MapErr(files, func(fileHeader **multipart.FileHeader) (*leads.Attachment, error) {
 			slog.DebugContext(r.Context(), "begin", "upload", (*fileHeader).Filename, "size", (*fileHeader).Size)
 
 			file, err := (*fileHeader).Open()
@@ -241,23 +854,37 @@ func handler(w http.ResponseWriter, r *http.Request) {
 			}
 			defer file.Close()
 
-			res, err := driveService.Files.
-				Create(&drive.File{
-					Name: fmt.Sprintf("%s - %s (%s)", body.Email, (*fileHeader).Filename, GO_ENV.Value()),
-					Properties: map[string]string{
-						"lead":        body.uuid,
-						"email":       body.Email,
-						"firstName":   body.FirstName,
-						"lastName":    body.LastName,
-						"mobile":      body.Mobile,
-						"environment": GO_ENV.Value(),
-					},
-					Parents: []string{GDRIVE_FOLDER_ID.Value()},
-				}).
-				Media(file).
-				Fields("id, webViewLink").
-				Context(uploadCtx).
-				Do()
+			var fileBody io.Reader = file
+			if notifySupport && (*fileHeader).Size <= embedMaxSize {
+				content, err := io.ReadAll(file)
+				if err != nil {
+					slog.ErrorContext(r.Context(), "error", "read file", err.Error(), "email", body.Email)
+
+					cancel()
+
+					return nil, err
+				}
+
+				contents[slices.Index(files, *fileHeader)] = content
+				fileBody = bytes.NewReader(content)
+			}
+
+			res, err := attachmentStore.Put(uploadCtx, storage.Object{
+				Filename:    (*fileHeader).Filename,
+				ContentType: (*fileHeader).Header.Get("Content-Type"),
+				Size:        (*fileHeader).Size,
+				Metadata: map[string]string{
+					storage.META_LEAD:        body.uuid,
+					storage.META_EMAIL:       body.Email,
+					storage.META_FIRST_NAME:  body.FirstName,
+					storage.META_LAST_NAME:   body.LastName,
+					storage.META_MOBILE:      body.Mobile,
+					storage.META_COUNTRY:     decision.Country,
+					storage.META_ENVIRONMENT: GO_ENV.Value(),
+				},
+				Body:   fileBody,
+				Folder: route.Folder,
+			})
 			if err != nil {
 				slog.ErrorContext(r.Context(), "error", "upload", err.Error(), "email", body.Email)
 
@@ -265,171 +892,1236 @@ func handler(w http.ResponseWriter, r *http.Request) {
 				return nil, err
 			}
 
-			slog.DebugContext(r.Context(), "end", "upload", (*fileHeader).Filename, "link", res.WebViewLink)
+			slog.DebugContext(r.Context(), "end", "upload", (*fileHeader).Filename, "link", res.Link)
 
-			return res, nil
+			return &leads.Attachment{
+				Id:          res.Id,
+				Backend:     STORAGE_BACKEND.Value(),
+				Filename:    (*fileHeader).Filename,
+				ContentType: (*fileHeader).Header.Get("Content-Type"),
+				Size:        (*fileHeader).Size,
+				Link:        res.Link,
+			}, nil
 		})
-		if err != nil {
-			for _, driveFile := range driveFiles {
-				go driveService.Files.
-					Delete(driveFile.Id).
-					Do()
+
+		// failed uploads leave gaps
+		for i, attachment := range attachments {
+			if attachment != nil {
+				lead.Attachments = append(lead.Attachments, *attachment)
+
+				if contents[i] != nil {
+					embedded = append(embedded, notify.Attachment{
+						Filename:    attachment.Filename,
+						ContentType: attachment.ContentType,
+						Content:     contents[i],
+					})
+				}
 			}
 		}
 
-		driveLinks := iter.Map(driveFiles, func(driveFile **drive.File) string {
-			return fmt.Sprintf("- %s", (*driveFile).WebViewLink)
+		if err != nil {
+			admissionController.Jobs.Release(1)
+			releaseStorageQuota(files)
+
+			emitFailure(r.Context(), lead.Id, "upload", err)
+			scheduleAttachmentCleanup(r.Context(), lead.Attachments)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		emit(r.Context(), events.TYPE_ATTACHMENTS_UPLOADED, lead.Id, map[string]any{
+			"attachments": enquiryEvent(lead)["attachments"],
 		})
+	}
 
-		body.driveFiles = driveFiles
-		body.driveFileWebViewLinks = driveLinks
+	// the request's bytes are released when this returns, the embedded
+	// files stay in memory until the job has sent them
+	embeddedSize := int64(0)
+	for _, attachment := range embedded {
+		embeddedSize += int64(len(attachment.Content))
+	}
+	if embeddedSize > 0 && !admissionController.Bytes.TryAcquire(embeddedSize) {
+		slog.WarnContext(r.Context(), "warning", "support attachments", "in-flight bytes budget exhausted, linking them only", "lead", lead.Id, "size", embeddedSize)
 
-		updatedEnquiry := fmt.Sprintf("%s\nAttached files:\n%s", body.Enquiry, strings.Join(driveLinks, "\n"))
-		body.Enquiry = updatedEnquiry
+		embedded, embeddedSize = []notify.Attachment{}, 0
 	}
 
-	slog.DebugContext(r.Context(), "processed", "enquiry", fmt.Sprintf("%+v", body))
+	slog.DebugContext(r.Context(), "processed", "enquiry", fmt.Sprintf("%+v", lead))
 
-	captureEnquiry := func() {
-		ctx := context.WithoutCancel(r.Context())
+	// not waiting for the job to complete
+	// since whether it completes successfully or not has no effect
+	err = workerPool.Submit(r.Context(), "capture enquiry", func(ctx context.Context) {
+		defer admissionController.Bytes.Release(embeddedSize)
 
-		sheetRange := fmt.Sprintf("%s!A1", GSHEETS_SHEET_NAME.Value())
+		captureEnquiry(ctx, lead, route, embedded)
+	})
+	if err != nil {
+		admissionController.Jobs.Release(1)
+		admissionController.Bytes.Release(embeddedSize)
+		releaseStorageQuota(files)
 
-		_, err = sheetsService.
-			Spreadsheets.
-			Values.
-			Append(GSHEETS_SPREADSHEET_ID.Value(), sheetRange, &sheets.ValueRange{
-				Values: [][]interface{}{
-					{
-						body.Email,
-						body.uuid,
-						body.FirstName,
-						body.LastName,
-						body.Mobile,
-						body.Enquiry,
-						strings.Join(body.driveFileWebViewLinks, "\n"),
-					},
-				},
-			}).
-			ValueInputOption("RAW").
-			InsertDataOption("INSERT_ROWS").
-			Context(ctx).
-			Do()
-		if err != nil {
-			slog.ErrorContext(ctx, "error", "gsheets", err.Error())
+		emitFailure(r.Context(), lead.Id, "capture", err)
+		scheduleAttachmentCleanup(r.Context(), lead.Attachments)
+		admissionController.Reject(w, r, admissionController.Jobs)
 
-			for _, file := range body.driveFiles {
-				go driveService.Files.
-					Delete(file.Id).
-					Do()
-			}
+		return
+	}
 
-			return
-		}
+	w.WriteHeader(http.StatusCreated)
+}
 
-		templateId := POSTMARK_TEMPLATE.Value()
-		postmarkFrom := POSTMARK_FROM.Value()
-
-		res, err := postmarkClient.SendTemplatedEmail(ctx, postmark.TemplatedEmail{
-			TemplateID: int64(templateId),
-			From:       postmarkFrom,
-			To:         body.Email,
-			TrackOpens: true,
-			TemplateModel: map[string]any{
-				"uuid":      body.uuid,
-				"email":     body.Email,
-				"firstName": body.FirstName,
-				"lastName":  body.LastName,
-				"mobile":    body.Email,
-				"enquiry":   body.Enquiry,
-			},
-		})
-		if err != nil {
-			slog.ErrorContext(ctx, "error", "postmark", err.Error())
-		}
+func captureEnquiry(ctx context.Context, lead *leads.Lead, route routing.Route, embedded []notify.Attachment) {
+	defer admissionController.Jobs.Release(1)
 
-		supportEmail, ok := POSTMARK_SUPPORT_EMAIL.Value()
-		if ok {
-			postmarkClient.SendEmail(ctx, postmark.Email{
-				From:     postmarkFrom,
-				To:       supportEmail,
-				TextBody: fmt.Sprintf("New enquiry from %s", body.Email),
-			})
-		}
+	if err := leadStore.Create(ctx, lead); err != nil {
+		slog.ErrorContext(ctx, "error", "lead store", err.Error(), "lead", lead.Id)
+		emitFailure(ctx, lead.Id, "record", err)
 
-		slog.DebugContext(ctx, "sent", "postmark message id", res.MessageID, "to", res.To, "at", res.SubmittedAt, "lead", body.uuid)
+		// already in the background, no need for another job
+		deleteAttachments(ctx, lead.Attachments)
+
+		return
 	}
 
-	// not waiting for goroutine to properly complete
-	// since whether it completes successfully or not has no effect
-	go captureEnquiry()
+	emit(ctx, events.TYPE_RECORDED, lead.Id, enquiryEvent(lead))
 
-	w.WriteHeader(http.StatusCreated)
+	if lead.Policy == enums.PolicyQuarantine {
+		// recorded for review, but no emails until a human has looked at it
+		slog.WarnContext(ctx, "quarantined", "lead", lead.Id, "country", lead.Country)
+		setProcessingStatus(ctx, lead.Id, enums.ProcessingQuarantined)
+
+		return
+	}
+
+	processEnquiry(ctx, lead, route, embedded)
 }
 
-func createGoogleSheetsService(ctx context.Context) *sheets.Service {
-	// Authenticate using ADC
-	// instance or account must have required permissions to docs api
-	// instance must have oauth scope: https://www.googleapis.com/auth/spreadsheets
-	service, err := sheets.NewService(ctx)
-	if err != nil {
-		slog.ErrorContext(ctx, "error", "gsheets service", err.Error())
-		panic(err)
+// processEnquiry notifies everyone about a recorded lead, either straight
+// away or once it's approved from quarantine
+func processEnquiry(ctx context.Context, lead *leads.Lead, route routing.Route, embedded []notify.Attachment) {
+	if _, err := webhookDispatcher.Publish(ctx, webhooks.EVENT_ENQUIRY_CREATED, enquiryEvent(lead)); err != nil {
+		slog.ErrorContext(ctx, "error", "webhooks", err.Error(), "lead", lead.Id)
 	}
 
-	slog.DebugContext(ctx, "create google sheets service")
+	from := POSTMARK_FROM.Value()
+
+	messageId, err := sendConfirmation(ctx, lead, from)
+	if errors.Is(err, errSuppressed) {
+		slog.WarnContext(ctx, "suppressed", "confirmation", lead.Email, "lead", lead.Id)
+		setProcessingStatus(ctx, lead.Id, enums.ProcessingFailed)
+		emitFailure(ctx, lead.Id, "confirmation", err)
+	} else if err != nil {
+		slog.ErrorContext(ctx, "error", "confirmation", err.Error(), "lead", lead.Id)
+		setProcessingStatus(ctx, lead.Id, enums.ProcessingFailed)
+		emitFailure(ctx, lead.Id, "confirmation", err)
+	} else {
+		setProcessingStatus(ctx, lead.Id, enums.ProcessingConfirmed)
+		if err := leadDatabase.SetConfirmation(ctx, lead.Id, messageId); err != nil {
+			slog.ErrorContext(ctx, "error", "confirmation message id", err.Error(), "lead", lead.Id)
+		}
+		emit(ctx, events.TYPE_CONFIRMATION_SENT, lead.Id, map[string]any{
+			"messageId": messageId,
+			"to":        lead.Email,
+		})
+		slog.DebugContext(ctx, "sent", "message id", messageId, "to", lead.Email, "lead", lead.Id)
+	}
 
-	return service
+	sendSupportNotification(ctx, lead, route.Recipients, embedded)
+	postToChat(ctx, lead)
 }
 
-func createGoogleDriveService(ctx context.Context) *drive.Service {
-	// Authenticate using ADC
-	// instance or account must have required permissions to drive api
-	// instance must have oauth scope: https://www.googleapis.com/auth/drive
-	// local run must have gdrive access enabled
-	service, err := drive.NewService(ctx)
+// releaseEnquiry processes a lead approved from quarantine. Files are only
+// linked from the support notification, they aren't kept to embed. When the
+// worker pool can't take it, the lead is put back in quarantine
+func releaseEnquiry(ctx context.Context, id string) error {
+	lead, err := leadDatabase.Get(ctx, id)
 	if err != nil {
-		slog.ErrorContext(ctx, "error", "gdrive service", err.Error())
-		panic(err)
+		return err
 	}
 
-	slog.DebugContext(ctx, "create google drive service")
+	route := router.Route(routing.Enquiry{
+		Text:           lead.Enquiry,
+		Email:          lead.Email,
+		Category:       lead.Category,
+		HasAttachments: len(lead.Attachments) > 0,
+	})
 
-	return service
+	err = workerPool.Submit(ctx, "release enquiry", func(ctx context.Context) {
+		processEnquiry(ctx, lead, route, nil)
+	})
+	if err != nil {
+		// back in the review queue so it can be approved again
+		setProcessingStatus(ctx, id, enums.ProcessingQuarantined)
+
+		return fmt.Errorf("lead is still quarantined, try again: %w", err)
+	}
+
+	return nil
 }
 
-func createPostmarkClient(ctx context.Context) *postmark.Client {
-	client := postmark.NewClient(POSTMARK_SERVER_TOKEN.Value(), POSTMARK_ACCOUNT_TOKEN.Value())
+var errSuppressed = errors.New("address is on the suppression list")
+
+// sendConfirmation skips addresses that hard bounced or complained before,
+// sending to them again hurts our sender reputation
+func sendConfirmation(ctx context.Context, lead *leads.Lead, from string) (string, error) {
+	suppressed, err := leadDatabase.IsSuppressed(ctx, lead.Email)
+	if err != nil {
+		return "", err
+	}
+
+	if suppressed {
+		if err := leadDatabase.SetEmailStatus(ctx, "", lead.Id, enums.EmailSuppressed, time.Now()); err != nil {
+			slog.ErrorContext(ctx, "error", "email status", err.Error(), "lead", lead.Id)
+		}
+
+		return "", errSuppressed
+	}
 
-	slog.DebugContext(ctx, "created postmark client")
+	confirmation := notify.Confirmation{
+		From:     from,
+		To:       lead.Email,
+		Model:    confirmationModel(lead),
+		Metadata: map[string]string{mailevents.METADATA_LEAD: lead.Id},
+	}
+	if address, ok := INBOUND_REPLY_ADDRESS.Value(); ok {
+		confirmation.ReplyTo = inbound.ReplyAddress(address, lead.Id)
+	}
 
-	return client
+	return notifier.SendConfirmation(ctx, confirmation)
 }
 
-func initOtel(ctx context.Context, r *chi.Mux) func(context.Context) error {
-	if !ENABLE_TELEMETRY.Value() {
-		// https://github.com/open-telemetry/opentelemetry-go/discussions/2659#discussioncomment-10798740
-		otel.SetTracerProvider(
-			noop.NewTracerProvider(),
-		)
+// confirmationModel is shared by the Postmark template and the local templates
+func confirmationModel(lead *leads.Lead) map[string]any {
+	model := map[string]any{
+		"uuid":      lead.Id,
+		"email":     lead.Email,
+		"firstName": lead.FirstName,
+		"lastName":  lead.LastName,
+		"mobile":    lead.Mobile,
+		"enquiry":   lead.EnquiryWithAttachments(),
+	}
 
-		return func(context.Context) error { // noop cleanup
-			return nil
-		}
+	if statusLinks != nil {
+		base, _ := STATUS_LINK_BASE_URL.Value()
+		model["statusUrl"] = statusLinks.URL(base, lead.Id)
 	}
 
-	exporter, err := otlptrace.New(
-		ctx,
-		otlptracehttp.NewClient(),
-	)
+	return model
+}
+
+// sendSupportNotification tells the routed recipients about a new lead,
+// replies go straight to the enquirer. Small attachments are included as
+// long as the message stays within the size limit, the rest are only linked
+func sendSupportNotification(ctx context.Context, lead *leads.Lead, recipients []string, embedded []notify.Attachment) {
+	if len(recipients) == 0 {
+		return
+	}
 
+	rendered, err := emails.Render(emails.SUPPORT, supportModel(lead))
 	if err != nil {
-		slog.ErrorContext(ctx, "error", "otel", fmt.Sprintf("failed to create exporter: %s", err.Error()))
-		panic(err)
+		slog.ErrorContext(ctx, "error", "support email", err.Error(), "lead", lead.Id)
+
+		return
 	}
-	resources, err := resource.New(
-		ctx,
-		resource.WithAttributes(
+
+	replyTo := mail.Address{
+		Name:    strings.TrimSpace(fmt.Sprintf("%s %s", lead.FirstName, lead.LastName)),
+		Address: lead.Email,
+	}
+
+	message := notify.Message{
+		From:     POSTMARK_FROM.Value(),
+		To:       strings.Join(recipients, ", "),
+		ReplyTo:  replyTo.String(),
+		Subject:  rendered.Subject,
+		HtmlBody: rendered.HtmlBody,
+		TextBody: rendered.TextBody,
+	}
+
+	// headroom for headers and the JSON envelope
+	message.Attachments = notify.FitAttachments(embedded, notify.MAX_MESSAGE_SIZE-message.Size()-(64<<10))
+	if len(message.Attachments) < len(embedded) {
+		slog.WarnContext(ctx, "warning", "support email", "attachments over the message size limit are linked only", "lead", lead.Id)
+	}
+
+	_, err = notifier.Send(ctx, message)
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "support email", err.Error(), "lead", lead.Id)
+	}
+}
+
+// inboundHandler threads replies to the confirmation onto their lead. Postmark
+// retries anything but a 200, so only storage errors fail the request
+func inboundHandler(w http.ResponseWriter, r *http.Request) {
+	var message postmark.InboundMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, inbound.MAX_REQUEST_SIZE)).Decode(&message); err != nil {
+		slog.WarnContext(r.Context(), "warning", "inbound", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	files, err := inbound.Attachments(message)
+	if err != nil {
+		slog.WarnContext(r.Context(), "warning", "inbound", err.Error(), "message id", message.MessageID)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	sender := inbound.Sender(message)
+	reply := &leads.Message{
+		Id:         message.MessageID,
+		FromEmail:  sender.Address,
+		FromName:   sender.Name,
+		Subject:    message.Subject,
+		TextBody:   message.TextBody,
+		HtmlBody:   message.HTMLBody,
+		ReceivedAt: inbound.ReceivedAt(message),
+	}
+	if reply.Id == "" {
+		reply.Id = uuid.NewString()
+	}
+
+	embedded := []notify.Attachment{}
+	for _, file := range files {
+		if int64(len(file.Content)) <= SUPPORT_EMBED_MAX_SIZE.Value() {
+			embedded = append(embedded, notify.Attachment(file))
+		}
+	}
+
+	var lead *leads.Lead
+	if id, ok := inbound.LeadId(message); ok {
+		lead, err = leadDatabase.Get(r.Context(), id)
+		if errors.Is(err, leads.ErrNotFound) {
+			lead = nil
+		} else if err != nil {
+			slog.ErrorContext(r.Context(), "error", "inbound", err.Error(), "lead", id)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		} else if !inbound.FromLead(message, lead.Id, lead.Email) {
+			slog.WarnContext(r.Context(), "warning", "inbound", "not from the lead", "lead", id, "from", sender.Address)
+
+			lead = nil
+		}
+	}
+
+	// still passed on, otherwise the reply would only be in Postmark's activity
+	if lead == nil {
+		slog.WarnContext(r.Context(), "unmatched", "inbound", message.MessageID, "from", sender.Address, "subject", message.Subject)
+
+		for _, file := range files {
+			reply.Attachments = append(reply.Attachments, leads.Attachment{
+				Filename:    file.Filename,
+				ContentType: file.ContentType,
+				Size:        int64(len(file.Content)),
+			})
+		}
+
+		route := router.Route(routing.Enquiry{
+			Text:           message.Subject + "\n" + message.TextBody,
+			Email:          sender.Address,
+			HasAttachments: len(files) > 0,
+		})
+		// nothing is stored, Postmark retries it later
+		if err := scheduleReplyNotification(r.Context(), nil, reply, route.Recipients, embedded); err != nil {
+			slog.ErrorContext(r.Context(), "error", "reply notification", err.Error(), "message id", message.MessageID)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+
+		return
+	}
+
+	reply.LeadId = lead.Id
+	route := router.Route(routing.Enquiry{
+		Text:           lead.Enquiry,
+		Email:          lead.Email,
+		Category:       lead.Category,
+		HasAttachments: len(lead.Attachments) > 0,
+	})
+
+	for _, file := range files {
+		res, err := attachmentStore.Put(r.Context(), storage.Object{
+			Filename:    file.Filename,
+			ContentType: file.ContentType,
+			Size:        int64(len(file.Content)),
+			Metadata: map[string]string{
+				storage.META_LEAD:        lead.Id,
+				storage.META_EMAIL:       lead.Email,
+				storage.META_FIRST_NAME:  lead.FirstName,
+				storage.META_LAST_NAME:   lead.LastName,
+				storage.META_MOBILE:      lead.Mobile,
+				storage.META_COUNTRY:     lead.Country,
+				storage.META_ENVIRONMENT: lead.Environment,
+			},
+			Body:   bytes.NewReader(file.Content),
+			Folder: route.Folder,
+		})
+		if err != nil {
+			slog.ErrorContext(r.Context(), "error", "inbound upload", err.Error(), "lead", lead.Id)
+			scheduleAttachmentCleanup(r.Context(), reply.Attachments)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		reply.Attachments = append(reply.Attachments, leads.Attachment{
+			Id:          res.Id,
+			Backend:     STORAGE_BACKEND.Value(),
+			Filename:    file.Filename,
+			ContentType: file.ContentType,
+			Size:        int64(len(file.Content)),
+			Link:        res.Link,
+		})
+	}
+
+	err = leadDatabase.AddMessage(r.Context(), reply)
+	if errors.Is(err, leads.ErrDuplicateMessage) {
+		slog.DebugContext(r.Context(), "ignored", "inbound", "duplicate", "message id", reply.Id, "lead", lead.Id)
+		scheduleAttachmentCleanup(r.Context(), reply.Attachments)
+		w.WriteHeader(http.StatusOK)
+
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error", "inbound", err.Error(), "lead", lead.Id)
+		scheduleAttachmentCleanup(r.Context(), reply.Attachments)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	emit(r.Context(), events.TYPE_REPLY_RECEIVED, lead.Id, map[string]any{
+		"messageId":   reply.Id,
+		"from":        reply.FromEmail,
+		"subject":     reply.Subject,
+		"attachments": len(reply.Attachments),
+	})
+
+	// a retry would be a duplicate, the reply is on the lead for staff to see
+	if err := scheduleReplyNotification(r.Context(), lead, reply, route.Recipients, embedded); err != nil {
+		slog.ErrorContext(r.Context(), "error", "reply notification", err.Error(), "lead", lead.Id, "message id", reply.Id)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// scheduleReplyNotification sends in the background, returning an error
+// when the worker pool can't take the job
+func scheduleReplyNotification(ctx context.Context, lead *leads.Lead, reply *leads.Message, recipients []string, embedded []notify.Attachment) error {
+	return workerPool.Submit(ctx, "reply notification", func(ctx context.Context) {
+		sendReplyNotification(ctx, lead, reply, recipients, embedded)
+	})
+}
+
+// sendReplyNotification tells the lead's recipients about a reply, lead is
+// nil when the reply couldn't be matched to one
+func sendReplyNotification(ctx context.Context, lead *leads.Lead, reply *leads.Message, recipients []string, embedded []notify.Attachment) {
+	if len(recipients) == 0 {
+		return
+	}
+
+	rendered, err := emails.Render(emails.REPLY, replyModel(lead, reply))
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "reply email", err.Error(), "lead", reply.LeadId)
+
+		return
+	}
+
+	replyTo := mail.Address{Name: reply.FromName, Address: reply.FromEmail}
+
+	message := notify.Message{
+		From:     POSTMARK_FROM.Value(),
+		To:       strings.Join(recipients, ", "),
+		ReplyTo:  replyTo.String(),
+		Subject:  rendered.Subject,
+		HtmlBody: rendered.HtmlBody,
+		TextBody: rendered.TextBody,
+	}
+
+	// headroom for headers and the JSON envelope
+	message.Attachments = notify.FitAttachments(embedded, notify.MAX_MESSAGE_SIZE-message.Size()-(64<<10))
+
+	_, err = notifier.Send(ctx, message)
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "reply email", err.Error(), "lead", reply.LeadId)
+	}
+}
+
+func replyModel(lead *leads.Lead, reply *leads.Message) map[string]any {
+	attachments := make([]map[string]any, 0, len(reply.Attachments))
+	for _, attachment := range reply.Attachments {
+		attachments = append(attachments, map[string]any{
+			"filename":    attachment.Filename,
+			"contentType": attachment.ContentType,
+			"size":        attachment.Size,
+			"link":        attachment.Link,
+		})
+	}
+
+	model := map[string]any{
+		"fromEmail":   reply.FromEmail,
+		"fromName":    reply.FromName,
+		"subject":     reply.Subject,
+		"body":        reply.TextBody,
+		"receivedAt":  reply.ReceivedAt.UTC().Format(time.RFC1123),
+		"attachments": attachments,
+	}
+
+	if lead != nil {
+		model["uuid"] = lead.Id
+		model["firstName"] = lead.FirstName
+		model["lastName"] = lead.LastName
+		model["enquiry"] = lead.Enquiry
+	}
+
+	return model
+}
+
+func emit(ctx context.Context, eventType string, leadId string, data any) {
+	if err := eventEmitter.Emit(ctx, eventType, leadId, data); err != nil {
+		slog.ErrorContext(ctx, "error", "cloudevents", err.Error(), "type", eventType, "lead", leadId)
+	}
+}
+
+// emitFailure reports the stage of the enquiry that failed
+func emitFailure(ctx context.Context, leadId string, stage string, err error) {
+	emit(ctx, events.TYPE_FAILED, leadId, map[string]any{
+		"stage": stage,
+		"error": err.Error(),
+	})
+}
+
+// enquiryEvent is the data of enquiry events sent to webhooks and CloudEvents
+func enquiryEvent(lead *leads.Lead) map[string]any {
+	attachments := make([]map[string]any, 0, len(lead.Attachments))
+	for _, attachment := range lead.Attachments {
+		attachments = append(attachments, map[string]any{
+			"filename":    attachment.Filename,
+			"contentType": attachment.ContentType,
+			"size":        attachment.Size,
+			"link":        attachment.Link,
+		})
+	}
+
+	return map[string]any{
+		"id":          lead.Id,
+		"email":       lead.Email,
+		"firstName":   lead.FirstName,
+		"lastName":    lead.LastName,
+		"mobile":      lead.Mobile,
+		"enquiry":     lead.Enquiry,
+		"category":    lead.Category,
+		"country":     lead.Country,
+		"environment": lead.Environment,
+		"attachments": attachments,
+		"createdAt":   lead.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func postToChat(ctx context.Context, lead *leads.Lead) {
+	err := chatNotifier.Notify(ctx, chat.Enquiry{
+		Id:          lead.Id,
+		FirstName:   lead.FirstName,
+		LastName:    lead.LastName,
+		Email:       lead.Email,
+		Enquiry:     lead.Enquiry,
+		Attachments: len(lead.Attachments),
+		Category:    lead.Category,
+		Environment: lead.Environment,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "chat", err.Error(), "lead", lead.Id)
+	}
+}
+
+// supportModel is everything that was submitted, plus what we know about
+// where it came from
+func supportModel(lead *leads.Lead) map[string]any {
+	attachments := make([]map[string]any, 0, len(lead.Attachments))
+	for _, attachment := range lead.Attachments {
+		attachments = append(attachments, map[string]any{
+			"filename":    attachment.Filename,
+			"contentType": attachment.ContentType,
+			"size":        attachment.Size,
+			"link":        attachment.Link,
+		})
+	}
+
+	createdAt := lead.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	return map[string]any{
+		"uuid":        lead.Id,
+		"email":       lead.Email,
+		"firstName":   lead.FirstName,
+		"lastName":    lead.LastName,
+		"mobile":      lead.Mobile,
+		"enquiry":     lead.Enquiry,
+		"category":    lead.Category,
+		"route":       lead.Route,
+		"country":     lead.Country,
+		"policy":      string(lead.Policy),
+		"ipAddress":   lead.IpAddress,
+		"environment": lead.Environment,
+		"createdAt":   createdAt.UTC().Format(time.RFC1123),
+		"attachments": attachments,
+	}
+}
+
+// clientIp is the address set by middleware.RealIP, without the port
+func clientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// attachmentHandler redirects to a short lived link to the attachment,
+// stored links point here as they'd expire otherwise
+func attachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "*")
+
+	link, err := attachmentStore.(storage.Presigner).Presign(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error", "attachment link", err.Error(), "id", id)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	http.Redirect(w, r, link, http.StatusFound)
+}
+
+// emailPreviewHandler renders a local email template for review, using the
+// stored lead when ?lead= is set and a sample lead otherwise. ?format=text
+// shows the text part
+func emailPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	lead := &leads.Lead{
+		Id:        uuid.Nil.String(),
+		Email:     "jane@example.com",
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Mobile:    "+447700900000",
+		Enquiry:   "We'd like a quote for a new website.",
+		Category:  "sales",
+		Country:   "NZ",
+		IpAddress: "203.0.113.7",
+		Policy:    enums.PolicyAllow,
+		Attachments: []leads.Attachment{
+			{Filename: "brief.pdf", ContentType: "application/pdf", Size: 48 << 10, Link: "https://example.com/brief.pdf"},
+		},
+		Environment: GO_ENV.Value(),
+	}
+
+	if id := r.URL.Query().Get("lead"); id != "" {
+		stored, err := leadDatabase.Get(r.Context(), id)
+		if errors.Is(err, leads.ErrNotFound) {
+			http.Error(w, "Lead not found", http.StatusNotFound)
+
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "error", "email preview", err.Error(), "lead", id)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		lead = stored
+	}
+
+	var model map[string]any
+	switch chi.URLParam(r, "name") {
+	case emails.CONFIRMATION:
+		model = confirmationModel(lead)
+	case emails.SUPPORT:
+		model = supportModel(lead)
+	case emails.REPLY:
+		model = replyModel(lead, &leads.Message{
+			LeadId:     lead.Id,
+			FromEmail:  lead.Email,
+			FromName:   strings.TrimSpace(fmt.Sprintf("%s %s", lead.FirstName, lead.LastName)),
+			Subject:    "Re: We've received your enquiry",
+			TextBody:   "Thanks, the drawings are attached.",
+			ReceivedAt: time.Now(),
+		})
+	default:
+		http.Error(w, "Email not found", http.StatusNotFound)
+
+		return
+	}
+
+	rendered, err := emails.Render(chi.URLParam(r, "name"), model)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error", "email preview", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("X-Email-Subject", rendered.Subject)
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(rendered.TextBody))
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(rendered.HtmlBody))
+}
+
+func setProcessingStatus(ctx context.Context, id string, status enums.ProcessingStatus) {
+	if err := leadStore.SetProcessingStatus(ctx, id, status); err != nil {
+		slog.ErrorContext(ctx, "error", "lead status", err.Error(), "lead", id, "status", status)
+	}
+}
+
+// scheduleAttachmentCleanup deletes uploaded files in the background. When
+// the worker pool can't take the job they're logged and left, the orphan
+// reconciler removes those whose lead was never recorded
+func scheduleAttachmentCleanup(ctx context.Context, attachments []leads.Attachment) {
+	err := workerPool.Submit(ctx, "attachment cleanup", func(ctx context.Context) {
+		deleteAttachments(ctx, attachments)
+	})
+	if err != nil {
+		for _, attachment := range attachments {
+			slog.WarnContext(ctx, "warning", "attachment cleanup", err.Error(), "id", attachment.Id)
+		}
+	}
+}
+
+func deleteAttachments(ctx context.Context, attachments []leads.Attachment) {
+	for _, attachment := range attachments {
+		if err := attachmentStore.Delete(ctx, attachment.Id); err != nil {
+			slog.ErrorContext(ctx, "error", "attachment delete", err.Error(), "id", attachment.Id)
+		}
+	}
+}
+
+func createDatabase(ctx context.Context) *sql.DB {
+	db, err := database.Open(ctx, DATABASE_PATH.Value())
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "database", err.Error())
+		panic(err)
+	}
+
+	slog.DebugContext(ctx, "opened database", "path", DATABASE_PATH.Value())
+
+	return db
+}
+
+func createLeadStore(ctx context.Context) leads.Store {
+	leadDatabase = leads.NewSQLiteStore(db)
+
+	if enums.LeadStore(LEAD_STORE.Value()) == enums.LeadStoreSQLite {
+		return leadDatabase
+	}
+
+	sheetsService = createGoogleSheetsService(ctx)
+
+	sheetsStore := leads.NewSheetsStore(sheetsService, GSHEETS_SPREADSHEET_ID.Value(), GSHEETS_SHEET_NAME.Value())
+	leadMirror = sheetsStore
+
+	return leads.NewDualStore(leadDatabase, sheetsStore)
+}
+
+func createGoogleSheetsService(ctx context.Context) *sheets.Service {
+	// Authenticate using ADC
+	// instance or account must have required permissions to docs api
+	// instance must have oauth scope: https://www.googleapis.com/auth/spreadsheets
+	service, err := sheets.NewService(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "gsheets service", err.Error())
+		panic(err)
+	}
+
+	slog.DebugContext(ctx, "create google sheets service")
+
+	return service
+}
+
+func createAttachmentStore(ctx context.Context) storage.Store {
+	switch enums.StorageBackend(STORAGE_BACKEND.Value()) {
+	case enums.StorageLocal:
+		baseUrl, _ := STORAGE_LOCAL_BASE_URL.Value()
+
+		store, err := storage.NewLocalStore(STORAGE_LOCAL_PATH.Value(), baseUrl)
+		if err != nil {
+			slog.ErrorContext(ctx, "error", "local storage", err.Error())
+			panic(err)
+		}
+
+		slog.DebugContext(ctx, "created local attachment store", "path", STORAGE_LOCAL_PATH.Value())
+
+		return store
+	case enums.StorageS3:
+		prefix, _ := S3_PREFIX.Value()
+		publicUrl, _ := S3_PUBLIC_URL.Value()
+		linkUrl, _ := S3_LINK_URL.Value()
+		archivePrefix, _ := S3_ARCHIVE_PREFIX.Value()
+		limit, _ := S3_QUOTA_LIMIT.Value()
+
+		store, err := storage.NewS3Store(storage.S3Config{
+			Endpoint:      S3_ENDPOINT.Value(),
+			Region:        S3_REGION.Value(),
+			Bucket:        S3_BUCKET.Value(),
+			AccessKey:     S3_ACCESS_KEY_ID.Value(),
+			SecretKey:     S3_SECRET_ACCESS_KEY.Value(),
+			UseSSL:        S3_USE_SSL.Value(),
+			Prefix:        prefix,
+			PublicUrl:     publicUrl,
+			LinkUrl:       linkUrl,
+			LinkExpiry:    S3_LINK_EXPIRY.Value(),
+			ArchivePrefix: archivePrefix,
+			Limit:         limit,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "error", "s3 storage", err.Error())
+			panic(err)
+		}
+
+		slog.DebugContext(ctx, "created s3 attachment store", "endpoint", S3_ENDPOINT.Value(), "bucket", S3_BUCKET.Value())
+
+		return store
+	default:
+		driveService = createGoogleDriveService(ctx)
+		archiveFolderId, _ := GDRIVE_ARCHIVE_FOLDER_ID.Value()
+
+		return storage.NewDriveStore(driveService, GDRIVE_FOLDER_ID.Value(), archiveFolderId)
+	}
+}
+
+func createGoogleDriveService(ctx context.Context) *drive.Service {
+	// Authenticate using ADC
+	// instance or account must have required permissions to drive api
+	// instance must have oauth scope: https://www.googleapis.com/auth/drive
+	// local run must have gdrive access enabled
+	service, err := drive.NewService(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "gdrive service", err.Error())
+		panic(err)
+	}
+
+	slog.DebugContext(ctx, "create google drive service")
+
+	return service
+}
+
+func createAdmissionController(ctx context.Context) *admission.Controller {
+	controller := &admission.Controller{
+		Parses:     admission.NewBudget("parses", ADMISSION_MAX_PARSES.Value()),
+		Bytes:      admission.NewBudget("bytes", ADMISSION_MAX_INFLIGHT_BYTES.Value()),
+		Jobs:       admission.NewBudget("jobs", ADMISSION_MAX_JOBS.Value()),
+		RetryAfter: ADMISSION_RETRY_AFTER.Value(),
+	}
+
+	if workers := int64(WORKER_CONCURRENCY.Value() + WORKER_QUEUE_SIZE.Value()); ADMISSION_MAX_JOBS.Value() >= workers {
+		slog.WarnContext(ctx, "warning", "admission", "ADMISSION_MAX_JOBS is not below WORKER_CONCURRENCY + WORKER_QUEUE_SIZE, a full worker queue may reject enquiries after their files are uploaded", "jobs", ADMISSION_MAX_JOBS.Value(), "workers", workers)
+	}
+
+	slog.DebugContext(ctx, "created admission controller")
+
+	return controller
+}
+
+func createRouter(ctx context.Context) *routing.Router {
+	fallback := routing.Route{}
+	if supportEmail, ok := POSTMARK_SUPPORT_EMAIL.Value(); ok {
+		fallback.Recipients = []string{supportEmail}
+	}
+
+	var created *routing.Router
+	var err error
+	if path, ok := ROUTING_RULES_FILE.Value(); ok {
+		created, err = routing.Load(path, fallback)
+	} else {
+		rules, _ := ROUTING_RULES.Value()
+		created, err = routing.Parse([]byte(rules), fallback)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "routing", err.Error())
+		panic(err)
+	}
+
+	slog.DebugContext(ctx, "created router")
+
+	return created
+}
+
+func createWebhookDispatcher(ctx context.Context) *webhooks.Dispatcher {
+	var subscriptions []webhooks.Subscription
+	var err error
+	if path, ok := WEBHOOKS_FILE.Value(); ok {
+		subscriptions, err = webhooks.Load(path)
+	} else {
+		config, _ := WEBHOOKS.Value()
+		subscriptions, err = webhooks.Parse([]byte(config))
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "webhooks", err.Error())
+		panic(err)
+	}
+
+	slog.DebugContext(ctx, "created webhook dispatcher", "subscriptions", len(subscriptions))
+
+	return webhooks.NewDispatcher(db, subscriptions, WEBHOOK_MAX_ATTEMPTS.Value())
+}
+
+func createEventPublisher(ctx context.Context) *events.AsyncPublisher {
+	var publisher events.Publisher
+	switch enums.EventPublisher(CLOUDEVENTS_PUBLISHER.Value()) {
+	case enums.EventPublisherStdout:
+		publisher = events.NewStdoutPublisher(os.Stdout)
+	case enums.EventPublisherHTTP:
+		publisher = events.NewHTTPPublisher(CLOUDEVENTS_URL.Value().String())
+	default:
+		publisher = events.NoopPublisher{}
+	}
+
+	slog.DebugContext(ctx, "created event publisher", "publisher", CLOUDEVENTS_PUBLISHER.Value())
+
+	return events.NewAsyncPublisher(publisher, 256)
+}
+
+// createAdminAuthenticators accepts API keys, created with `landing apikeys create`,
+// and identity provider tokens when an issuer is configured
+func createAdminAuthenticators(ctx context.Context) []auth.Authenticator {
+	authenticators := []auth.Authenticator{auth.NewAPIKeys(db)}
+
+	issuer, ok := ADMIN_OIDC_ISSUER.Value()
+	if !ok {
+		slog.DebugContext(ctx, "created admin authenticators", "oidc", false)
+
+		return authenticators
+	}
+
+	audience, ok := ADMIN_OIDC_AUDIENCE.Value()
+	if !ok {
+		err := errors.New("ADMIN_OIDC_AUDIENCE is required with ADMIN_OIDC_ISSUER")
+		slog.ErrorContext(ctx, "error", "admin auth", err.Error())
+		panic(err)
+	}
+
+	var allowed []string
+	if emails, ok := ADMIN_OIDC_ALLOWED_EMAILS.Value(); ok {
+		allowed = strings.Split(emails, ",")
+	}
+
+	// go-oidc compares the issuer exactly, url.URL adds nothing
+	oidc, err := auth.NewOIDC(strings.TrimSuffix(issuer.String(), "/"), audience, allowed)
+	if err != nil {
+		err = fmt.Errorf("ADMIN_OIDC_ALLOWED_EMAILS is required with ADMIN_OIDC_ISSUER: %w", err)
+		slog.ErrorContext(ctx, "error", "admin auth", err.Error())
+		panic(err)
+	}
+
+	authenticators = append(authenticators, oidc)
+
+	slog.DebugContext(ctx, "created admin authenticators", "oidc", true, "issuer", issuer.String())
+
+	return authenticators
+}
+
+func createStatusLinks(ctx context.Context) *statuslink.Signer {
+	value, ok := STATUS_LINK_KEYS.Value()
+	if !ok {
+		slog.DebugContext(ctx, "status links disabled")
+
+		return nil
+	}
+
+	if _, ok := STATUS_LINK_BASE_URL.Value(); !ok {
+		err := errors.New("STATUS_LINK_BASE_URL is required with STATUS_LINK_KEYS")
+		slog.ErrorContext(ctx, "error", "status links", err.Error())
+		panic(err)
+	}
+
+	keys, err := statuslink.ParseKeys(value)
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "status links", err.Error())
+		panic(err)
+	}
+
+	signer, err := statuslink.NewSigner(keys, STATUS_LINK_TTL.Value())
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "status links", err.Error())
+		panic(err)
+	}
+
+	slog.DebugContext(ctx, "created status links", "keys", len(keys), "ttl", STATUS_LINK_TTL.Value())
+
+	return signer
+}
+
+// createPrivacyService needs the attachment store, lead store, webhook
+// dispatcher and notifier created first
+func createPrivacyService(ctx context.Context) *privacy.Service {
+	config := privacy.Config{
+		Store:    leadDatabase,
+		Files:    attachmentStore,
+		Backend:  STORAGE_BACKEND.Value(),
+		Webhooks: webhookDispatcher,
+	}
+
+	if sheetsStore, ok := leadMirror.(*leads.SheetsStore); ok {
+		config.Sheets = sheetsStore
+	}
+
+	if postmarkNotifier, ok := notifier.(*notify.PostmarkNotifier); ok {
+		requestedBy, ok := POSTMARK_SUPPORT_EMAIL.Value()
+		if !ok {
+			requestedBy = POSTMARK_FROM.Value()
+		}
+
+		// the first, when there's a list of them
+		if addresses, err := mail.ParseAddressList(requestedBy); err == nil {
+			requestedBy = addresses[0].Address
+		}
+
+		config.Remover = postmarkNotifier
+		config.RequestedBy = requestedBy
+	}
+
+	slog.DebugContext(ctx, "created privacy service", "sheets", config.Sheets != nil, "email provider", config.Remover != nil)
+
+	return privacy.New(config)
+}
+
+func createRetentionJob(ctx context.Context) *retention.Job {
+	value, ok := RETENTION_POLICIES.Value()
+	if !ok {
+		return nil
+	}
+
+	policies, err := retention.ParsePolicies(value)
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "retention", err.Error())
+		panic(err)
+	}
+
+	// other environments' policies are applied by their own deployments
+	policy, ok := policies[GO_ENV.Value()]
+	if !ok {
+		slog.DebugContext(ctx, "no retention policy", "environment", GO_ENV.Value())
+
+		return nil
+	}
+
+	archive := RETENTION_ACTION.Value() == string(enums.RetentionArchive)
+	if _, ok := GDRIVE_ARCHIVE_FOLDER_ID.Value(); archive && STORAGE_BACKEND.Value() == string(enums.StorageGoogleDrive) && !ok {
+		err := errors.New("RETENTION_ACTION archive requires GDRIVE_ARCHIVE_FOLDER_ID")
+		slog.ErrorContext(ctx, "error", "retention", err.Error())
+		panic(err)
+	}
+	if _, ok := S3_ARCHIVE_PREFIX.Value(); archive && STORAGE_BACKEND.Value() == string(enums.StorageS3) && !ok {
+		err := errors.New("RETENTION_ACTION archive requires S3_ARCHIVE_PREFIX")
+		slog.ErrorContext(ctx, "error", "retention", err.Error())
+		panic(err)
+	}
+
+	config := retention.Config{
+		Environment: GO_ENV.Value(),
+		Policy:      policy,
+		DB:          db,
+		Store:       leadDatabase,
+		Files:       attachmentStore,
+		Backend:     STORAGE_BACKEND.Value(),
+		Webhooks:    webhookDispatcher,
+		Archive:     archive,
+		DryRun:      RETENTION_DRY_RUN.Value(),
+	}
+
+	if sheetsStore, ok := leadMirror.(*leads.SheetsStore); ok {
+		config.Sheets = sheetsStore
+	}
+
+	job, err := retention.New(config)
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "retention", err.Error())
+		panic(err)
+	}
+
+	slog.DebugContext(ctx, "created retention job", "environment", config.Environment, "attachments", policy.Attachments, "leads", policy.Leads, "archive", archive, "dry run", config.DryRun)
+
+	return job
+}
+
+func createStorageQuota(ctx context.Context) *quota.Monitor {
+	reporter, ok := attachmentStore.(storage.QuotaReporter)
+	if !ok {
+		return nil
+	}
+
+	thresholds, err := quota.ParseThresholds(STORAGE_QUOTA_WARN_THRESHOLDS.Value())
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "storage quota", err.Error())
+		panic(err)
+	}
+
+	monitor := quota.New(quota.Config{
+		Reporter:   reporter,
+		Backend:    STORAGE_BACKEND.Value(),
+		TTL:        STORAGE_QUOTA_CACHE_TTL.Value(),
+		Thresholds: thresholds,
+		Alert:      alertStorageQuota,
+	})
+
+	slog.DebugContext(ctx, "created storage quota monitor", "ttl", monitor.TTL, "thresholds", thresholds)
+
+	return monitor
+}
+
+// releaseStorageQuota gives back the space reserved for files that weren't
+// kept, their uploads failed or were cleaned up
+func releaseStorageQuota(files []*multipart.FileHeader) {
+	if storageQuota != nil {
+		storageQuota.Release(filesSize(files))
+	}
+}
+
+func filesSize(files []*multipart.FileHeader) int64 {
+	size := int64(0)
+	for _, fileHeader := range files {
+		size += fileHeader.Size
+	}
+
+	return size
+}
+
+// alertStorageQuota posts to chat in the background, the quota is checked
+// while uploads wait
+func alertStorageQuota(ctx context.Context, title string, text string) {
+	alert := chat.Alert{Title: title, Text: text, Environment: GO_ENV.Value()}
+
+	err := workerPool.Submit(ctx, "storage quota alert", func(ctx context.Context) {
+		if err := chatNotifier.Alert(ctx, alert); err != nil {
+			slog.ErrorContext(ctx, "error", "storage quota alert", err.Error())
+		}
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "storage quota alert", err.Error())
+	}
+}
+
+func createOrphanReconciler(ctx context.Context) *orphans.Reconciler {
+	if _, ok := attachmentStore.(storage.Lister); !ok {
+		slog.DebugContext(ctx, "no orphan reconciler", "storage", STORAGE_BACKEND.Value())
+
+		return nil
+	}
+
+	config := orphans.Config{
+		Environment: GO_ENV.Value(),
+		Grace:       ORPHAN_GRACE_PERIOD.Value(),
+		DB:          db,
+		Store:       leadDatabase,
+		Files:       attachmentStore,
+		Delete:      ORPHAN_ACTION.Value() == string(enums.OrphanDelete),
+	}
+
+	if sheetsStore, ok := leadMirror.(*leads.SheetsStore); ok {
+		config.Sheets = sheetsStore
+	}
+
+	reconciler, err := orphans.New(config)
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "orphans", err.Error())
+		panic(err)
+	}
+
+	slog.DebugContext(ctx, "created orphan reconciler", "grace period", config.Grace, "delete", config.Delete)
+
+	return reconciler
+}
+
+func createChatNotifier(ctx context.Context) *chat.Notifier {
+	urls, _ := CHAT_WEBHOOK_URLS.Value()
+
+	notifier, err := chat.New(urls)
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "chat", err.Error())
+		panic(err)
+	}
+
+	slog.DebugContext(ctx, "created chat notifier", "webhooks", len(notifier.Webhooks()))
+
+	return notifier
+}
+
+func createNotifier(ctx context.Context) notify.Notifier {
+	switch enums.Notifier(NOTIFIER.Value()) {
+	case enums.NotifierSMTP:
+		port, err := strconv.Atoi(SMTP_PORT.Value())
+		if err != nil {
+			slog.ErrorContext(ctx, "error", "smtp port", err.Error())
+			panic(err)
+		}
+
+		username, _ := SMTP_USERNAME.Value()
+		password, _ := SMTP_PASSWORD.Value()
+
+		slog.DebugContext(ctx, "created smtp notifier", "host", SMTP_HOST.Value(), "port", port)
+
+		return notify.NewSMTPNotifier(notify.SMTPConfig{
+			Host:     SMTP_HOST.Value(),
+			Port:     port,
+			Username: username,
+			Password: password,
+			Security: enums.SMTPSecurity(SMTP_SECURITY.Value()),
+		})
+	default:
+		client := postmark.NewClient(POSTMARK_SERVER_TOKEN.Value(), POSTMARK_ACCOUNT_TOKEN.Value())
+
+		slog.DebugContext(ctx, "created postmark notifier")
+
+		return notify.NewPostmarkNotifier(client, int64(POSTMARK_TEMPLATE.Value()))
+	}
+}
+
+func initOtel(ctx context.Context, r *chi.Mux) func(context.Context) error {
+	if !ENABLE_TELEMETRY.Value() {
+		// https://github.com/open-telemetry/opentelemetry-go/discussions/2659#discussioncomment-10798740
+		otel.SetTracerProvider(
+			noop.NewTracerProvider(),
+		)
+		otel.SetMeterProvider(
+			metricnoop.NewMeterProvider(),
+		)
+
+		return func(context.Context) error { // noop cleanup
+			return nil
+		}
+	}
+
+	exporter, err := otlptrace.New(
+		ctx,
+		otlptracehttp.NewClient(),
+	)
+
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "otel", fmt.Sprintf("failed to create exporter: %s", err.Error()))
+		panic(err)
+	}
+	resources, err := resource.New(
+		ctx,
+		resource.WithAttributes(
 			attribute.String("service.name", OTEL_SERVICE_NAME.Value()),
 			attribute.String("library.language", "go"),
 			attribute.String("deployment.environment", GO_ENV.Value()),
@@ -449,6 +2141,18 @@ func initOtel(ctx context.Context, r *chi.Mux) func(context.Context) error {
 		),
 	)
 
+	metricExporter, err := otlpmetrichttp.New(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "otel", fmt.Sprintf("failed to create metric exporter: %s", err.Error()))
+		panic(err)
+	}
+
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+		sdkmetric.WithResource(resources),
+	)
+	otel.SetMeterProvider(meterProvider)
+
 	logExporter, _ := otlplogs.NewExporter(ctx, otlplogs.WithClient(otlplogshttp.NewClient(
 		// parseable only supports json payloads
 		// see: https://www.parseable.com/docs/OpenTelemetry/logs
@@ -480,8 +2184,9 @@ func initOtel(ctx context.Context, r *chi.Mux) func(context.Context) error {
 
 	return func(ctx context.Context) error {
 		loggerErr := loggerProvider.Shutdown((ctx))
+		meterErr := meterProvider.Shutdown(ctx)
 		exporterErr := exporter.Shutdown(ctx)
 
-		return errors.Join(loggerErr, exporterErr)
+		return errors.Join(loggerErr, meterErr, exporterErr)
 	}
 }
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"encoding/base64"
+)
+
+// MAX_MESSAGE_SIZE is Postmark's limit for a message including attachments
+const MAX_MESSAGE_SIZE = 10 << 20 // 10 MB
+
+type Message struct {
+	From     string
+	To       string
+	ReplyTo  string
+	Subject  string
+	HtmlBody string
+	TextBody string
+
+	Attachments []Attachment
+	// Metadata is echoed back by Postmark's webhooks, and written as
+	// X-PM-Metadata headers over SMTP
+	Metadata map[string]string
+}
+
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// Confirmation is sent to the enquirer, Model matches the Postmark template model
+type Confirmation struct {
+	From  string
+	To    string
+	Model map[string]any
+	// ReplyTo threads replies back to the lead, see inbound.ReplyAddress
+	ReplyTo string
+
+	Metadata map[string]string
+}
+
+type Notifier interface {
+	// SendConfirmation returns the sent message's id
+	SendConfirmation(ctx context.Context, confirmation Confirmation) (string, error)
+	Send(ctx context.Context, message Message) (string, error)
+}
+
+// FitAttachments keeps attachments, in order, while their encoded size
+// stays within budget. Anything left out is still linked from the body
+func FitAttachments(attachments []Attachment, budget int) []Attachment {
+	fitted := []Attachment{}
+	for _, attachment := range attachments {
+		size := base64.StdEncoding.EncodedLen(len(attachment.Content))
+		if size > budget {
+			continue
+		}
+
+		budget -= size
+		fitted = append(fitted, attachment)
+	}
+
+	return fitted
+}
+
+// Size is the message size as counted against MAX_MESSAGE_SIZE
+func (m Message) Size() int {
+	size := len(m.Subject) + len(m.HtmlBody) + len(m.TextBody)
+	for _, attachment := range m.Attachments {
+		size += base64.StdEncoding.EncodedLen(len(attachment.Content))
+	}
+
+	return size
+}
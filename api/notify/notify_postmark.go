@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"skulpture/landing/emails"
+
+	"github.com/mrz1836/postmark"
+)
+
+type PostmarkNotifier struct {
+	client     *postmark.Client
+	templateId int64
+}
+
+func NewPostmarkNotifier(client *postmark.Client, templateId int64) *PostmarkNotifier {
+	return &PostmarkNotifier{
+		client:     client,
+		templateId: templateId,
+	}
+}
+
+func (n *PostmarkNotifier) SendConfirmation(ctx context.Context, confirmation Confirmation) (string, error) {
+	res, err := n.client.SendTemplatedEmail(ctx, postmark.TemplatedEmail{
+		TemplateID:    n.templateId,
+		From:          confirmation.From,
+		To:            confirmation.To,
+		ReplyTo:       confirmation.ReplyTo,
+		TrackOpens:    true,
+		TemplateModel: confirmation.Model,
+		Metadata:      templateMetadata(confirmation.Metadata),
+	})
+	if err == nil {
+		return res.MessageID, nil
+	}
+
+	// anything else, e.g. a timeout or a 5xx, may have been sent anyway and
+	// falling back would send the enquirer a second confirmation
+	if !isTemplateError(err) {
+		return "", err
+	}
+
+	// the template is missing or invalid on Postmark's side, the local
+	// templates render the same model
+	slog.WarnContext(ctx, "fallback", "postmark template", err.Error(), "template", n.templateId)
+
+	rendered, renderErr := emails.Render(emails.CONFIRMATION, confirmation.Model)
+	if renderErr != nil {
+		return "", errors.Join(err, renderErr)
+	}
+
+	messageId, sendErr := n.Send(ctx, Message{
+		From:     confirmation.From,
+		To:       confirmation.To,
+		ReplyTo:  confirmation.ReplyTo,
+		Subject:  rendered.Subject,
+		HtmlBody: rendered.HtmlBody,
+		TextBody: rendered.TextBody,
+		Metadata: confirmation.Metadata,
+	})
+	if sendErr != nil {
+		return "", errors.Join(err, sendErr)
+	}
+
+	return messageId, nil
+}
+
+// isTemplateError reports whether Postmark rejected the message because of
+// its template, codes 1100-1199 are template and layout errors
+// https://postmarkapp.com/developer/api/overview#error-codes
+func isTemplateError(err error) bool {
+	var apiErr postmark.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	return apiErr.ErrorCode >= 1100 && apiErr.ErrorCode < 1200
+}
+
+func (n *PostmarkNotifier) Send(ctx context.Context, message Message) (string, error) {
+	res, err := n.client.SendEmail(ctx, postmark.Email{
+		From:     message.From,
+		To:       message.To,
+		ReplyTo:  message.ReplyTo,
+		Subject:  message.Subject,
+		HTMLBody: message.HtmlBody,
+		TextBody: message.TextBody,
+		Metadata: message.Metadata,
+
+		Attachments: postmarkAttachments(message.Attachments),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return res.MessageID, nil
+}
+
+func postmarkAttachments(attachments []Attachment) []postmark.Attachment {
+	converted := make([]postmark.Attachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		converted = append(converted, postmark.Attachment{
+			Name:        attachment.Filename,
+			Content:     base64.StdEncoding.EncodeToString(attachment.Content),
+			ContentType: contentType(attachment.ContentType),
+		})
+	}
+
+	return converted
+}
+
+func templateMetadata(metadata map[string]string) map[string]interface{} {
+	if metadata == nil {
+		return nil
+	}
+
+	converted := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		converted[key] = value
+	}
+
+	return converted
+}
+
+func contentType(contentType string) string {
+	if contentType == "" {
+		return "application/octet-stream"
+	}
+
+	return contentType
+}
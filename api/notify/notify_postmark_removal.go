@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type dataRemovalRequest struct {
+	RequestedBy         string
+	RequestedFor        string
+	NotifyWhenCompleted bool
+}
+
+type dataRemovalResponse struct {
+	ID     int64
+	Status string
+}
+
+// RequestDataRemoval asks Postmark to delete what it holds about the
+// address, e.g. message content and events. It completes asynchronously,
+// requestedBy is emailed once it has. The client has no support for it
+// so it's called directly, with the account token
+func (n *PostmarkNotifier) RequestDataRemoval(ctx context.Context, email string, requestedBy string) (string, error) {
+	body, err := json.Marshal(dataRemovalRequest{
+		RequestedBy:         requestedBy,
+		RequestedFor:        email,
+		NotifyWhenCompleted: true,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(n.client.BaseURL, "/")+"/data-removals", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Postmark-Account-Token", n.client.AccountToken)
+
+	res, err := n.client.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1<<10))
+
+		return "", fmt.Errorf("postmark data removal: status %d: %s", res.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var removal dataRemovalResponse
+	if err := json.NewDecoder(res.Body).Decode(&removal); err != nil {
+		return "", err
+	}
+
+	return strconv.FormatInt(removal.ID, 10), nil
+}
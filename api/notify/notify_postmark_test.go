@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mrz1836/postmark"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostmarkNotifierFallsBackToLocalTemplates(t *testing.T) {
+	var sent postmark.Email
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/email/withTemplate":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(postmark.APIError{ErrorCode: 1101, Message: "Template not found"})
+		case "/email":
+			json.NewDecoder(r.Body).Decode(&sent)
+			json.NewEncoder(w).Encode(postmark.EmailResponse{MessageID: "fallback-id", To: sent.To})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := postmark.NewClient("server", "account")
+	client.BaseURL = server.URL
+
+	messageId, err := NewPostmarkNotifier(client, 1).SendConfirmation(context.Background(), Confirmation{
+		From:    "hey@skulpture.xyz",
+		To:      "jane@example.com",
+		Model:   map[string]any{"uuid": "6f1c0b0e-lead", "firstName": "Jane"},
+		ReplyTo: "abc123+6f1c0b0e-lead@inbound.postmarkapp.com",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "fallback-id", messageId)
+	assert.Equal(t, "jane@example.com", sent.To)
+	assert.Equal(t, "We've received your enquiry (ref 6f1c0b0e-lead)", sent.Subject)
+	assert.Equal(t, "abc123+6f1c0b0e-lead@inbound.postmarkapp.com", sent.ReplyTo)
+	assert.Contains(t, sent.TextBody, "Hi Jane,")
+	assert.Contains(t, sent.HTMLBody, "6f1c0b0e-lead")
+}
+
+func TestPostmarkNotifierOnlyFallsBackOnTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		error  postmark.APIError
+	}{
+		{"inactive recipient", http.StatusUnprocessableEntity, postmark.APIError{ErrorCode: 406, Message: "Inactive recipient"}},
+		{"server error", http.StatusInternalServerError, postmark.APIError{ErrorCode: 0, Message: "Internal server error"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fallback := false
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/email/withTemplate":
+					w.WriteHeader(test.status)
+					json.NewEncoder(w).Encode(test.error)
+				case "/email":
+					fallback = true
+					json.NewEncoder(w).Encode(postmark.EmailResponse{MessageID: "fallback-id"})
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			client := postmark.NewClient("server", "account")
+			client.BaseURL = server.URL
+
+			_, err := NewPostmarkNotifier(client, 1).SendConfirmation(context.Background(), Confirmation{
+				From:  "hey@skulpture.xyz",
+				To:    "jane@example.com",
+				Model: map[string]any{"uuid": "6f1c0b0e-lead", "firstName": "Jane"},
+			})
+			assert.ErrorContains(t, err, test.error.Message)
+			assert.False(t, fallback, "the templated message may have been sent")
+		})
+	}
+}
+
+func TestPostmarkNotifierRequestDataRemoval(t *testing.T) {
+	var requested dataRemovalRequest
+	var token string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/data-removals" {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		token = r.Header.Get("X-Postmark-Account-Token")
+		json.NewDecoder(r.Body).Decode(&requested)
+
+		if requested.RequestedFor == "fail@example.com" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(postmark.APIError{ErrorCode: 300, Message: "Invalid email request"})
+
+			return
+		}
+
+		json.NewEncoder(w).Encode(dataRemovalResponse{ID: 1234, Status: "Pending"})
+	}))
+	defer server.Close()
+
+	client := postmark.NewClient("server", "account")
+	client.BaseURL = server.URL
+	notifier := NewPostmarkNotifier(client, 1)
+
+	id, err := notifier.RequestDataRemoval(context.Background(), "jane@example.com", "privacy@skulpture.xyz")
+	assert.NoError(t, err)
+	assert.Equal(t, "1234", id)
+	assert.Equal(t, "account", token)
+	assert.Equal(t, dataRemovalRequest{RequestedBy: "privacy@skulpture.xyz", RequestedFor: "jane@example.com", NotifyWhenCompleted: true}, requested)
+
+	_, err = notifier.RequestDataRemoval(context.Background(), "fail@example.com", "privacy@skulpture.xyz")
+	assert.ErrorContains(t, err, "Invalid email request")
+}
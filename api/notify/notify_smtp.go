@@ -0,0 +1,301 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"skulpture/landing/emails"
+	enums "skulpture/landing/enums"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const SMTP_TIMEOUT = 30 * time.Second
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Security enums.SMTPSecurity
+}
+
+// SMTPNotifier sends through any SMTP server, e.g. Mailpit or MailHog in
+// development. Confirmations are rendered from the local templates
+type SMTPNotifier struct {
+	config SMTPConfig
+}
+
+func NewSMTPNotifier(config SMTPConfig) *SMTPNotifier {
+	return &SMTPNotifier{
+		config: config,
+	}
+}
+
+func (n *SMTPNotifier) SendConfirmation(ctx context.Context, confirmation Confirmation) (string, error) {
+	rendered, err := emails.Render(emails.CONFIRMATION, confirmation.Model)
+	if err != nil {
+		return "", err
+	}
+
+	return n.Send(ctx, Message{
+		From:     confirmation.From,
+		To:       confirmation.To,
+		ReplyTo:  confirmation.ReplyTo,
+		Subject:  rendered.Subject,
+		HtmlBody: rendered.HtmlBody,
+		TextBody: rendered.TextBody,
+		Metadata: confirmation.Metadata,
+	})
+}
+
+func (n *SMTPNotifier) Send(ctx context.Context, message Message) (string, error) {
+	from, err := mail.ParseAddress(message.From)
+	if err != nil {
+		return "", fmt.Errorf("invalid from address: %w", err)
+	}
+
+	recipients, err := mail.ParseAddressList(message.To)
+	if err != nil {
+		return "", fmt.Errorf("invalid to address: %w", err)
+	}
+
+	messageId := fmt.Sprintf("%s@%s", uuid.NewString(), domain(from.Address))
+
+	body, err := buildMessage(message, messageId)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := n.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	if n.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return "", err
+	}
+
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient.Address); err != nil {
+			return "", err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := w.Write(body); err != nil {
+		return "", err
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return messageId, client.Quit()
+}
+
+func (n *SMTPNotifier) dial(ctx context.Context) (*smtp.Client, error) {
+	address := net.JoinHostPort(n.config.Host, strconv.Itoa(n.config.Port))
+	tlsConfig := &tls.Config{
+		ServerName: n.config.Host,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(SMTP_TIMEOUT)
+	}
+	conn.SetDeadline(deadline)
+
+	if n.config.Security == enums.SMTPSecurityTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, n.config.Host)
+	if err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	if n.config.Security == enums.SMTPSecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+
+			return nil, errors.New("smtp server does not support STARTTLS")
+		}
+
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
+
+// buildMessage renders a multipart/alternative message with text and html
+// parts, wrapped in multipart/mixed when there are attachments
+func buildMessage(message Message, messageId string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	headers := []struct{ name, value string }{
+		{"From", message.From},
+		{"To", message.To},
+		{"Reply-To", message.ReplyTo},
+		{"Subject", mime.QEncoding.Encode("utf-8", message.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s>", messageId)},
+		{"MIME-Version", "1.0"},
+	}
+	for _, key := range slices.Sorted(maps.Keys(message.Metadata)) {
+		headers = append(headers, struct{ name, value string }{"X-PM-Metadata-" + key, message.Metadata[key]})
+	}
+	for _, header := range headers {
+		if header.value != "" {
+			fmt.Fprintf(&buf, "%s: %s\r\n", header.name, header.value)
+		}
+	}
+
+	if len(message.Attachments) == 0 {
+		alternative := multipart.NewWriter(&buf)
+		fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", alternative.Boundary())
+
+		if err := writeAlternative(alternative, message); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mixed.Boundary())
+
+	alternative := multipart.NewWriter(nil)
+	w, err := mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {fmt.Sprintf("multipart/alternative; boundary=%s", alternative.Boundary())},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// same boundary, now writing into the mixed part
+	boundary := alternative.Boundary()
+	alternative = multipart.NewWriter(w)
+	if err := alternative.SetBoundary(boundary); err != nil {
+		return nil, err
+	}
+
+	if err := writeAlternative(alternative, message); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range message.Attachments {
+		w, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(contentType(attachment.ContentType), map[string]string{"name": attachment.Filename})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := writeBase64(w, attachment.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeAlternative(alternative *multipart.Writer, message Message) error {
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", message.TextBody},
+		{"text/html; charset=utf-8", message.HtmlBody},
+	}
+	for _, part := range parts {
+		if part.body == "" {
+			continue
+		}
+
+		w, err := alternative.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return err
+		}
+		if err := qp.Close(); err != nil {
+			return err
+		}
+	}
+
+	return alternative.Close()
+}
+
+// writeBase64 wraps lines at 76 characters as required by RFC 2045
+func writeBase64(w io.Writer, content []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > 0 {
+		line := encoded[:min(76, len(encoded))]
+		encoded = encoded[len(line):]
+
+		if _, err := io.WriteString(w, line+"\r\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func domain(address string) string {
+	_, host, ok := strings.Cut(address, "@")
+	if !ok {
+		return "localhost"
+	}
+
+	return host
+}
@@ -0,0 +1,214 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	enums "skulpture/landing/enums"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type received struct {
+	from       string
+	recipients []string
+	data       string
+}
+
+// fakeSMTPServer accepts a single plaintext session and reports what it received
+func fakeSMTPServer(t *testing.T) (SMTPConfig, <-chan received) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan received, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+		var message received
+		reply("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(line)
+
+			switch {
+			case strings.HasPrefix(command, "EHLO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				message.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				message.recipients = append(message.recipients, strings.Trim(line[len("RCPT TO:"):], "<> "))
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 go ahead")
+
+				var data strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				message.data = data.String()
+				messages <- message
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	return SMTPConfig{
+		Host:     host,
+		Port:     portNumber,
+		Security: enums.SMTPSecurityNone,
+	}, messages
+}
+
+func TestSMTPNotifierSendConfirmation(t *testing.T) {
+	config, messages := fakeSMTPServer(t)
+	notifier := NewSMTPNotifier(config)
+
+	messageId, err := notifier.SendConfirmation(context.Background(), Confirmation{
+		From: "Skulpture <hello@skulpture.xyz>",
+		To:   "jane@example.com",
+		Model: map[string]any{
+			"uuid":      "6f1c0b0e-lead",
+			"email":     "jane@example.com",
+			"firstName": "Jane",
+			"lastName":  "Doe",
+			"mobile":    "+447700900000",
+			"enquiry":   "Café <script> fit-out",
+		},
+		Metadata: map[string]string{"lead": "6f1c0b0e-lead"},
+	})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(messageId, "@skulpture.xyz"))
+
+	message := <-messages
+	assert.Equal(t, "hello@skulpture.xyz", message.from)
+	assert.Equal(t, []string{"jane@example.com"}, message.recipients)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(message.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "We've received your enquiry (ref 6f1c0b0e-lead)", parsed.Header.Get("Subject"))
+	assert.Equal(t, "<"+messageId+">", parsed.Header.Get("Message-ID"))
+	assert.Equal(t, "6f1c0b0e-lead", parsed.Header.Get("X-PM-Metadata-lead"))
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	bodies := map[string]string{}
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := io.ReadAll(part)
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		bodies[contentType] = string(body)
+	}
+
+	assert.Contains(t, bodies["text/plain"], "Hi Jane,")
+	assert.Contains(t, bodies["text/plain"], "Mobile: +447700900000")
+	assert.Contains(t, bodies["text/plain"], "Café <script> fit-out")
+	assert.Contains(t, bodies["text/html"], "Café &lt;script&gt; fit-out")
+}
+
+func TestSMTPNotifierInvalidAddress(t *testing.T) {
+	notifier := NewSMTPNotifier(SMTPConfig{Host: "127.0.0.1", Port: 1})
+
+	_, err := notifier.Send(context.Background(), Message{From: "not an address", To: "jane@example.com"})
+	assert.Error(t, err)
+}
+
+func TestSMTPNotifierAttachments(t *testing.T) {
+	config, messages := fakeSMTPServer(t)
+	notifier := NewSMTPNotifier(config)
+
+	_, err := notifier.Send(context.Background(), Message{
+		From:     "hey@skulpture.xyz",
+		To:       "support@skulpture.xyz",
+		ReplyTo:  "Jane Doe <jane@example.com>",
+		Subject:  "New enquiry from Jane Doe",
+		TextBody: "Hello",
+		HtmlBody: "<p>Hello</p>",
+		Attachments: []Attachment{
+			{Filename: "brief v1.pdf", ContentType: "application/pdf", Content: []byte(strings.Repeat("%PDF", 100))},
+		},
+	})
+	assert.NoError(t, err)
+
+	message := <-messages
+	parsed, err := mail.ReadMessage(strings.NewReader(message.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Jane Doe <jane@example.com>", parsed.Header.Get("Reply-To"))
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+
+	alternative, err := parts.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, _, _ = mime.ParseMediaType(alternative.Header.Get("Content-Type"))
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	attachment, err := parts.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "brief v1.pdf", attachment.FileName())
+
+	content, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, attachment))
+	assert.Equal(t, strings.Repeat("%PDF", 100), string(content))
+
+	_, err = parts.NextPart()
+	assert.Equal(t, io.EOF, err)
+}
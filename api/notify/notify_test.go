@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFitAttachments(t *testing.T) {
+	attachment := func(name string, size int) Attachment {
+		return Attachment{Filename: name, Content: make([]byte, size)}
+	}
+
+	tests := []struct {
+		name        string
+		attachments []Attachment
+		budget      int
+		expected    []string
+	}{
+		{"nothing", nil, 100, []string{}},
+		{"all fit", []Attachment{attachment("a", 3), attachment("b", 3)}, 8, []string{"a", "b"}},
+		{"counts encoded size", []Attachment{attachment("a", 6)}, 7, []string{}},
+		{"skips what doesn't fit", []Attachment{attachment("a", 3), attachment("b", 30), attachment("c", 3)}, 8, []string{"a", "c"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names := []string{}
+			for _, fitted := range FitAttachments(test.attachments, test.budget) {
+				names = append(names, fitted.Filename)
+			}
+
+			assert.Equal(t, test.expected, names)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package orphans
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"skulpture/landing/database"
+	"skulpture/landing/leads"
+	"skulpture/landing/storage"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LEASE is the job lease replicas take turns holding
+const LEASE = "orphans"
+
+// ErrNoSheet is returned when deleting without the sheet, as the database
+// alone doesn't have every lead
+var ErrNoSheet = errors.New("orphans are only deleted when leads are mirrored to a sheet")
+
+// Sheets is the spreadsheet leads are mirrored to, it has leads from before
+// they were kept in the database
+type Sheets interface {
+	LeadIds(ctx context.Context) ([]string, error)
+}
+
+type Config struct {
+	// Environment is the one files are checked for, the database only has
+	// this environment's leads
+	Environment string
+	// Grace is how old a file must be before it's an orphan, leaving time
+	// for the enquiry it was uploaded with to be recorded
+	Grace time.Duration
+	// DB holds the lease replicas share
+	DB    *sql.DB
+	Store *leads.SQLiteStore
+	Files storage.Store
+	// Sheets is optional when orphans are only reported
+	Sheets Sheets
+	// Delete removes orphans, they're only reported otherwise. A mirror that
+	// failed or a replica with its own database would make a recorded lead
+	// look unknown, so the sheet must be readable too
+	Delete bool
+}
+
+// Reconciler finds stored files whose lead was never recorded, e.g. when
+// an enquiry failed after some of its files were uploaded and cleaning
+// them up failed too
+type Reconciler struct {
+	Config
+
+	now func() time.Time
+}
+
+func New(config Config) (*Reconciler, error) {
+	if _, ok := config.Files.(storage.Lister); !ok {
+		return nil, errors.New("the storage backend can't list files")
+	}
+
+	if config.Delete && config.Sheets == nil {
+		return nil, ErrNoSheet
+	}
+
+	return &Reconciler{Config: config, now: time.Now}, nil
+}
+
+type Report struct {
+	// Checked is how many files are older than the grace period
+	Checked int
+	Orphans []storage.File
+	Deleted int
+}
+
+// Run reconciles every interval while this replica holds the lease, which
+// is held for the interval so it runs once per interval across replicas
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) {
+	holder := uuid.NewString()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		acquired, err := database.AcquireLease(ctx, r.DB, LEASE, holder, interval)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "error", "orphans", err.Error())
+		}
+
+		if acquired {
+			if _, err := r.Reconcile(ctx, !r.Delete); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "error", "orphans", err.Error())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile finds files past the grace period whose lead isn't in the
+// database or the sheet, deleting them unless reportOnly. Nothing is
+// deleted unless the sheet was read
+func (r *Reconciler) Reconcile(ctx context.Context, reportOnly bool) (*Report, error) {
+	if !reportOnly && r.Sheets == nil {
+		return nil, ErrNoSheet
+	}
+
+	files, err := r.Files.(storage.Lister).ListCreatedBefore(ctx, r.Environment, r.now().Add(-r.Grace))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Checked: len(files), Orphans: []storage.File{}}
+	if len(files) == 0 {
+		return report, nil
+	}
+
+	ids := make([]string, 0, len(files))
+	for _, file := range files {
+		ids = append(ids, file.Metadata[storage.META_LEAD])
+	}
+
+	known, err := r.Store.Existing(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Sheets != nil {
+		sheetIds, err := r.Sheets.LeadIds(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("reading sheet lead ids: %w", err)
+		}
+
+		for _, id := range sheetIds {
+			known[id] = true
+		}
+	}
+
+	var errs []error
+	for _, file := range files {
+		lead := file.Metadata[storage.META_LEAD]
+		if known[lead] {
+			continue
+		}
+
+		report.Orphans = append(report.Orphans, file)
+		slog.WarnContext(ctx, "orphan", "file", file.Id, "lead", lead, "created", file.CreatedAt, "delete", !reportOnly)
+
+		if reportOnly {
+			continue
+		}
+
+		if err := r.Files.Delete(ctx, file.Id); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file.Id, err))
+
+			continue
+		}
+
+		report.Deleted++
+	}
+
+	slog.InfoContext(ctx, "orphans", "environment", r.Environment, "checked", report.Checked, "orphans", len(report.Orphans), "deleted", report.Deleted)
+
+	return report, errors.Join(errs...)
+}
@@ -0,0 +1,107 @@
+package orphans
+
+import (
+	"context"
+	"errors"
+	enums "skulpture/landing/enums"
+	"skulpture/landing/leads"
+	"skulpture/landing/leads/leadstest"
+	"skulpture/landing/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newReconciler(t *testing.T) (*Reconciler, *storage.LocalStore) {
+	t.Helper()
+
+	f := leadstest.New(t)
+	if err := f.Store.Create(context.Background(), &leads.Lead{Id: "recorded", Email: "jane@example.com", Environment: string(enums.Development)}); err != nil {
+		t.Fatal(err)
+	}
+
+	uploads := []map[string]string{
+		{storage.META_LEAD: "recorded", storage.META_ENVIRONMENT: string(enums.Development)},
+		{storage.META_LEAD: "in-sheet", storage.META_ENVIRONMENT: string(enums.Development)},
+		{storage.META_LEAD: "failed", storage.META_ENVIRONMENT: string(enums.Development)},
+		{storage.META_LEAD: "production", storage.META_ENVIRONMENT: string(enums.Production)},
+	}
+	for _, metadata := range uploads {
+		f.Put(t, "brief.pdf", "%PDF", metadata)
+	}
+
+	f.Sheets.Ids = []string{"Lead", "in-sheet"}
+	reconciler, err := New(Config{
+		Environment: string(enums.Development),
+		Grace:       time.Hour,
+		DB:          f.DB,
+		Store:       f.Store,
+		Files:       f.Files,
+		Sheets:      f.Sheets,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return reconciler, f.Files
+}
+
+func TestReconcile(t *testing.T) {
+	reconciler, files := newReconciler(t)
+	ctx := context.Background()
+
+	report, err := reconciler.Reconcile(ctx, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.Checked, "within the grace period")
+
+	reconciler.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	report, err = reconciler.Reconcile(ctx, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Checked, "only development files")
+	assert.Len(t, report.Orphans, 1)
+	assert.Equal(t, "failed/brief.pdf", report.Orphans[0].Id)
+	assert.Equal(t, 0, report.Deleted)
+
+	report, err = reconciler.Reconcile(ctx, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Deleted)
+
+	_, err = files.Open(ctx, "failed/brief.pdf")
+	assert.Error(t, err)
+	for _, id := range []string{"recorded/brief.pdf", "in-sheet/brief.pdf", "production/brief.pdf"} {
+		reader, err := files.Open(ctx, id)
+		if assert.NoError(t, err, id) {
+			reader.Close()
+		}
+	}
+}
+
+func TestReconcileOnlyDeletesWithTheSheet(t *testing.T) {
+	reconciler, files := newReconciler(t)
+	ctx := context.Background()
+	reconciler.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	reconciler.Sheets.(*leadstest.Sheets).Err = errors.New("quota exceeded")
+	_, err := reconciler.Reconcile(ctx, false)
+	assert.Error(t, err)
+
+	reconciler.Sheets = nil
+	_, err = reconciler.Reconcile(ctx, false)
+	assert.ErrorIs(t, err, ErrNoSheet)
+
+	report, err := reconciler.Reconcile(ctx, true)
+	assert.NoError(t, err, "reported without the sheet")
+	assert.Len(t, report.Orphans, 2, "the sheet's lead looks like an orphan without it")
+
+	for _, id := range []string{"in-sheet/brief.pdf", "failed/brief.pdf"} {
+		reader, err := files.Open(ctx, id)
+		if assert.NoError(t, err, id) {
+			reader.Close()
+		}
+	}
+
+	_, err = New(Config{Files: files, Delete: true})
+	assert.ErrorIs(t, err, ErrNoSheet)
+}
@@ -0,0 +1,284 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"skulpture/landing/leads"
+	"skulpture/landing/storage"
+	"slices"
+	"strings"
+)
+
+var ErrInvalidEmail = errors.New("invalid email")
+
+// Sheets is the spreadsheet leads are mirrored to
+type Sheets interface {
+	RowsByEmail(ctx context.Context, email string) ([]leads.SheetRow, error)
+	ClearRows(ctx context.Context, rows []leads.SheetRow) error
+}
+
+// Webhooks keeps copies of lead events until they're delivered
+type Webhooks interface {
+	Forget(ctx context.Context, value string) (int64, error)
+}
+
+// Remover asks the email provider to delete what it holds
+type Remover interface {
+	RequestDataRemoval(ctx context.Context, email string, requestedBy string) (string, error)
+}
+
+type Config struct {
+	Store *leads.SQLiteStore
+	Files storage.Store
+	// Backend is the name Files is recorded under on attachments, files in
+	// other backends can't be reached
+	Backend string
+	// Sheets, Webhooks and Remover are optional
+	Sheets   Sheets
+	Webhooks Webhooks
+	Remover  Remover
+	// RequestedBy is told when the email provider has removed the data
+	RequestedBy string
+}
+
+// Service answers data subject access and erasure requests, which take an
+// email address. Suppressions are kept so the address is never emailed again
+type Service struct {
+	Config
+}
+
+func New(config Config) *Service {
+	return &Service{Config: config}
+}
+
+// Subject is everything held about an email address
+type Subject struct {
+	Email     string
+	Leads     []SubjectLead
+	SheetRows []leads.SheetRow
+	Files     []File
+}
+
+type SubjectLead struct {
+	leads.Lead
+	Messages []leads.Message
+	Notes    []leads.Note
+}
+
+// File is an attachment or a file found by its email metadata
+type File struct {
+	Id       string
+	Backend  string
+	Filename string
+	// LeadId is empty for files the database doesn't know about
+	LeadId string
+	// Path within an export
+	Path string
+}
+
+// Reachable is whether the file is in the configured backend
+func (f File) Reachable(backend string) bool {
+	return f.Backend == backend
+}
+
+// Find collects the leads, sheet rows and files for the address
+func (s *Service) Find(ctx context.Context, email string) (*Subject, error) {
+	email = strings.TrimSpace(email)
+	if email == "" || !strings.Contains(email, "@") {
+		return nil, fmt.Errorf("%w %q", ErrInvalidEmail, email)
+	}
+
+	subject := &Subject{Email: email, SheetRows: []leads.SheetRow{}, Files: []File{}}
+	paths := map[string]bool{}
+	seen := map[string]bool{}
+
+	addFile := func(file File) {
+		key := file.Backend + ":" + file.Id
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+
+		file.Path = uniquePath(paths, file)
+		subject.Files = append(subject.Files, file)
+	}
+
+	stored, err := s.Store.LeadsByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, lead := range stored {
+		found := SubjectLead{Lead: lead}
+
+		if found.Messages, err = s.Store.Messages(ctx, lead.Id); err != nil {
+			return nil, err
+		}
+
+		if found.Notes, err = s.Store.Notes(ctx, lead.Id); err != nil {
+			return nil, err
+		}
+
+		attachments := slices.Clone(lead.Attachments)
+		for _, message := range found.Messages {
+			attachments = append(attachments, message.Attachments...)
+		}
+		for _, attachment := range attachments {
+			addFile(File{Id: attachment.Id, Backend: attachment.Backend, Filename: attachment.Filename, LeadId: lead.Id})
+		}
+
+		subject.Leads = append(subject.Leads, found)
+	}
+
+	if finder, ok := s.Files.(storage.EmailFinder); ok {
+		files, err := finder.FindByEmail(ctx, email)
+		if err != nil {
+			return nil, fmt.Errorf("finding files: %w", err)
+		}
+
+		for _, file := range files {
+			addFile(File{Id: file.Id, Backend: s.Backend, Filename: file.Filename, LeadId: file.Metadata[storage.META_LEAD]})
+		}
+	}
+
+	if s.Sheets != nil {
+		rows, err := s.Sheets.RowsByEmail(ctx, email)
+		if err != nil {
+			return nil, fmt.Errorf("finding sheet rows: %w", err)
+		}
+
+		subject.SheetRows = rows
+	}
+
+	return subject, nil
+}
+
+// LeadIds are the ids of every lead found, including ones only known from
+// the sheet or file metadata
+func (s *Subject) LeadIds() []string {
+	ids := []string{}
+	for _, lead := range s.Leads {
+		ids = append(ids, lead.Id)
+	}
+	for _, file := range s.Files {
+		ids = append(ids, file.LeadId)
+	}
+	for _, row := range s.SheetRows {
+		// column B is the lead id
+		if len(row.Values) > 1 {
+			ids = append(ids, strings.TrimSpace(row.Values[1]))
+		}
+	}
+
+	slices.Sort(ids)
+
+	return slices.DeleteFunc(slices.Compact(ids), func(id string) bool { return id == "" })
+}
+
+// Erasure is what was removed
+type Erasure struct {
+	Leads      []string
+	Files      int
+	SheetRows  int
+	Deliveries int64
+	// RemovalRequest is the email provider's data removal request id
+	RemovalRequest string
+	// Warnings are data that couldn't be removed and needs a manual follow up
+	Warnings []string
+}
+
+// Erase deletes the subject's files first and the leads last, so a failure
+// part way through can be retried with nothing left unfindable
+func (s *Service) Erase(ctx context.Context, email string, actor string) (*Erasure, error) {
+	subject, err := s.Find(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	erasure := &Erasure{Leads: subject.LeadIds(), Warnings: []string{}}
+
+	var errs []error
+	for _, file := range subject.Files {
+		if !file.Reachable(s.Backend) {
+			erasure.Warnings = append(erasure.Warnings, fmt.Sprintf("%s is stored in %s, not %s", file.Path, file.Backend, s.Backend))
+
+			continue
+		}
+
+		if err := s.Files.Delete(ctx, file.Id); err != nil {
+			errs = append(errs, fmt.Errorf("deleting %s: %w", file.Path, err))
+
+			continue
+		}
+
+		erasure.Files++
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if s.Sheets != nil {
+		if err := s.Sheets.ClearRows(ctx, subject.SheetRows); err != nil {
+			return nil, fmt.Errorf("clearing sheet rows: %w", err)
+		}
+
+		erasure.SheetRows = len(subject.SheetRows)
+	}
+
+	if s.Webhooks != nil {
+		for _, id := range erasure.Leads {
+			deleted, err := s.Webhooks.Forget(ctx, id)
+			if err != nil {
+				return nil, fmt.Errorf("deleting webhook deliveries: %w", err)
+			}
+
+			erasure.Deliveries += deleted
+		}
+	}
+
+	if s.Remover != nil {
+		id, err := s.Remover.RequestDataRemoval(ctx, subject.Email, s.RequestedBy)
+		if err != nil {
+			erasure.Warnings = append(erasure.Warnings, fmt.Sprintf("email provider data removal: %s", err.Error()))
+		}
+
+		erasure.RemovalRequest = id
+	}
+
+	detail := fmt.Sprintf("%d files, %d sheet rows, %d webhook deliveries", erasure.Files, erasure.SheetRows, erasure.Deliveries)
+	if erasure.RemovalRequest != "" {
+		detail += ", removal request " + erasure.RemovalRequest
+	}
+
+	if err := s.Store.Erase(ctx, erasure.Leads, actor, detail); err != nil {
+		return nil, err
+	}
+
+	return erasure, nil
+}
+
+var unsafeCharacters = strings.NewReplacer("/", "_", "\\", "_", ":", "_")
+
+// uniquePath files attachments by lead, numbering repeated names
+func uniquePath(paths map[string]bool, file File) string {
+	folder := file.LeadId
+	if folder == "" {
+		folder = "unknown"
+	}
+
+	filename := strings.Trim(unsafeCharacters.Replace(path.Base("/"+file.Filename)), ". ")
+	if filename == "" {
+		filename = "attachment"
+	}
+
+	ext := path.Ext(filename)
+	candidate := path.Join("files", unsafeCharacters.Replace(folder), filename)
+	for i := 1; paths[candidate]; i++ {
+		candidate = path.Join("files", unsafeCharacters.Replace(folder), fmt.Sprintf("%s-%d%s", strings.TrimSuffix(filename, ext), i, ext))
+	}
+	paths[candidate] = true
+
+	return candidate
+}
@@ -0,0 +1,193 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"skulpture/landing/leads"
+	"time"
+)
+
+// EXPORT_MANIFEST is the zip entry describing everything exported
+const EXPORT_MANIFEST = "subject.json"
+
+type exportManifest struct {
+	Email      string       `json:"email"`
+	ExportedAt time.Time    `json:"exportedAt"`
+	Leads      []exportLead `json:"leads"`
+	SheetRows  []exportRow  `json:"sheetRows"`
+	Files      []exportFile `json:"files"`
+}
+
+type exportLead struct {
+	Id          string          `json:"id"`
+	Email       string          `json:"email"`
+	FirstName   string          `json:"firstName"`
+	LastName    string          `json:"lastName"`
+	Mobile      string          `json:"mobile"`
+	Enquiry     string          `json:"enquiry"`
+	Category    string          `json:"category"`
+	Country     string          `json:"country"`
+	IpAddress   string          `json:"ipAddress"`
+	State       string          `json:"state"`
+	EmailStatus string          `json:"emailStatus"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	Messages    []exportMessage `json:"messages"`
+	Notes       []exportNote    `json:"notes"`
+}
+
+type exportMessage struct {
+	FromEmail  string    `json:"fromEmail"`
+	FromName   string    `json:"fromName"`
+	Subject    string    `json:"subject"`
+	TextBody   string    `json:"textBody"`
+	ReceivedAt time.Time `json:"receivedAt"`
+}
+
+type exportNote struct {
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type exportRow struct {
+	Tab    string   `json:"tab"`
+	Row    int      `json:"row"`
+	Values []string `json:"values"`
+}
+
+type exportFile struct {
+	Filename string `json:"filename"`
+	LeadId   string `json:"leadId"`
+	// Path is empty when the file couldn't be exported
+	Path string `json:"path"`
+	Note string `json:"note,omitempty"`
+}
+
+// Export writes a zip of the subject's leads, sheet rows and files, with the
+// files under files/<lead id>/. Any file that can't be read fails the export
+func (s *Service) Export(ctx context.Context, email string, w io.Writer, actor string) error {
+	subject, err := s.Find(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+
+	manifest := exportManifest{
+		Email:      subject.Email,
+		ExportedAt: time.Now().UTC(),
+		Leads:      make([]exportLead, 0, len(subject.Leads)),
+		SheetRows:  make([]exportRow, 0, len(subject.SheetRows)),
+		Files:      make([]exportFile, 0, len(subject.Files)),
+	}
+
+	for _, lead := range subject.Leads {
+		manifest.Leads = append(manifest.Leads, toExportLead(lead))
+	}
+
+	for _, row := range subject.SheetRows {
+		manifest.SheetRows = append(manifest.SheetRows, exportRow{Tab: row.Tab, Row: row.Number, Values: row.Values})
+	}
+
+	for _, file := range subject.Files {
+		exported := exportFile{Filename: file.Filename, LeadId: file.LeadId}
+
+		if !file.Reachable(s.Backend) {
+			exported.Note = fmt.Sprintf("stored in %s, not available from %s", file.Backend, s.Backend)
+			manifest.Files = append(manifest.Files, exported)
+
+			continue
+		}
+
+		if err := s.writeFile(ctx, archive, file); err != nil {
+			return fmt.Errorf("exporting %s: %w", file.Path, err)
+		}
+
+		exported.Path = file.Path
+		manifest.Files = append(manifest.Files, exported)
+	}
+
+	entry, err := archive.Create(EXPORT_MANIFEST)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return err
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	for _, id := range subject.LeadIds() {
+		if err := s.Store.Audit(ctx, id, actor, leads.AUDIT_EXPORT, fmt.Sprintf("%d files, %d sheet rows", len(manifest.Files), len(manifest.SheetRows))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) writeFile(ctx context.Context, archive *zip.Writer, file File) error {
+	reader, err := s.Files.Open(ctx, file.Id)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	entry, err := archive.Create(file.Path)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(entry, reader)
+
+	return err
+}
+
+func toExportLead(lead SubjectLead) exportLead {
+	exported := exportLead{
+		Id:          lead.Id,
+		Email:       lead.Email,
+		FirstName:   lead.FirstName,
+		LastName:    lead.LastName,
+		Mobile:      lead.Mobile,
+		Enquiry:     lead.Enquiry,
+		Category:    lead.Category,
+		Country:     lead.Country,
+		IpAddress:   lead.IpAddress,
+		State:       string(lead.State),
+		EmailStatus: string(lead.EmailStatus),
+		CreatedAt:   lead.CreatedAt,
+		UpdatedAt:   lead.UpdatedAt,
+		Messages:    make([]exportMessage, 0, len(lead.Messages)),
+		Notes:       make([]exportNote, 0, len(lead.Notes)),
+	}
+
+	for _, message := range lead.Messages {
+		exported.Messages = append(exported.Messages, exportMessage{
+			FromEmail:  message.FromEmail,
+			FromName:   message.FromName,
+			Subject:    message.Subject,
+			TextBody:   message.TextBody,
+			ReceivedAt: message.ReceivedAt,
+		})
+	}
+
+	for _, note := range lead.Notes {
+		exported.Notes = append(exported.Notes, exportNote{
+			Author:    note.Author,
+			Body:      note.Body,
+			CreatedAt: note.CreatedAt,
+		})
+	}
+
+	return exported
+}
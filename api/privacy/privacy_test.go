@@ -0,0 +1,198 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	enums "skulpture/landing/enums"
+	"skulpture/landing/leads"
+	"skulpture/landing/leads/leadstest"
+	"skulpture/landing/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRemover struct {
+	err error
+}
+
+func (f *fakeRemover) RequestDataRemoval(ctx context.Context, email string, requestedBy string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+
+	return "1234", nil
+}
+
+type fixture struct {
+	*leadstest.Fixture
+	service *Service
+}
+
+const JANE = "9b2f8c4e-0000-4000-8000-000000000700"
+const JOE = "9b2f8c4e-0000-4000-8000-000000000701"
+
+func newFixture(t *testing.T, remover *fakeRemover) *fixture {
+	t.Helper()
+	ctx := context.Background()
+
+	f := &fixture{Fixture: leadstest.New(t)}
+
+	put := func(lead string, email string, filename string) string {
+		return f.Put(t, filename, filename+" for "+email, map[string]string{storage.META_LEAD: lead, storage.META_EMAIL: email})
+	}
+
+	store := f.Store
+	for _, lead := range []*leads.Lead{
+		{
+			Id: JANE, Email: "jane@example.com", FirstName: "Jane", LastName: "Doe", Enquiry: "A new website",
+			Attachments: []leads.Attachment{
+				{Id: put(JANE, "jane@example.com", "brief.pdf"), Backend: "local", Filename: "brief.pdf"},
+				{Id: "drive-file", Backend: "gdrive", Filename: "brief.pdf"},
+			},
+		},
+		{
+			Id: JOE, Email: "joe@example.com", FirstName: "Joe", LastName: "Bloggs", Enquiry: "A shop",
+			Attachments: []leads.Attachment{{Id: put(JOE, "joe@example.com", "plan.pdf"), Backend: "local", Filename: "plan.pdf"}},
+		},
+	} {
+		lead.Environment = string(enums.Test)
+		lead.ProcessingStatus = enums.ProcessingConfirmed
+		assert.NoError(t, store.Create(ctx, lead))
+	}
+
+	assert.NoError(t, store.AddMessage(ctx, &leads.Message{
+		Id: "inbound-1", LeadId: JANE, FromEmail: "jane@example.com", Subject: "Re: ref", TextBody: "Drawings attached",
+		Attachments: []leads.Attachment{{Id: put(JANE, "jane@example.com", "brief.pdf"), Backend: "local", Filename: "brief.pdf"}},
+	}))
+	_, err := store.AddNote(ctx, JANE, "ops@skulpture.xyz", "Called back")
+	assert.NoError(t, err)
+
+	// uploaded before leads were kept in the database
+	put("legacy-lead", "Jane@Example.com", "old.pdf")
+
+	f.Sheets.Rows = []leads.SheetRow{{Tab: "Sheet1", Number: 2, Values: []string{"jane@example.com", JANE}}, {Tab: "Sheet1", Number: 9, Values: []string{"jane@example.com", "legacy-lead"}}}
+	f.service = New(Config{
+		Store:       store,
+		Files:       f.Files,
+		Backend:     "local",
+		Sheets:      f.Sheets,
+		Webhooks:    f.Webhooks,
+		Remover:     remover,
+		RequestedBy: "privacy@skulpture.xyz",
+	})
+
+	return f
+}
+
+func TestFind(t *testing.T) {
+	f := newFixture(t, nil)
+
+	subject, err := f.service.Find(context.Background(), " JANE@example.com ")
+	assert.NoError(t, err)
+	assert.Len(t, subject.Leads, 1)
+	assert.Len(t, subject.Leads[0].Messages, 1)
+	assert.Len(t, subject.Leads[0].Notes, 1)
+	assert.Len(t, subject.SheetRows, 2)
+	assert.Equal(t, []string{JANE, "legacy-lead"}, subject.LeadIds())
+
+	paths := []string{}
+	for _, file := range subject.Files {
+		paths = append(paths, file.Path)
+	}
+	assert.Equal(t, []string{
+		"files/" + JANE + "/brief.pdf",
+		"files/" + JANE + "/brief-1.pdf",
+		"files/" + JANE + "/brief-2.pdf",
+		"files/legacy-lead/old.pdf",
+	}, paths)
+
+	_, err = f.service.Find(context.Background(), "jane")
+	assert.ErrorIs(t, err, ErrInvalidEmail)
+}
+
+func TestExport(t *testing.T) {
+	f := newFixture(t, nil)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	assert.NoError(t, f.service.Export(ctx, "jane@example.com", &buf, "ops@skulpture.xyz"))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := map[string]string{}
+	for _, file := range archive.File {
+		reader, _ := file.Open()
+		content, _ := io.ReadAll(reader)
+		reader.Close()
+		entries[file.Name] = string(content)
+	}
+
+	assert.Equal(t, "brief.pdf for jane@example.com", entries["files/"+JANE+"/brief.pdf"])
+	assert.Equal(t, "old.pdf for Jane@Example.com", entries["files/legacy-lead/old.pdf"])
+	assert.Len(t, entries, 4, "three readable files and the manifest")
+
+	var manifest exportManifest
+	assert.NoError(t, json.Unmarshal([]byte(entries[EXPORT_MANIFEST]), &manifest))
+	assert.Equal(t, "Jane", manifest.Leads[0].FirstName)
+	assert.Equal(t, "Drawings attached", manifest.Leads[0].Messages[0].TextBody)
+	assert.Equal(t, "Called back", manifest.Leads[0].Notes[0].Body)
+	assert.Len(t, manifest.SheetRows, 2)
+	assert.Equal(t, exportFile{Filename: "brief.pdf", LeadId: JANE, Note: "stored in gdrive, not available from local"}, manifest.Files[1])
+	assert.NotContains(t, entries[EXPORT_MANIFEST], "joe@example.com")
+
+	audited, _ := f.Store.AuditLog(ctx, JANE)
+	assert.Equal(t, leads.AUDIT_EXPORT, audited[len(audited)-1].Action)
+}
+
+func TestErase(t *testing.T) {
+	f := newFixture(t, &fakeRemover{})
+	ctx := context.Background()
+
+	erasure, err := f.service.Erase(ctx, "jane@example.com", "ops@skulpture.xyz")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{JANE, "legacy-lead"}, erasure.Leads)
+	assert.Equal(t, 3, erasure.Files)
+	assert.Equal(t, 2, erasure.SheetRows)
+	assert.Equal(t, int64(2), erasure.Deliveries)
+	assert.Equal(t, "1234", erasure.RemovalRequest)
+	assert.Len(t, erasure.Warnings, 1, "the gdrive attachment")
+
+	assert.Equal(t, f.Sheets.Rows, f.Sheets.Cleared)
+	assert.Equal(t, []string{JANE, "legacy-lead"}, f.Webhooks.Forgotten)
+
+	_, err = f.Store.Get(ctx, JANE)
+	assert.ErrorIs(t, err, leads.ErrNotFound)
+	_, err = f.Store.Get(ctx, JOE)
+	assert.NoError(t, err, "other subjects are untouched")
+
+	remaining, err := f.Files.FindByEmail(ctx, "jane@example.com")
+	assert.NoError(t, err)
+	assert.Empty(t, remaining)
+
+	entries, err := f.Store.AuditLog(ctx, JANE)
+	assert.NoError(t, err)
+	erased := entries[len(entries)-1]
+	assert.Equal(t, leads.AUDIT_ERASE, erased.Action)
+	assert.Equal(t, "ops@skulpture.xyz", erased.Actor)
+	assert.Equal(t, "3 files, 2 sheet rows, 2 webhook deliveries, removal request 1234", erased.Detail)
+
+	legacy, _ := f.Store.AuditLog(ctx, "legacy-lead")
+	assert.Len(t, legacy, 1, "recorded for leads only known from the sheet")
+}
+
+func TestEraseRemovalFailure(t *testing.T) {
+	f := newFixture(t, &fakeRemover{err: errors.New("unavailable")})
+
+	erasure, err := f.service.Erase(context.Background(), "jane@example.com", "ops@skulpture.xyz")
+	assert.NoError(t, err, "the email provider is followed up manually")
+	assert.Empty(t, erasure.RemovalRequest)
+	assert.Contains(t, erasure.Warnings, "email provider data removal: unavailable")
+}
@@ -0,0 +1,234 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"skulpture/landing/emails"
+	"skulpture/landing/storage"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const INSTRUMENTATION_NAME = "skulpture/landing/quota"
+
+var ErrInsufficientStorage = errors.New("not enough storage for the attachments")
+
+// Alert is told when usage crosses a threshold
+type Alert func(ctx context.Context, title string, text string)
+
+type Config struct {
+	Reporter storage.QuotaReporter
+	// Backend is recorded on the metrics
+	Backend string
+	// TTL is how long a reading is used before the backend is asked again
+	TTL time.Duration
+	// Thresholds are fractions of the limit, ascending, e.g. 0.8 and 0.95
+	Thresholds []float64
+	// Alert is optional
+	Alert Alert
+}
+
+// Monitor caches the storage quota so it isn't fetched for every upload,
+// exporting it as metrics and alerting as thresholds are crossed
+type Monitor struct {
+	Config
+
+	mu        sync.Mutex
+	usage     int64
+	limit     int64
+	checkedAt time.Time
+	// reserved is what's been added to usage since it was read
+	reserved int64
+	// alerted is the highest threshold alerted since usage was last below it
+	alerted float64
+
+	now          func() time.Time
+	registration metric.Registration
+}
+
+func New(config Config) *Monitor {
+	monitor := &Monitor{Config: config, now: time.Now}
+
+	monitor.registerMetrics()
+
+	return monitor
+}
+
+// ParseThresholds parses comma separated percentages, e.g. 80,95
+func ParseThresholds(value string) ([]float64, error) {
+	thresholds := []float64{}
+	for _, raw := range strings.Split(value, ",") {
+		raw = strings.TrimSuffix(strings.TrimSpace(raw), "%")
+		if raw == "" {
+			continue
+		}
+
+		percent, err := strconv.ParseFloat(raw, 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid quota threshold %q, expected a percentage", raw)
+		}
+
+		thresholds = append(thresholds, percent/100)
+	}
+
+	slices.Sort(thresholds)
+
+	return slices.Compact(thresholds), nil
+}
+
+// Quota is the cached usage and limit, refreshed once older than TTL. A
+// limit of zero is unlimited
+func (m *Monitor) Quota(ctx context.Context) (int64, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.refresh(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	return m.usage, m.limit, nil
+}
+
+// Reserve checks size more bytes fit within the limit, returning
+// ErrInsufficientStorage when they don't. They're counted as used until the
+// next reading, so uploads in between can't overcommit the space
+func (m *Monitor) Reserve(ctx context.Context, size int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.refresh(ctx); err != nil {
+		return err
+	}
+
+	if m.limit <= 0 {
+		return nil
+	}
+
+	if m.usage+size > m.limit {
+		return fmt.Errorf("%w: %s needed, %s free", ErrInsufficientStorage, emails.FormatBytes(size), emails.FormatBytes(max(m.limit-m.usage, 0)))
+	}
+
+	m.usage += size
+	m.reserved += size
+	m.checkThresholds(ctx)
+
+	return nil
+}
+
+// Release gives back bytes reserved for uploads that failed. Once the
+// quota's been read again they're no longer counted anyway
+func (m *Monitor) Release(size int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	size = min(size, m.reserved)
+	m.usage -= size
+	m.reserved -= size
+}
+
+// refresh reads the quota when the cached reading is stale. A stale reading
+// is kept when the backend can't be reached, rather than failing uploads
+func (m *Monitor) refresh(ctx context.Context) error {
+	if !m.checkedAt.IsZero() && m.now().Sub(m.checkedAt) < m.TTL {
+		return nil
+	}
+
+	usage, limit, err := m.Reporter.Quota(ctx)
+	if err != nil {
+		if m.checkedAt.IsZero() {
+			return err
+		}
+
+		slog.WarnContext(ctx, "warning", "storage quota", err.Error(), "checked", m.checkedAt)
+
+		return nil
+	}
+
+	m.usage, m.limit, m.checkedAt, m.reserved = usage, limit, m.now(), 0
+	slog.DebugContext(ctx, "stats", "storage usage", usage, "storage limit", limit)
+
+	m.checkThresholds(ctx)
+
+	return nil
+}
+
+// checkThresholds alerts the first time usage goes over a threshold, and
+// again if it drops below and crosses it later
+func (m *Monitor) checkThresholds(ctx context.Context) {
+	if m.limit <= 0 {
+		return
+	}
+
+	used := float64(m.usage) / float64(m.limit)
+
+	crossed := 0.0
+	for _, threshold := range m.Thresholds {
+		if used >= threshold {
+			crossed = threshold
+		}
+	}
+
+	if crossed <= m.alerted {
+		m.alerted = crossed
+
+		return
+	}
+	m.alerted = crossed
+
+	title := fmt.Sprintf("Attachment storage is %.0f%% full", used*100)
+	text := fmt.Sprintf("%s of %s used in %s, uploads are refused once it's full", emails.FormatBytes(m.usage), emails.FormatBytes(m.limit), m.Backend)
+
+	slog.WarnContext(ctx, "warning", "storage quota", title, "usage", m.usage, "limit", m.limit)
+
+	if m.Alert != nil {
+		m.Alert(ctx, title, text)
+	}
+}
+
+func (m *Monitor) registerMetrics() {
+	meter := otel.Meter(INSTRUMENTATION_NAME)
+
+	usage, err := meter.Int64ObservableGauge("storage.quota.usage",
+		metric.WithDescription("Bytes used in the attachment storage backend"),
+		metric.WithUnit("By"))
+	if err != nil {
+		slog.Error("error", "quota metrics", err.Error())
+
+		return
+	}
+
+	limit, err := meter.Int64ObservableGauge("storage.quota.limit",
+		metric.WithDescription("Bytes the attachment storage backend is limited to, 0 when unlimited"),
+		metric.WithUnit("By"))
+	if err != nil {
+		slog.Error("error", "quota metrics", err.Error())
+
+		return
+	}
+
+	attributes := metric.WithAttributes(attribute.String("storage.backend", m.Backend))
+	m.registration, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		// keeps the reading fresh between uploads too
+		currentUsage, currentLimit, err := m.Quota(ctx)
+		if err != nil {
+			return err
+		}
+
+		o.ObserveInt64(usage, currentUsage, attributes)
+		o.ObserveInt64(limit, currentLimit, attributes)
+
+		return nil
+	}, usage, limit)
+	if err != nil {
+		slog.Error("error", "quota metrics", err.Error())
+	}
+}
@@ -0,0 +1,116 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeReporter struct {
+	usage int64
+	limit int64
+	err   error
+	calls int
+}
+
+func (f *fakeReporter) Quota(ctx context.Context) (int64, int64, error) {
+	f.calls++
+
+	return f.usage, f.limit, f.err
+}
+
+func TestParseThresholds(t *testing.T) {
+	thresholds, err := ParseThresholds("95, 80%,80")
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0.8, 0.95}, thresholds)
+
+	thresholds, err = ParseThresholds("")
+	assert.NoError(t, err)
+	assert.Empty(t, thresholds)
+
+	for _, value := range []string{"0", "101", "eighty"} {
+		_, err := ParseThresholds(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestMonitorCaches(t *testing.T) {
+	reporter := &fakeReporter{usage: 100, limit: 1000}
+	monitor := New(Config{Reporter: reporter, TTL: time.Minute})
+	now := time.Now()
+	monitor.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	usage, limit, err := monitor.Quota(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), usage)
+	assert.Equal(t, int64(1000), limit)
+
+	reporter.usage = 200
+	usage, _, _ = monitor.Quota(ctx)
+	assert.Equal(t, int64(100), usage, "cached")
+	assert.Equal(t, 1, reporter.calls)
+
+	now = now.Add(2 * time.Minute)
+	usage, _, _ = monitor.Quota(ctx)
+	assert.Equal(t, int64(200), usage, "refreshed")
+
+	reporter.err = errors.New("unavailable")
+	now = now.Add(2 * time.Minute)
+	usage, _, err = monitor.Quota(ctx)
+	assert.NoError(t, err, "the stale reading is used")
+	assert.Equal(t, int64(200), usage)
+
+	_, _, err = New(Config{Reporter: reporter}).Quota(ctx)
+	assert.Error(t, err, "no reading to fall back on")
+}
+
+func TestMonitorReserve(t *testing.T) {
+	reporter := &fakeReporter{usage: 700, limit: 1000}
+	monitor := New(Config{Reporter: reporter, TTL: time.Minute})
+	ctx := context.Background()
+
+	assert.NoError(t, monitor.Reserve(ctx, 200))
+	assert.ErrorIs(t, monitor.Reserve(ctx, 200), ErrInsufficientStorage, "the first upload is counted until the next reading")
+	assert.NoError(t, monitor.Reserve(ctx, 100))
+
+	// the uploads failed
+	monitor.Release(300)
+	assert.NoError(t, monitor.Reserve(ctx, 300))
+
+	monitor.Release(1000)
+	usage, _, _ := monitor.Quota(ctx)
+	assert.Equal(t, int64(700), usage, "only what was reserved is released")
+
+	unlimited := New(Config{Reporter: &fakeReporter{usage: 5000}, TTL: time.Minute})
+	assert.NoError(t, unlimited.Reserve(ctx, 1<<30))
+}
+
+func TestMonitorAlerts(t *testing.T) {
+	reporter := &fakeReporter{usage: 500, limit: 1000}
+	alerts := []string{}
+	monitor := New(Config{
+		Reporter:   reporter,
+		Backend:    "gdrive",
+		Thresholds: []float64{0.8, 0.95},
+		Alert: func(ctx context.Context, title string, text string) {
+			alerts = append(alerts, title)
+		},
+	})
+	ctx := context.Background()
+
+	// a zero TTL reads the quota every time
+	for _, usage := range []int64{500, 810, 850, 960, 700, 820} {
+		reporter.usage = usage
+		monitor.Quota(ctx)
+	}
+
+	assert.Equal(t, []string{
+		"Attachment storage is 81% full",
+		"Attachment storage is 96% full",
+		"Attachment storage is 82% full",
+	}, alerts)
+}
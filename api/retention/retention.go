@@ -0,0 +1,362 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"skulpture/landing/database"
+	"skulpture/landing/leads"
+	"skulpture/landing/storage"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ACTOR is recorded in the audit log for what the job removes
+const ACTOR = "retention"
+
+// LEASE is the job lease replicas take turns holding
+const LEASE = "retention"
+
+var ErrInvalidPolicy = errors.New("invalid retention policy")
+
+// Policy is how long an environment's data is kept, zero keeps it
+type Policy struct {
+	Attachments time.Duration
+	Leads       time.Duration
+}
+
+// ParsePolicies parses comma separated environment[:attachments|leads]=age
+// policies, e.g. development=7d,production:attachments=730d. An environment
+// without a kind applies to both. Ages are whole days or Go durations
+func ParsePolicies(value string) (map[string]Policy, error) {
+	policies := map[string]Policy{}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, rawAge, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w %q: expected environment=age", ErrInvalidPolicy, entry)
+		}
+
+		age, err := parseAge(strings.TrimSpace(rawAge))
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %w", ErrInvalidPolicy, entry, err)
+		}
+
+		environment, kind, _ := strings.Cut(strings.TrimSpace(key), ":")
+		if environment == "" {
+			return nil, fmt.Errorf("%w %q: missing environment", ErrInvalidPolicy, entry)
+		}
+
+		policy := policies[environment]
+		switch kind {
+		case "":
+			policy.Attachments = age
+			policy.Leads = age
+		case "attachments":
+			policy.Attachments = age
+		case "leads":
+			policy.Leads = age
+		default:
+			return nil, fmt.Errorf("%w %q: expected attachments or leads, got %q", ErrInvalidPolicy, entry, kind)
+		}
+		policies[environment] = policy
+	}
+
+	return policies, nil
+}
+
+func parseAge(value string) (time.Duration, error) {
+	var age time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, err
+		}
+
+		age = duration
+	}
+
+	if age <= 0 {
+		return 0, errors.New("age must be positive")
+	}
+
+	return age, nil
+}
+
+// Sheets is the spreadsheet leads are mirrored to
+type Sheets interface {
+	RowsByLead(ctx context.Context, ids []string) ([]leads.SheetRow, error)
+	ClearRows(ctx context.Context, rows []leads.SheetRow) error
+}
+
+// Webhooks keeps copies of lead events until they're delivered
+type Webhooks interface {
+	Forget(ctx context.Context, value string) (int64, error)
+}
+
+type Config struct {
+	// Environment is the one the policy applies to, data from other
+	// environments sharing the backend is left alone
+	Environment string
+	Policy      Policy
+	// DB holds the lease replicas share
+	DB    *sql.DB
+	Store *leads.SQLiteStore
+	Files storage.Store
+	// Backend is the name Files is recorded under on attachments
+	Backend string
+	// Sheets and Webhooks are optional
+	Sheets   Sheets
+	Webhooks Webhooks
+	// Archive moves files into the backend's archive rather than deleting
+	// them, leads are always deleted
+	Archive bool
+	// DryRun only logs what would be removed
+	DryRun bool
+}
+
+// Job removes data older than the environment's policy
+type Job struct {
+	Config
+
+	now func() time.Time
+}
+
+func New(config Config) (*Job, error) {
+	if _, ok := config.Files.(storage.Lister); config.Policy.Attachments > 0 && !ok {
+		return nil, errors.New("the storage backend can't list files for an attachments policy, use its lifecycle rules instead")
+	}
+
+	if _, ok := config.Files.(storage.Archiver); config.Archive && !ok {
+		return nil, errors.New("the storage backend can't archive files")
+	}
+
+	return &Job{Config: config, now: time.Now}, nil
+}
+
+// Result is what was removed, or would be on a dry run
+type Result struct {
+	Files      []storage.File
+	Leads      []string
+	SheetRows  int
+	Deliveries int64
+	DryRun     bool
+
+	// removed is Files by id, a lead's attachments may already be removed
+	removed map[string]bool
+}
+
+// Run purges every interval while this replica holds the lease, which is
+// held for the interval so the job runs once per interval across replicas
+func (j *Job) Run(ctx context.Context, interval time.Duration) {
+	holder := uuid.NewString()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		acquired, err := database.AcquireLease(ctx, j.DB, LEASE, holder, interval)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "error", "retention", err.Error())
+		}
+
+		if acquired {
+			// logged even when it failed part way, to show what was removed
+			result, err := j.Purge(ctx)
+			if err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "error", "retention", err.Error())
+			}
+
+			slog.InfoContext(ctx, "retention", "environment", j.Environment, "files", len(result.Files), "leads", len(result.Leads),
+				"sheetRows", result.SheetRows, "webhookDeliveries", result.Deliveries, "dryRun", result.DryRun)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge removes attachments then leads older than the policy. A failure
+// part way through is returned with what was removed before it, whatever
+// is left is removed next time
+func (j *Job) Purge(ctx context.Context) (*Result, error) {
+	result := &Result{Files: []storage.File{}, Leads: []string{}, DryRun: j.DryRun, removed: map[string]bool{}}
+
+	if j.Policy.Attachments > 0 {
+		if err := j.purgeFiles(ctx, result); err != nil {
+			return result, fmt.Errorf("attachments: %w", err)
+		}
+	}
+
+	if j.Policy.Leads > 0 {
+		if err := j.purgeLeads(ctx, result); err != nil {
+			return result, fmt.Errorf("leads: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+func (j *Job) purgeFiles(ctx context.Context, result *Result) error {
+	files, err := j.Files.(storage.Lister).ListCreatedBefore(ctx, j.Environment, j.now().Add(-j.Policy.Attachments))
+	if err != nil {
+		return err
+	}
+
+	ids, removeErr := j.removeFiles(ctx, files, result)
+
+	// archived files are still attachments, found by exports and erasures
+	if j.Archive || j.DryRun {
+		return removeErr
+	}
+
+	return errors.Join(removeErr, j.Store.RemoveAttachments(ctx, j.Backend, ids, ACTOR))
+}
+
+func (j *Job) purgeLeads(ctx context.Context, result *Result) error {
+	age := j.Policy.Leads
+
+	found, err := j.Store.LeadsCreatedBefore(ctx, j.Environment, j.now().Add(-age))
+	if err != nil {
+		return err
+	}
+
+	if len(found) == 0 {
+		return nil
+	}
+
+	ids := []string{}
+	files := []storage.File{}
+	for _, lead := range found {
+		ids = append(ids, lead.Id)
+
+		for _, attachment := range lead.Attachments {
+			if attachment.Backend != j.Backend {
+				slog.WarnContext(ctx, "warning", "retention", "attachment is in another storage backend", "lead", lead.Id, "backend", attachment.Backend)
+
+				continue
+			}
+
+			files = append(files, storage.File{
+				Id:        attachment.Id,
+				Filename:  attachment.Filename,
+				Metadata:  map[string]string{storage.META_LEAD: lead.Id},
+				CreatedAt: lead.CreatedAt,
+			})
+		}
+	}
+
+	// files first, so a lead isn't removed while its files can't be
+	if _, err := j.removeFiles(ctx, files, result); err != nil {
+		return err
+	}
+
+	var rows []leads.SheetRow
+	if j.Sheets != nil {
+		if rows, err = j.Sheets.RowsByLead(ctx, ids); err != nil {
+			return fmt.Errorf("finding sheet rows: %w", err)
+		}
+	}
+
+	if j.DryRun {
+		for _, lead := range found {
+			slog.InfoContext(ctx, "retention dry run", "lead", lead.Id, "created", lead.CreatedAt)
+		}
+
+		result.Leads = append(result.Leads, ids...)
+		result.SheetRows += len(rows)
+
+		return nil
+	}
+
+	if j.Sheets != nil {
+		if err := j.Sheets.ClearRows(ctx, rows); err != nil {
+			return fmt.Errorf("clearing sheet rows: %w", err)
+		}
+
+		result.SheetRows += len(rows)
+	}
+
+	if j.Webhooks != nil {
+		for _, id := range ids {
+			deleted, err := j.Webhooks.Forget(ctx, id)
+			if err != nil {
+				return fmt.Errorf("deleting webhook deliveries: %w", err)
+			}
+
+			result.Deliveries += deleted
+		}
+	}
+
+	if err := j.Store.Erase(ctx, ids, ACTOR, fmt.Sprintf("older than %s", formatAge(age))); err != nil {
+		return err
+	}
+
+	result.Leads = append(result.Leads, ids...)
+
+	return nil
+}
+
+// removeFiles deletes or archives the files, carrying on past failures so
+// one bad file doesn't hold up the rest. The ids removed are returned
+func (j *Job) removeFiles(ctx context.Context, files []storage.File, result *Result) ([]string, error) {
+	ids := []string{}
+
+	var errs []error
+	for _, file := range files {
+		if result.removed[file.Id] {
+			continue
+		}
+
+		if j.DryRun {
+			slog.InfoContext(ctx, "retention dry run", "file", file.Id, "lead", file.Metadata[storage.META_LEAD], "created", file.CreatedAt, "archive", j.Archive)
+		} else if err := j.remove(ctx, file.Id); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file.Id, err))
+
+			continue
+		}
+
+		ids = append(ids, file.Id)
+		result.Files = append(result.Files, file)
+		result.removed[file.Id] = true
+	}
+
+	return ids, errors.Join(errs...)
+}
+
+func (j *Job) remove(ctx context.Context, id string) error {
+	if j.Archive {
+		return j.Files.(storage.Archiver).Archive(ctx, id)
+	}
+
+	return j.Files.Delete(ctx, id)
+}
+
+// formatAge shows whole days as days, e.g. 730d
+func formatAge(age time.Duration) string {
+	if age%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", age/(24*time.Hour))
+	}
+
+	return age.String()
+}
@@ -0,0 +1,212 @@
+package retention
+
+import (
+	"context"
+	enums "skulpture/landing/enums"
+	"skulpture/landing/leads"
+	"skulpture/landing/leads/leadstest"
+	"skulpture/landing/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected map[string]Policy
+		err      bool
+	}{
+		{"empty", "", map[string]Policy{}, false},
+		{
+			"both and one kind",
+			"development=7d, production:attachments=730d",
+			map[string]Policy{
+				"development": {Attachments: 7 * 24 * time.Hour, Leads: 7 * 24 * time.Hour},
+				"production":  {Attachments: 730 * 24 * time.Hour},
+			},
+			false,
+		},
+		{
+			"kinds combine",
+			"production:attachments=730d,production:leads=1095d",
+			map[string]Policy{"production": {Attachments: 730 * 24 * time.Hour, Leads: 1095 * 24 * time.Hour}},
+			false,
+		},
+		{"go duration", "test=36h", map[string]Policy{"test": {Attachments: 36 * time.Hour, Leads: 36 * time.Hour}}, false},
+		{"missing age", "development", nil, true},
+		{"unknown kind", "development:sheets=7d", nil, true},
+		{"invalid age", "development=a week", nil, true},
+		{"zero age", "development=0d", nil, true},
+		{"missing environment", ":leads=7d", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policies, err := ParsePolicies(test.value)
+			if test.err {
+				assert.ErrorIs(t, err, ErrInvalidPolicy)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, policies)
+		})
+	}
+}
+
+// unlistedStore can't be searched, like S3
+type unlistedStore struct {
+	storage.Store
+}
+
+type fixture struct {
+	*leadstest.Fixture
+	config Config
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	ctx := context.Background()
+
+	f := &fixture{Fixture: leadstest.New(t)}
+	now := time.Now().UTC()
+
+	for _, lead := range []*leads.Lead{
+		{Id: "old", Environment: string(enums.Development), CreatedAt: now.Add(-30 * 24 * time.Hour)},
+		{Id: "recent", Environment: string(enums.Development), CreatedAt: now},
+		{Id: "production", Environment: string(enums.Production), CreatedAt: now.Add(-30 * 24 * time.Hour)},
+	} {
+		id := f.Put(t, "brief.pdf", "%PDF", map[string]string{storage.META_LEAD: lead.Id, storage.META_ENVIRONMENT: lead.Environment})
+
+		lead.Email = "jane@example.com"
+		lead.Attachments = []leads.Attachment{{Id: id, Backend: string(enums.StorageLocal), Filename: "brief.pdf"}}
+		if err := f.Store.Create(ctx, lead); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f.Sheets.Rows = []leads.SheetRow{{Tab: "Sheet1", Number: 2}}
+	f.config = Config{
+		Environment: string(enums.Development),
+		DB:          f.DB,
+		Store:       f.Store,
+		Files:       f.Files,
+		Backend:     string(enums.StorageLocal),
+		Sheets:      f.Sheets,
+		Webhooks:    f.Webhooks,
+	}
+
+	return f
+}
+
+func TestPurgeAttachments(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	f.config.Policy = Policy{Attachments: 7 * 24 * time.Hour}
+	job, err := New(f.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the files were all just uploaded
+	job.now = func() time.Time { return time.Now().Add(8 * 24 * time.Hour) }
+
+	result, err := job.Purge(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, result.Files, 2, "only development files")
+	assert.Empty(t, result.Leads)
+	assert.False(t, f.Exists(t, "old/brief.pdf"))
+	assert.False(t, f.Exists(t, "recent/brief.pdf"))
+	assert.True(t, f.Exists(t, "production/brief.pdf"))
+
+	lead, err := f.config.Store.Get(ctx, "old")
+	assert.NoError(t, err)
+	assert.Empty(t, lead.Attachments)
+
+	entries, err := f.config.Store.AuditLog(ctx, "old")
+	assert.NoError(t, err)
+	assert.Equal(t, ACTOR, entries[len(entries)-1].Actor)
+	assert.Equal(t, leads.AUDIT_RETENTION, entries[len(entries)-1].Action)
+}
+
+func TestPurgeLeads(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	f.config.Policy = Policy{Leads: 7 * 24 * time.Hour}
+	job, err := New(f.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := job.Purge(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"old"}, result.Leads)
+	assert.Len(t, result.Files, 1)
+	assert.Equal(t, 1, result.SheetRows)
+	assert.Equal(t, int64(1), result.Deliveries)
+	assert.Equal(t, f.Sheets.Rows, f.Sheets.Cleared)
+	assert.Equal(t, []string{"old"}, f.Webhooks.Forgotten)
+	assert.False(t, f.Exists(t, "old/brief.pdf"))
+	assert.True(t, f.Exists(t, "recent/brief.pdf"))
+
+	_, err = f.config.Store.Get(ctx, "old")
+	assert.ErrorIs(t, err, leads.ErrNotFound)
+	_, err = f.config.Store.Get(ctx, "production")
+	assert.NoError(t, err, "other environments are left alone")
+
+	entries, err := f.config.Store.AuditLog(ctx, "old")
+	assert.NoError(t, err)
+	assert.Equal(t, leads.AUDIT_ERASE, entries[len(entries)-1].Action)
+	assert.Equal(t, "older than 7d", entries[len(entries)-1].Detail)
+}
+
+func TestPurgeDryRun(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	f.config.Policy = Policy{Attachments: 7 * 24 * time.Hour, Leads: 7 * 24 * time.Hour}
+	f.config.DryRun = true
+	job, err := New(f.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.now = func() time.Time { return time.Now().Add(8 * 24 * time.Hour) }
+
+	result, err := job.Purge(ctx)
+	assert.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Len(t, result.Files, 2, "the leads' attachments were already listed")
+	assert.Equal(t, []string{"old", "recent"}, result.Leads)
+	assert.Equal(t, 1, result.SheetRows)
+
+	assert.True(t, f.Exists(t, "old/brief.pdf"))
+	assert.Empty(t, f.Sheets.Cleared)
+	assert.Empty(t, f.Webhooks.Forgotten)
+
+	lead, err := f.config.Store.Get(ctx, "old")
+	assert.NoError(t, err)
+	assert.Len(t, lead.Attachments, 1)
+}
+
+func TestNewRequiresBackendSupport(t *testing.T) {
+	f := newFixture(t)
+
+	f.config.Files = unlistedStore{f.Files}
+	f.config.Policy = Policy{Leads: time.Hour}
+	_, err := New(f.config)
+	assert.NoError(t, err, "leads don't need the backend listed")
+
+	f.config.Policy = Policy{Attachments: time.Hour}
+	_, err = New(f.config)
+	assert.Error(t, err)
+
+	f.config.Files = f.Files
+	f.config.Archive = true
+	_, err = New(f.config)
+	assert.Error(t, err, "local files can't be archived")
+}
@@ -0,0 +1,162 @@
+package routing
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is read from YAML, e.g.
+//
+//	default:
+//	  recipients: [hey@skulpture.xyz]
+//	rules:
+//	  - name: careers
+//	    match:
+//	      categories: [careers]
+//	      keywords: [job, internship, cv]
+//	    recipients: [careers@skulpture.xyz]
+//	    sheet: Careers
+//	    folder: careers
+//
+// Rules are checked in order and the first match wins
+type Config struct {
+	Default Route  `yaml:"default"`
+	Rules   []Rule `yaml:"rules"`
+}
+
+type Rule struct {
+	Name  string `yaml:"name"`
+	Match Match  `yaml:"match"`
+	Route `yaml:",inline"`
+}
+
+// Match requires every condition that is set. Within a condition any
+// value may match, e.g. any keyword or any domain
+type Match struct {
+	// Keywords are matched case-insensitively against the enquiry text
+	Keywords []string `yaml:"keywords"`
+	// Domains match the email's domain or any of its subdomains
+	Domains    []string `yaml:"domains"`
+	Categories []string `yaml:"categories"`
+	// HasAttachments matches either way when unset
+	HasAttachments *bool `yaml:"hasAttachments"`
+}
+
+// Route is where an enquiry goes. Empty fields are taken from the default
+type Route struct {
+	// Rule is the name of the matched rule, empty for the default
+	Rule       string   `yaml:"-"`
+	Recipients []string `yaml:"recipients"`
+	// Sheet is the Google Sheets tab
+	Sheet string `yaml:"sheet"`
+	// Folder is the storage subfolder, e.g. within the Drive folder
+	Folder string `yaml:"folder"`
+}
+
+type Enquiry struct {
+	Text           string
+	Email          string
+	Category       string
+	HasAttachments bool
+}
+
+type Router struct {
+	config Config
+}
+
+// Parse reads rules from YAML, the fallback route fills in anything the
+// configured default leaves empty
+func Parse(data []byte, fallback Route) (*Router, error) {
+	config := Config{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid routing rules: %w", err)
+	}
+
+	for i, rule := range config.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("invalid routing rules: rule %d has no name", i+1)
+		}
+	}
+
+	config.Default = merge(config.Default, fallback)
+
+	return &Router{
+		config: config,
+	}, nil
+}
+
+func Load(path string, fallback Route) (*Router, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data, fallback)
+}
+
+func (r *Router) Route(enquiry Enquiry) Route {
+	for _, rule := range r.config.Rules {
+		if rule.Match.matches(enquiry) {
+			route := merge(rule.Route, r.config.Default)
+			route.Rule = rule.Name
+
+			return route
+		}
+	}
+
+	return r.config.Default
+}
+
+func (m Match) matches(enquiry Enquiry) bool {
+	if len(m.Keywords) > 0 {
+		text := strings.ToLower(enquiry.Text)
+		if !slices.ContainsFunc(m.Keywords, func(keyword string) bool {
+			return strings.Contains(text, strings.ToLower(keyword))
+		}) {
+			return false
+		}
+	}
+
+	if len(m.Domains) > 0 {
+		_, domain, _ := strings.Cut(strings.ToLower(enquiry.Email), "@")
+		if !slices.ContainsFunc(m.Domains, func(candidate string) bool {
+			candidate = strings.ToLower(strings.TrimPrefix(candidate, "@"))
+
+			return domain == candidate || strings.HasSuffix(domain, "."+candidate)
+		}) {
+			return false
+		}
+	}
+
+	if len(m.Categories) > 0 {
+		if !slices.ContainsFunc(m.Categories, func(category string) bool {
+			return strings.EqualFold(category, enquiry.Category)
+		}) {
+			return false
+		}
+	}
+
+	if m.HasAttachments != nil && *m.HasAttachments != enquiry.HasAttachments {
+		return false
+	}
+
+	return true
+}
+
+func merge(route Route, fallback Route) Route {
+	if len(route.Recipients) == 0 {
+		route.Recipients = fallback.Recipients
+	}
+	if route.Sheet == "" {
+		route.Sheet = fallback.Sheet
+	}
+	if route.Folder == "" {
+		route.Folder = fallback.Folder
+	}
+
+	return route
+}
@@ -0,0 +1,120 @@
+package routing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const rules = `
+default:
+  recipients: [hey@skulpture.xyz]
+rules:
+  - name: careers
+    match:
+      categories: [careers]
+    recipients: [careers@skulpture.xyz]
+    sheet: Careers
+    folder: careers
+  - name: billing
+    match:
+      keywords: [invoice, refund]
+    recipients: [billing@skulpture.xyz, accounts@skulpture.xyz]
+    sheet: Billing
+  - name: partners
+    match:
+      domains: [partner.co.nz]
+      hasAttachments: true
+    folder: partners
+  - name: briefs
+    match:
+      categories: [sales]
+      hasAttachments: true
+    sheet: Briefs
+`
+
+func TestRoute(t *testing.T) {
+	router, err := Parse([]byte(rules), Route{Recipients: []string{"fallback@skulpture.xyz"}, Sheet: "Sheet1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		enquiry  Enquiry
+		expected Route
+	}{
+		{
+			name:     "no rule matches",
+			enquiry:  Enquiry{Text: "Hello", Email: "jane@example.com"},
+			expected: Route{Recipients: []string{"hey@skulpture.xyz"}, Sheet: "Sheet1"},
+		},
+		{
+			name:     "category",
+			enquiry:  Enquiry{Text: "Hello", Email: "jane@example.com", Category: "Careers"},
+			expected: Route{Rule: "careers", Recipients: []string{"careers@skulpture.xyz"}, Sheet: "Careers", Folder: "careers"},
+		},
+		{
+			name:     "keyword is case insensitive",
+			enquiry:  Enquiry{Text: "Where is my INVOICE?", Email: "jane@example.com"},
+			expected: Route{Rule: "billing", Recipients: []string{"billing@skulpture.xyz", "accounts@skulpture.xyz"}, Sheet: "Billing"},
+		},
+		{
+			name:     "first match wins",
+			enquiry:  Enquiry{Text: "A refund for my application fee", Email: "jane@example.com", Category: "careers"},
+			expected: Route{Rule: "careers", Recipients: []string{"careers@skulpture.xyz"}, Sheet: "Careers", Folder: "careers"},
+		},
+		{
+			name:     "subdomain with attachments",
+			enquiry:  Enquiry{Text: "Brief attached", Email: "sam@mail.partner.co.nz", HasAttachments: true},
+			expected: Route{Rule: "partners", Recipients: []string{"hey@skulpture.xyz"}, Sheet: "Sheet1", Folder: "partners"},
+		},
+		{
+			name:     "every condition must match",
+			enquiry:  Enquiry{Text: "Hello", Email: "sam@partner.co.nz"},
+			expected: Route{Recipients: []string{"hey@skulpture.xyz"}, Sheet: "Sheet1"},
+		},
+		{
+			name:     "domain must be whole",
+			enquiry:  Enquiry{Text: "Hello", Email: "sam@notpartner.co.nz", HasAttachments: true},
+			expected: Route{Recipients: []string{"hey@skulpture.xyz"}, Sheet: "Sheet1"},
+		},
+		{
+			name:     "category and attachments",
+			enquiry:  Enquiry{Text: "Quote please", Email: "jane@example.com", Category: "sales", HasAttachments: true},
+			expected: Route{Rule: "briefs", Recipients: []string{"hey@skulpture.xyz"}, Sheet: "Briefs"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, router.Route(test.enquiry))
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	router, err := Parse(nil, Route{Recipients: []string{"hey@skulpture.xyz"}, Sheet: "Sheet1"})
+	assert.NoError(t, err)
+	assert.Equal(t, Route{Recipients: []string{"hey@skulpture.xyz"}, Sheet: "Sheet1"}, router.Route(Enquiry{}))
+
+	_, err = Parse([]byte("rules:\n  - match:\n      keywords: [job]\n"), Route{})
+	assert.Error(t, err, "rules need a name")
+
+	_, err = Parse([]byte("rules: {"), Route{})
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routing.yml")
+	assert.NoError(t, os.WriteFile(path, []byte(rules), 0o600))
+
+	router, err := Load(path, Route{})
+	assert.NoError(t, err)
+	assert.Equal(t, "careers", router.Route(Enquiry{Category: "careers"}).Rule)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yml"), Route{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,126 @@
+package statuslink
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MIN_SECRET_LENGTH is the shortest secret accepted, in bytes
+const MIN_SECRET_LENGTH = 32
+
+var ErrInvalidToken = errors.New("invalid status token")
+var ErrExpired = errors.New("status link has expired")
+
+type Key struct {
+	Id     string
+	Secret []byte
+}
+
+// Signer signs status links with the first key and accepts tokens signed
+// by any of them, so keys are rotated by adding a new one first and
+// removing the old one once its links have expired
+type Signer struct {
+	keys []Key
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// ParseKeys parses comma separated id=secret pairs, e.g. 2026-10=...,2026-04=...
+func ParseKeys(value string) ([]Key, error) {
+	keys := []Key{}
+	seen := map[string]bool{}
+	for _, pair := range strings.Split(value, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || id == "" || strings.Contains(id, ".") {
+			return nil, fmt.Errorf("invalid status link key %q, expected id=secret", id)
+		}
+
+		if len(secret) < MIN_SECRET_LENGTH {
+			return nil, fmt.Errorf("status link key %q must be at least %d bytes", id, MIN_SECRET_LENGTH)
+		}
+
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate status link key %q", id)
+		}
+		seen[id] = true
+
+		keys = append(keys, Key{Id: id, Secret: []byte(secret)})
+	}
+
+	return keys, nil
+}
+
+func NewSigner(keys []Key, ttl time.Duration) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one status link key is required")
+	}
+
+	return &Signer{
+		keys: keys,
+		ttl:  ttl,
+		now:  time.Now,
+	}, nil
+}
+
+// Sign returns a token for the lead, formatted as <key id>.<expiry>.<signature>
+func (s *Signer) Sign(leadId string) string {
+	key := s.keys[0]
+	expires := strconv.FormatInt(s.now().Add(s.ttl).Unix(), 10)
+
+	return key.Id + "." + expires + "." + signature(key, leadId, expires)
+}
+
+// Verify returns ErrInvalidToken unless the token was signed for the lead by
+// a current key, and ErrExpired once it's past its expiry
+func (s *Signer) Verify(leadId string, token string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrInvalidToken
+	}
+
+	id, expires, sig := parts[0], parts[1], parts[2]
+	for _, key := range s.keys {
+		if key.Id != id {
+			continue
+		}
+
+		if !hmac.Equal([]byte(sig), []byte(signature(key, leadId, expires))) {
+			return ErrInvalidToken
+		}
+
+		unix, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil {
+			return ErrInvalidToken
+		}
+
+		if s.now().After(time.Unix(unix, 0)) {
+			return ErrExpired
+		}
+
+		return nil
+	}
+
+	return ErrInvalidToken
+}
+
+// URL is the lead's status link under base, e.g. https://api.skulpture.xyz
+func (s *Signer) URL(base *url.URL, leadId string) string {
+	link := base.JoinPath("/api/v1/contact", leadId)
+	link.RawQuery = url.Values{"token": {s.Sign(leadId)}}.Encode()
+
+	return link.String()
+}
+
+func signature(key Key, leadId string, expires string) string {
+	mac := hmac.New(sha256.New, key.Secret)
+	mac.Write([]byte("status:" + leadId + ":" + expires))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,106 @@
+package statuslink
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	enums "skulpture/landing/enums"
+	"skulpture/landing/leads"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Status string
+
+const (
+	StatusReceived  Status = "received"
+	StatusInReview  Status = "in_review"
+	StatusResponded Status = "responded"
+)
+
+//go:embed templates/status.html.tmpl
+var files embed.FS
+
+// page is shown when the link is opened in a browser, e.g. from the
+// confirmation email
+var page = template.Must(template.ParseFS(files, "templates/status.html.tmpl"))
+
+// response is all that's exposed, the link may be forwarded or leaked
+type response struct {
+	Status     Status    `json:"status"`
+	ReceivedAt time.Time `json:"receivedAt"`
+}
+
+// StatusOf is what the enquirer is told. Spam looks like any other
+// received enquiry
+func StatusOf(lead *leads.Lead) Status {
+	switch lead.State {
+	case enums.LeadContacted, enums.LeadWon, enums.LeadLost:
+		return StatusResponded
+	case enums.LeadTriaged:
+		return StatusInReview
+	}
+
+	if lead.ProcessingStatus == enums.ProcessingQuarantined && lead.State != enums.LeadSpam {
+		return StatusInReview
+	}
+
+	return StatusReceived
+}
+
+// Handler serves GET /contact/{uuid}?token=, as HTML when the client accepts
+// it and JSON otherwise. Unknown leads and invalid tokens are both not
+// found, so ids can't be probed for
+func Handler(store *leads.SQLiteStore, signer *Signer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		id := chi.URLParam(r, "uuid")
+
+		err := signer.Verify(id, r.URL.Query().Get("token"))
+		if errors.Is(err, ErrExpired) {
+			http.Error(w, "This link has expired", http.StatusGone)
+
+			return
+		}
+		if err != nil {
+			http.Error(w, "Not found", http.StatusNotFound)
+
+			return
+		}
+
+		lead, err := store.Get(r.Context(), id)
+		if errors.Is(err, leads.ErrNotFound) {
+			http.Error(w, "Not found", http.StatusNotFound)
+
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "error", "status link", err.Error(), "lead", id)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+
+			return
+		}
+
+		body := response{Status: StatusOf(lead), ReceivedAt: lead.CreatedAt.UTC()}
+
+		if strings.Contains(r.Header.Get("Accept"), "text/html") {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			if err := page.Execute(w, body); err != nil {
+				slog.ErrorContext(r.Context(), "error", "status link page", err.Error(), "lead", id)
+			}
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			slog.ErrorContext(r.Context(), "error", "status link response", err.Error(), "lead", id)
+		}
+	}
+}
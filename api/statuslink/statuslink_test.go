@@ -0,0 +1,201 @@
+package statuslink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"skulpture/landing/database/databasetest"
+	enums "skulpture/landing/enums"
+	"skulpture/landing/leads"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+var current = Key{Id: "2026-10", Secret: []byte(strings.Repeat("a", MIN_SECRET_LENGTH))}
+var previous = Key{Id: "2026-04", Secret: []byte(strings.Repeat("b", MIN_SECRET_LENGTH))}
+
+func TestParseKeys(t *testing.T) {
+	secret := strings.Repeat("s", MIN_SECRET_LENGTH)
+
+	tests := []struct {
+		name  string
+		value string
+		ids   []string
+		err   bool
+	}{
+		{"single", "2026-10=" + secret, []string{"2026-10"}, false},
+		{"rotated", "2026-10=" + secret + ", 2026-04=" + secret, []string{"2026-10", "2026-04"}, false},
+		{"secret with equals", "k=" + secret + "==", []string{"k"}, false},
+		{"missing id", "=" + secret, nil, true},
+		{"dot in id", "2026.10=" + secret, nil, true},
+		{"short secret", "k=short", nil, true},
+		{"duplicate", "k=" + secret + ",k=" + secret, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys, err := ParseKeys(test.value)
+			if test.err {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+
+			ids := []string{}
+			for _, key := range keys {
+				ids = append(ids, key.Id)
+			}
+			assert.Equal(t, test.ids, ids)
+		})
+	}
+}
+
+func TestSigner(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	signer, err := NewSigner([]Key{current, previous}, time.Hour)
+	assert.NoError(t, err)
+	signer.now = func() time.Time { return now }
+
+	old, _ := NewSigner([]Key{previous}, time.Hour)
+	old.now = signer.now
+
+	retired, _ := NewSigner([]Key{{Id: "2025-10", Secret: previous.Secret}}, time.Hour)
+	retired.now = signer.now
+
+	token := signer.Sign("lead-1")
+	assert.True(t, strings.HasPrefix(token, "2026-10."))
+
+	tests := []struct {
+		name   string
+		leadId string
+		token  string
+		err    error
+	}{
+		{"valid", "lead-1", token, nil},
+		{"previous key", "lead-1", old.Sign("lead-1"), nil},
+		{"retired key", "lead-1", retired.Sign("lead-1"), ErrInvalidToken},
+		{"other lead", "lead-2", token, ErrInvalidToken},
+		{"tampered expiry", "lead-1", strings.Replace(token, ".", ".9", 1), ErrInvalidToken},
+		{"empty", "lead-1", "", ErrInvalidToken},
+		{"malformed", "lead-1", "2026-10.abc", ErrInvalidToken},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.err, signer.Verify(test.leadId, test.token))
+		})
+	}
+
+	now = now.Add(time.Hour + time.Second)
+	assert.Equal(t, ErrExpired, signer.Verify("lead-1", token))
+
+	_, err = NewSigner(nil, time.Hour)
+	assert.Error(t, err)
+}
+
+func TestSignerURL(t *testing.T) {
+	signer, _ := NewSigner([]Key{current}, time.Hour)
+	base, _ := url.Parse("https://api.skulpture.xyz/")
+
+	link, err := url.Parse(signer.URL(base, "lead-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/v1/contact/lead-1", link.Path)
+	assert.NoError(t, signer.Verify("lead-1", link.Query().Get("token")))
+}
+
+func TestStatusOf(t *testing.T) {
+	tests := []struct {
+		state    enums.LeadState
+		status   enums.ProcessingStatus
+		expected Status
+	}{
+		{enums.LeadNew, enums.ProcessingConfirmed, StatusReceived},
+		{enums.LeadNew, enums.ProcessingQuarantined, StatusInReview},
+		{enums.LeadTriaged, enums.ProcessingConfirmed, StatusInReview},
+		{enums.LeadContacted, enums.ProcessingConfirmed, StatusResponded},
+		{enums.LeadWon, enums.ProcessingConfirmed, StatusResponded},
+		{enums.LeadLost, enums.ProcessingConfirmed, StatusResponded},
+		{enums.LeadSpam, enums.ProcessingQuarantined, StatusReceived},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, StatusOf(&leads.Lead{State: test.state, ProcessingStatus: test.status}), test.state)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	db := databasetest.Open(t)
+
+	store := leads.NewSQLiteStore(db)
+	lead := &leads.Lead{
+		Id:               "9b2f8c4e-0000-4000-8000-000000000600",
+		FirstName:        "Jane",
+		LastName:         "Doe",
+		Email:            "jane@example.com",
+		Enquiry:          "A new website",
+		Environment:      string(enums.Test),
+		ProcessingStatus: enums.ProcessingConfirmed,
+	}
+	assert.NoError(t, store.Create(context.Background(), lead))
+
+	signer, _ := NewSigner([]Key{current}, time.Hour)
+	expired, _ := NewSigner([]Key{current}, -time.Hour)
+
+	r := chi.NewRouter()
+	r.Get("/contact/{uuid}", Handler(store, signer))
+
+	tests := []struct {
+		name   string
+		id     string
+		token  string
+		status int
+	}{
+		{"valid", lead.Id, signer.Sign(lead.Id), http.StatusOK},
+		{"expired", lead.Id, expired.Sign(lead.Id), http.StatusGone},
+		{"missing token", lead.Id, "", http.StatusNotFound},
+		{"other lead's token", lead.Id, signer.Sign("9b2f8c4e-0000-4000-8000-000000000601"), http.StatusNotFound},
+		{"unknown lead", "9b2f8c4e-0000-4000-8000-000000000601", signer.Sign("9b2f8c4e-0000-4000-8000-000000000601"), http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/contact/"+test.id+"?token="+url.QueryEscape(test.token), nil))
+
+			assert.Equal(t, test.status, res.Code)
+			assert.Equal(t, "no-store", res.Header().Get("Cache-Control"))
+
+			if test.status != http.StatusOK {
+				return
+			}
+
+			var body map[string]any
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+			assert.Equal(t, map[string]any{"status": "received", "receivedAt": lead.CreatedAt.UTC().Format(time.RFC3339Nano)}, body)
+		})
+	}
+
+	t.Run("browser", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/contact/"+lead.Id+"?token="+url.QueryEscape(signer.Sign(lead.Id)), nil)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+		assert.Equal(t, "no-store", res.Header().Get("Cache-Control"))
+		assert.Contains(t, res.Body.String(), "We've received your enquiry")
+		assert.Contains(t, res.Body.String(), lead.CreatedAt.UTC().Format("2 January 2006"))
+		assert.NotContains(t, res.Body.String(), lead.Email, "only the status is exposed")
+	})
+}
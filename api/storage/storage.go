@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Metadata keys shared by every backend
+const (
+	META_LEAD        = "lead"
+	META_EMAIL       = "email"
+	META_FIRST_NAME  = "firstName"
+	META_LAST_NAME   = "lastName"
+	META_MOBILE      = "mobile"
+	META_COUNTRY     = "country"
+	META_ENVIRONMENT = "environment"
+)
+
+var ErrNotFound = errors.New("file not found")
+
+type Object struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	Metadata    map[string]string
+	Body        io.Reader
+	// Folder optionally files the object under a subfolder, e.g. per team
+	Folder string
+}
+
+type Stored struct {
+	Id string
+	// Shareable link to the stored file, e.g. a Drive webViewLink
+	Link string
+}
+
+type Store interface {
+	Put(ctx context.Context, object Object) (*Stored, error)
+	Open(ctx context.Context, id string) (io.ReadCloser, error)
+	// Delete succeeds when the file is already gone
+	Delete(ctx context.Context, id string) error
+}
+
+// File is a stored file found by its metadata
+type File struct {
+	Id        string
+	Filename  string
+	Metadata  map[string]string
+	CreatedAt time.Time
+}
+
+// EmailFinder is implemented by backends that can search by META_EMAIL,
+// finding files the database may not know about
+type EmailFinder interface {
+	FindByEmail(ctx context.Context, email string) ([]File, error)
+}
+
+// Lister is implemented by backends that can find files a retention policy
+// applies to. Only files with META_LEAD are listed, anything else in the
+// backend wasn't uploaded by us
+type Lister interface {
+	ListCreatedBefore(ctx context.Context, environment string, before time.Time) ([]File, error)
+}
+
+// Archiver is implemented by backends that can move files out of the way
+// rather than delete them
+type Archiver interface {
+	Archive(ctx context.Context, id string) error
+}
+
+// QuotaReporter is implemented by backends with a storage limit
+type QuotaReporter interface {
+	Quota(ctx context.Context) (usage int64, limit int64, err error)
+}
+
+// Presigner is implemented by backends whose links come back to us, as
+// presigned links expire. Following one redirects to a short lived link
+type Presigner interface {
+	// Presign returns ErrNotFound when there's no such file
+	Presign(ctx context.Context, id string) (string, error)
+}
+
+var unsafeCharacters = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// objectKey groups a lead's files together, i.e. `[<folder>/]<lead>/<filename>`
+func objectKey(object Object) string {
+	return path.Join(folderKey(object.Folder), leadKey(object))
+}
+
+func leadKey(object Object) string {
+	filename := unsafeCharacters.ReplaceAllString(path.Base(object.Filename), "_")
+	filename = strings.Trim(filename, "._")
+	if filename == "" {
+		filename = "attachment"
+	}
+
+	lead := unsafeCharacters.ReplaceAllString(object.Metadata[META_LEAD], "_")
+	if lead == "" {
+		return filename
+	}
+
+	return path.Join(lead, filename)
+}
+
+func folderKey(folder string) string {
+	segments := []string{}
+	for _, segment := range strings.Split(folder, "/") {
+		segment = strings.Trim(unsafeCharacters.ReplaceAllString(segment, "_"), "._")
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	return path.Join(segments...)
+}
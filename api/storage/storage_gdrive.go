@@ -0,0 +1,293 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const FOLDER_MIME_TYPE = "application/vnd.google-apps.folder"
+
+type DriveStore struct {
+	service  *drive.Service
+	folderId string
+	// archiveFolderId is where Archive moves files, optional
+	archiveFolderId string
+
+	mu sync.Mutex
+	// subfolder ids by name, within folderId
+	subfolders map[string]string
+}
+
+func NewDriveStore(service *drive.Service, folderId string, archiveFolderId string) *DriveStore {
+	return &DriveStore{
+		service:         service,
+		folderId:        folderId,
+		archiveFolderId: archiveFolderId,
+		subfolders:      map[string]string{},
+	}
+}
+
+func (s *DriveStore) Put(ctx context.Context, object Object) (*Stored, error) {
+	parent := s.folderId
+	if object.Folder != "" {
+		folderId, err := s.subfolder(ctx, object.Folder)
+		if err != nil {
+			return nil, err
+		}
+
+		parent = folderId
+	}
+
+	res, err := s.service.Files.
+		Create(&drive.File{
+			Name:       fmt.Sprintf("%s - %s (%s)", object.Metadata[META_EMAIL], object.Filename, object.Metadata[META_ENVIRONMENT]),
+			Properties: object.Metadata,
+			Parents:    []string{parent},
+		}).
+		Media(object.Body).
+		Fields("id, webViewLink").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stored{
+		Id:   res.Id,
+		Link: res.WebViewLink,
+	}, nil
+}
+
+func (s *DriveStore) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	res, err := s.service.Files.
+		Get(id).
+		Context(ctx).
+		Download()
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Body, nil
+}
+
+func (s *DriveStore) Delete(ctx context.Context, id string) error {
+	err := s.service.Files.
+		Delete(id).
+		Context(ctx).
+		Do()
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		return nil
+	}
+
+	return err
+}
+
+// FindByEmail searches the email property files are created with, as
+// given and lower case since enquirers don't type it consistently
+func (s *DriveStore) FindByEmail(ctx context.Context, email string) ([]File, error) {
+	conditions := []string{}
+	for _, value := range slices.Compact([]string{email, strings.ToLower(email)}) {
+		conditions = append(conditions, fmt.Sprintf("properties has { key='%s' and value='%s' }", META_EMAIL, escapeQuery(value)))
+	}
+	query := fmt.Sprintf("(%s) and trashed = false", strings.Join(conditions, " or "))
+
+	files := []File{}
+	err := s.service.Files.
+		List().
+		Q(query).
+		Fields("nextPageToken, files(id, name, properties, createdTime)").
+		PageSize(100).
+		Pages(ctx, func(list *drive.FileList) error {
+			files = append(files, toFiles(list.Files)...)
+
+			return nil
+		})
+
+	return files, err
+}
+
+// ListCreatedBefore searches the environment property files are created
+// with, only within folderId and its route subfolders as the service
+// account may see other files with the same properties. Files already
+// archived are left out
+func (s *DriveStore) ListCreatedBefore(ctx context.Context, environment string, before time.Time) ([]File, error) {
+	folders, err := s.folders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make([]string, 0, len(folders))
+	for _, folder := range folders {
+		parents = append(parents, fmt.Sprintf("'%s' in parents", escapeQuery(folder)))
+	}
+
+	query := fmt.Sprintf("(%s) and properties has { key='%s' and value='%s' } and createdTime < '%s' and mimeType != '%s' and trashed = false",
+		strings.Join(parents, " or "), META_ENVIRONMENT, escapeQuery(environment), before.UTC().Format(time.RFC3339), FOLDER_MIME_TYPE)
+
+	files := []File{}
+	err = s.service.Files.
+		List().
+		Q(query).
+		Fields("nextPageToken, files(id, name, properties, createdTime)").
+		OrderBy("createdTime").
+		PageSize(100).
+		Pages(ctx, func(list *drive.FileList) error {
+			for _, file := range toFiles(list.Files) {
+				if file.Metadata[META_LEAD] != "" {
+					files = append(files, file)
+				}
+			}
+
+			return nil
+		})
+
+	return files, err
+}
+
+// Archive moves the file into the archive folder, out of any subfolder
+// it was routed to
+func (s *DriveStore) Archive(ctx context.Context, id string) error {
+	if s.archiveFolderId == "" {
+		return errors.New("no archive folder configured")
+	}
+
+	file, err := s.service.Files.
+		Get(id).
+		Fields("parents").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.service.Files.
+		Update(id, &drive.File{}).
+		AddParents(s.archiveFolderId).
+		RemoveParents(strings.Join(file.Parents, ",")).
+		Fields("id").
+		Context(ctx).
+		Do()
+
+	return err
+}
+
+func (s *DriveStore) Quota(ctx context.Context) (int64, int64, error) {
+	about, err := s.service.About.
+		Get().
+		Fields("storageQuota").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// the limit applies to Gmail, Photos and trash as well as Drive
+	return about.StorageQuota.Usage, about.StorageQuota.Limit, nil
+}
+
+// folders are folderId and the subfolders within it, other than the archive
+// folder. Subfolders are looked up each time, other replicas may have
+// created them
+func (s *DriveStore) folders(ctx context.Context) ([]string, error) {
+	query := fmt.Sprintf("mimeType = '%s' and '%s' in parents and trashed = false", FOLDER_MIME_TYPE, escapeQuery(s.folderId))
+
+	folders := []string{s.folderId}
+	err := s.service.Files.
+		List().
+		Q(query).
+		Fields("nextPageToken, files(id)").
+		PageSize(100).
+		Pages(ctx, func(list *drive.FileList) error {
+			for _, folder := range list.Files {
+				if folder.Id != s.archiveFolderId {
+					folders = append(folders, folder.Id)
+				}
+			}
+
+			return nil
+		})
+
+	return folders, err
+}
+
+// subfolder finds or creates a folder by name within folderId. The lock is
+// held throughout so concurrent uploads don't create duplicates
+func (s *DriveStore) subfolder(ctx context.Context, name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, ok := s.subfolders[name]; ok {
+		return id, nil
+	}
+
+	query := fmt.Sprintf("name = '%s' and mimeType = '%s' and '%s' in parents and trashed = false",
+		escapeQuery(name), FOLDER_MIME_TYPE, s.folderId)
+
+	list, err := s.service.Files.
+		List().
+		Q(query).
+		Fields("files(id)").
+		PageSize(1).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", err
+	}
+
+	if len(list.Files) > 0 {
+		s.subfolders[name] = list.Files[0].Id
+
+		return list.Files[0].Id, nil
+	}
+
+	folder, err := s.service.Files.
+		Create(&drive.File{
+			Name:     name,
+			MimeType: FOLDER_MIME_TYPE,
+			Parents:  []string{s.folderId},
+		}).
+		Fields("id").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", err
+	}
+
+	s.subfolders[name] = folder.Id
+
+	return folder.Id, nil
+}
+
+func toFiles(list []*drive.File) []File {
+	files := make([]File, 0, len(list))
+	for _, file := range list {
+		// unparsable times are left zero rather than failing the search
+		createdAt, _ := time.Parse(time.RFC3339, file.CreatedTime)
+
+		files = append(files, File{
+			Id:        file.Id,
+			Filename:  file.Name,
+			Metadata:  file.Properties,
+			CreatedAt: createdAt,
+		})
+	}
+
+	return files
+}
+
+// escapeQuery escapes a string value in a Drive search query
+func escapeQuery(value string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(value, `\`, `\\`), "'", `\'`)
+}
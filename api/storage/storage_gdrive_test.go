@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+func TestDriveStoreListCreatedBefore(t *testing.T) {
+	var query string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/drive/v3/files", r.URL.Path)
+
+		q := r.URL.Query().Get("q")
+		if strings.HasPrefix(q, "mimeType = '"+FOLDER_MIME_TYPE+"'") {
+			assert.Contains(t, q, "'uploads' in parents")
+			json.NewEncoder(w).Encode(drive.FileList{Files: []*drive.File{{Id: "sales"}, {Id: "archive"}}})
+
+			return
+		}
+
+		query = q
+		json.NewEncoder(w).Encode(drive.FileList{Files: []*drive.File{
+			{Id: "file-1", Name: "brief.pdf", Properties: map[string]string{META_LEAD: "lead-1", META_ENVIRONMENT: "development"}, CreatedTime: "2026-01-02T03:04:05Z"},
+			{Id: "file-2", Name: "notes.txt", Properties: map[string]string{META_ENVIRONMENT: "development"}},
+		}})
+	}))
+	defer server.Close()
+
+	service, err := drive.NewService(context.Background(), option.WithEndpoint(server.URL+"/drive/v3/"), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewDriveStore(service, "uploads", "archive")
+
+	files, err := store.ListCreatedBefore(context.Background(), "development", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	if assert.Len(t, files, 1, "only files with a lead") {
+		assert.Equal(t, "file-1", files[0].Id)
+	}
+
+	assert.True(t, strings.HasPrefix(query, "('uploads' in parents or 'sales' in parents) and "), "within the folder and its subfolders: %s", query)
+	assert.NotContains(t, query, "'archive' in parents")
+	assert.Contains(t, query, "properties has { key='environment' and value='development' }")
+}
@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const META_SUFFIX = ".meta.json"
+
+// LocalStore keeps attachments on disk for development and tests,
+// metadata is written next to each file
+type LocalStore struct {
+	root    string
+	baseUrl string
+}
+
+type localMetadata struct {
+	Filename    string            `json:"filename"`
+	ContentType string            `json:"contentType"`
+	Size        int64             `json:"size"`
+	CreatedTime time.Time         `json:"createdTime"`
+	Properties  map[string]string `json:"properties"`
+}
+
+// NewLocalStore stores files under root. Links are built from baseUrl
+// when set (e.g. a static file server), otherwise they are file:// URLs
+func NewLocalStore(root string, baseUrl string) (*LocalStore, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(root, 0750); err != nil {
+		return nil, err
+	}
+
+	return &LocalStore{
+		root:    root,
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+	}, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, object Object) (*Stored, error) {
+	id, file, err := s.create(objectKey(object))
+	if err != nil {
+		return nil, err
+	}
+	dest := s.path(id)
+
+	size, err := io.Copy(file, object.Body)
+	closeErr := file.Close()
+	if err = errors.Join(err, closeErr); err != nil {
+		os.Remove(dest)
+
+		return nil, err
+	}
+
+	meta, err := json.MarshalIndent(localMetadata{
+		Filename:    object.Filename,
+		ContentType: object.ContentType,
+		Size:        size,
+		CreatedTime: time.Now().UTC(),
+		Properties:  object.Metadata,
+	}, "", "  ")
+	if err != nil {
+		os.Remove(dest)
+
+		return nil, err
+	}
+
+	if err := os.WriteFile(dest+META_SUFFIX, meta, 0640); err != nil {
+		os.Remove(dest)
+
+		return nil, err
+	}
+
+	return &Stored{
+		Id:   id,
+		Link: s.link(id),
+	}, nil
+}
+
+func (s *LocalStore) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	return os.Open(s.path(id))
+}
+
+// FindByEmail reads every metadata file, fine for the volumes a local
+// store is used with
+func (s *LocalStore) FindByEmail(ctx context.Context, email string) ([]File, error) {
+	return s.find(func(file File) bool {
+		return strings.EqualFold(file.Metadata[META_EMAIL], email)
+	})
+}
+
+func (s *LocalStore) ListCreatedBefore(ctx context.Context, environment string, before time.Time) ([]File, error) {
+	return s.find(func(file File) bool {
+		return file.Metadata[META_ENVIRONMENT] == environment && file.Metadata[META_LEAD] != "" && file.CreatedAt.Before(before)
+	})
+}
+
+// find walks the metadata files for those matching
+func (s *LocalStore) find(match func(file File) bool) ([]File, error) {
+	files := []File{}
+	err := filepath.WalkDir(s.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(path, META_SUFFIX) {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var meta localMetadata
+		if err := json.Unmarshal(content, &meta); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		id, err := filepath.Rel(s.root, strings.TrimSuffix(path, META_SUFFIX))
+		if err != nil {
+			return err
+		}
+
+		file := File{
+			Id:        filepath.ToSlash(id),
+			Filename:  meta.Filename,
+			Metadata:  meta.Properties,
+			CreatedAt: meta.CreatedTime,
+		}
+		if match(file) {
+			files = append(files, file)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
+
+func (s *LocalStore) Delete(ctx context.Context, id string) error {
+	dest := s.path(id)
+
+	var errs []error
+	for _, file := range []string{dest, dest + META_SUFFIX} {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	// tidy up the lead's folder once it's empty
+	if dir := filepath.Dir(dest); dir != s.root {
+		os.Remove(dir)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *LocalStore) path(id string) string {
+	// ids are relative slash separated keys,
+	// Clean on a rooted path prevents escaping the root
+	return filepath.Join(s.root, filepath.FromSlash(filepath.Clean("/"+id)))
+}
+
+// create opens a new file for key, numbering it when the key is taken. The
+// exclusive create is what makes it unique, a lead's files are stored in
+// parallel and may share a name
+func (s *LocalStore) create(key string) (string, *os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(s.path(key)), 0750); err != nil {
+		return "", nil, err
+	}
+
+	ext := filepath.Ext(key)
+	base := strings.TrimSuffix(key, ext)
+
+	candidate := key
+	for i := 1; ; i++ {
+		file, err := os.OpenFile(s.path(candidate), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+		if err == nil {
+			return candidate, file, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return "", nil, err
+		}
+
+		candidate = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+}
+
+func (s *LocalStore) link(id string) string {
+	if s.baseUrl != "" {
+		return s.baseUrl + "/" + id
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(s.path(id))}).String()
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	root := t.TempDir()
+
+	store, err := NewLocalStore(root, "http://localhost:8080/attachments/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	object := func() Object {
+		return Object{
+			Filename:    "../brief v1.pdf",
+			ContentType: "application/pdf",
+			Metadata: map[string]string{
+				META_LEAD:  "6f1c0b0e-lead",
+				META_EMAIL: "test@example.com",
+			},
+			Body: strings.NewReader("%PDF"),
+		}
+	}
+
+	first, err := store.Put(context.Background(), object())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := store.Put(context.Background(), object())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "6f1c0b0e-lead/brief_v1.pdf", first.Id)
+	assert.Equal(t, "6f1c0b0e-lead/brief_v1-1.pdf", second.Id)
+	assert.Equal(t, "http://localhost:8080/attachments/6f1c0b0e-lead/brief_v1.pdf", first.Link)
+
+	contents, err := os.ReadFile(filepath.Join(root, "6f1c0b0e-lead", "brief_v1.pdf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "%PDF", string(contents))
+
+	var meta localMetadata
+	raw, err := os.ReadFile(filepath.Join(root, "6f1c0b0e-lead", "brief_v1.pdf"+META_SUFFIX))
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(raw, &meta))
+	assert.Equal(t, "test@example.com", meta.Properties[META_EMAIL])
+	assert.Equal(t, int64(4), meta.Size)
+
+	assert.NoError(t, store.Delete(context.Background(), first.Id))
+	assert.NoError(t, store.Delete(context.Background(), second.Id))
+	assert.NoError(t, store.Delete(context.Background(), second.Id), "deleting twice should be a no-op")
+
+	_, err = os.Stat(filepath.Join(root, "6f1c0b0e-lead"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLocalStoreConcurrentPutsWithTheSameName(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]string, 32)
+	errs := make([]error, len(ids))
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			stored, err := store.Put(context.Background(), Object{
+				Filename: "brief.pdf",
+				Metadata: map[string]string{META_LEAD: "6f1c0b0e-lead"},
+				Body:     strings.NewReader("%PDF"),
+			})
+			errs[i] = err
+			if err == nil {
+				ids[i] = stored.Id
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, make([]error, len(ids)), errs)
+	assert.Len(t, slices.Compact(slices.Sorted(slices.Values(ids))), len(ids), "each gets its own key")
+}
+
+func TestLocalStoreStaysInRoot(t *testing.T) {
+	root := t.TempDir()
+
+	store, err := NewLocalStore(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, strings.HasPrefix(store.path("../../etc/passwd"), root))
+}
+
+func TestLocalStoreFindByEmail(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, email := range []string{"Jane@Example.com", "joe@example.com"} {
+		_, err := store.Put(ctx, Object{
+			Filename: "brief.pdf",
+			Metadata: map[string]string{META_LEAD: "lead-" + email[:3], META_EMAIL: email},
+			Body:     strings.NewReader("%PDF " + email),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := store.FindByEmail(ctx, "jane@example.com")
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "lead-Jan/brief.pdf", files[0].Id)
+	assert.Equal(t, "brief.pdf", files[0].Filename)
+
+	reader, err := store.Open(ctx, files[0].Id)
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, "%PDF Jane@Example.com", string(content))
+
+	files, err = store.FindByEmail(ctx, "nobody@example.com")
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestLocalStoreListCreatedBefore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	objects := []map[string]string{
+		{META_LEAD: "lead-1", META_ENVIRONMENT: "development"},
+		{META_LEAD: "lead-2", META_ENVIRONMENT: "production"},
+		// not uploaded with an enquiry
+		{META_ENVIRONMENT: "development"},
+	}
+	for _, metadata := range objects {
+		if _, err := store.Put(ctx, Object{Filename: "brief.pdf", Metadata: metadata, Body: strings.NewReader("%PDF")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := store.ListCreatedBefore(ctx, "development", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "lead-1/brief.pdf", files[0].Id)
+	assert.False(t, files[0].CreatedAt.IsZero())
+
+	files, err = store.ListCreatedBefore(ctx, "development", time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
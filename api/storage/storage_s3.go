@@ -0,0 +1,304 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Presigned URLs can't be valid for longer than 7 days
+const MAX_LINK_EXPIRY = 7 * 24 * time.Hour
+
+// metadata keys files are found by, headers don't keep their case
+var s3MetadataKeys = []string{META_LEAD, META_EMAIL, META_FIRST_NAME, META_LAST_NAME, META_MOBILE, META_COUNTRY, META_ENVIRONMENT}
+
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	// Key prefix, e.g. to share a bucket between environments
+	Prefix string
+	// Base URL for publicly readable buckets
+	PublicUrl string
+	// LinkUrl is where links point otherwise, a route redirecting to a
+	// link presigned by Presign when it's followed
+	LinkUrl string
+	// LinkExpiry is how long presigned links are valid for
+	LinkExpiry time.Duration
+	// ArchivePrefix is where Archive moves objects, optional
+	ArchivePrefix string
+	// Limit is how many bytes may be stored under Prefix, unlimited when zero
+	Limit int64
+	// Transport is optional, e.g. for tests
+	Transport http.RoundTripper
+}
+
+// S3Store works with S3 compatible object stores, e.g. MinIO,
+// Cloudflare R2 or Google Cloud Storage with HMAC keys
+type S3Store struct {
+	client *minio.Client
+	config S3Config
+}
+
+func NewS3Store(config S3Config) (*S3Store, error) {
+	config.PublicUrl = strings.TrimSuffix(config.PublicUrl, "/")
+	config.LinkUrl = strings.TrimSuffix(config.LinkUrl, "/")
+	if config.PublicUrl == "" && config.LinkUrl == "" {
+		// links are stored, so they can't be presigned up front
+		return nil, errors.New("s3 links need a public url or a link url")
+	}
+
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+		Secure:       config.UseSSL,
+		Region:       config.Region,
+		BucketLookup: minio.BucketLookupAuto,
+		Transport:    config.Transport,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	config.LinkExpiry = min(config.LinkExpiry, MAX_LINK_EXPIRY)
+
+	return &S3Store{
+		client: client,
+		config: config,
+	}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, object Object) (*Stored, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	// random segment keeps files with the same name on the same lead apart
+	dir, filename := path.Split(objectKey(object))
+	key := path.Join(s.config.Prefix, dir, hex.EncodeToString(suffix)+"-"+filename)
+
+	metadata := map[string]string{}
+	for k, v := range object.Metadata {
+		// object metadata is sent as headers, which must be ASCII
+		metadata[k] = mime.QEncoding.Encode("utf-8", v)
+	}
+	metadata["filename"] = mime.QEncoding.Encode("utf-8", object.Filename)
+
+	_, err := s.client.PutObject(ctx, s.config.Bucket, key, object.Body, object.Size, minio.PutObjectOptions{
+		ContentType:  object.ContentType,
+		UserMetadata: metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stored{
+		Id:   key,
+		Link: s.link(key),
+	}, nil
+}
+
+func (s *S3Store) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, s.config.Bucket, id, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// GetObject is lazy, Stat surfaces a missing object now
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+
+		return nil, err
+	}
+
+	return object, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, id string) error {
+	return s.client.RemoveObject(ctx, s.config.Bucket, id, minio.RemoveObjectOptions{})
+}
+
+// Presign links to an object under Prefix for LinkExpiry
+func (s *S3Store) Presign(ctx context.Context, id string) (string, error) {
+	if !s.owns(id) {
+		return "", ErrNotFound
+	}
+
+	if _, err := s.client.StatObject(ctx, s.config.Bucket, id, minio.StatObjectOptions{}); err != nil {
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			return "", ErrNotFound
+		}
+
+		return "", err
+	}
+
+	presigned, err := s.client.PresignedGetObject(ctx, s.config.Bucket, id, s.config.LinkExpiry, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return presigned.String(), nil
+}
+
+// FindByEmail reads every object's metadata, as given and lower case since
+// enquirers don't type it consistently
+func (s *S3Store) FindByEmail(ctx context.Context, email string) ([]File, error) {
+	return s.find(ctx, func(object minio.ObjectInfo) bool { return true }, func(file File) bool {
+		return strings.EqualFold(file.Metadata[META_EMAIL], email)
+	})
+}
+
+// ListCreatedBefore only reads the metadata of objects old enough, leaving
+// out archived objects
+func (s *S3Store) ListCreatedBefore(ctx context.Context, environment string, before time.Time) ([]File, error) {
+	return s.find(ctx, func(object minio.ObjectInfo) bool {
+		return object.LastModified.Before(before)
+	}, func(file File) bool {
+		return file.Metadata[META_ENVIRONMENT] == environment && file.Metadata[META_LEAD] != ""
+	})
+}
+
+// Archive copies the object under ArchivePrefix and deletes the original
+func (s *S3Store) Archive(ctx context.Context, id string) error {
+	if s.config.ArchivePrefix == "" {
+		return errors.New("no archive prefix configured")
+	}
+
+	_, err := s.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: s.config.Bucket, Object: path.Join(s.config.ArchivePrefix, id)},
+		minio.CopySrcOptions{Bucket: s.config.Bucket, Object: id})
+	if err != nil {
+		return err
+	}
+
+	return s.Delete(ctx, id)
+}
+
+// Quota adds up the size of every object under Prefix, including archived
+// objects when ArchivePrefix is within it
+func (s *S3Store) Quota(ctx context.Context) (int64, int64, error) {
+	// stops the listing when it fails part way
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	usage := int64(0)
+	for object := range s.client.ListObjects(ctx, s.config.Bucket, minio.ListObjectsOptions{Prefix: s.listPrefix(), Recursive: true}) {
+		if object.Err != nil {
+			return 0, 0, object.Err
+		}
+
+		usage += object.Size
+	}
+
+	return usage, s.config.Limit, nil
+}
+
+// find lists objects under Prefix, reading the metadata of those listed
+// matches and returning the files it matches
+func (s *S3Store) find(ctx context.Context, listed func(object minio.ObjectInfo) bool, match func(file File) bool) ([]File, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	files := []File{}
+	options := minio.ListObjectsOptions{Prefix: s.listPrefix(), Recursive: true, WithMetadata: true}
+	for object := range s.client.ListObjects(ctx, s.config.Bucket, options) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+
+		if s.archived(object.Key) || !listed(object) {
+			continue
+		}
+
+		// only MinIO lists metadata, other stores have to be asked per object
+		if len(object.UserMetadata) == 0 {
+			stat, err := s.client.StatObject(ctx, s.config.Bucket, object.Key, minio.StatObjectOptions{})
+			if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			object.UserMetadata = stat.UserMetadata
+		}
+
+		file := toFile(object)
+		if match(file) {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+func (s *S3Store) listPrefix() string {
+	if s.config.Prefix == "" {
+		return ""
+	}
+
+	return path.Clean(s.config.Prefix) + "/"
+}
+
+func (s *S3Store) owns(id string) bool {
+	return strings.HasPrefix(id, s.listPrefix()) && !s.archived(id)
+}
+
+func (s *S3Store) archived(key string) bool {
+	return s.config.ArchivePrefix != "" && strings.HasPrefix(key, path.Clean(s.config.ArchivePrefix)+"/")
+}
+
+func (s *S3Store) link(key string) string {
+	if s.config.PublicUrl != "" {
+		return s.config.PublicUrl + "/" + key
+	}
+
+	return s.config.LinkUrl + "/" + key
+}
+
+// toFile decodes the metadata Put encoded, keys are listed with or without
+// the X-Amz-Meta- prefix depending on the store
+func toFile(object minio.ObjectInfo) File {
+	decoder := new(mime.WordDecoder)
+
+	file := File{
+		Id:        object.Key,
+		Filename:  path.Base(object.Key),
+		Metadata:  map[string]string{},
+		CreatedAt: object.LastModified,
+	}
+
+	for key, value := range object.UserMetadata {
+		key = strings.TrimPrefix(strings.ToLower(key), "x-amz-meta-")
+		if decoded, err := decoder.DecodeHeader(value); err == nil {
+			value = decoded
+		}
+
+		if key == "filename" {
+			file.Filename = value
+
+			continue
+		}
+
+		for _, known := range s3MetadataKeys {
+			if strings.EqualFold(key, known) {
+				file.Metadata[known] = value
+			}
+		}
+	}
+
+	return file
+}
@@ -0,0 +1,313 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const TEST_BUCKET = "attachments"
+
+type fakeObject struct {
+	body     []byte
+	header   http.Header
+	modified time.Time
+}
+
+// fakeS3 is just enough of the S3 API for S3Store, path style
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+	server  *httptest.Server
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	f := &fakeS3{objects: map[string]fakeObject{}}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+func (f *fakeS3) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/"+TEST_BUCKET), "/")
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		f.list(w, r.URL.Query().Get("prefix"))
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		source, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		object, ok := f.objects[strings.TrimPrefix(strings.TrimPrefix(source, "/"), TEST_BUCKET+"/")]
+		if !ok {
+			notFound(w)
+
+			return
+		}
+
+		object.modified = time.Now()
+		f.objects[key] = object
+		fmt.Fprintf(w, `<CopyObjectResult><ETag>"etag"</ETag><LastModified>%s</LastModified></CopyObjectResult>`, object.modified.UTC().Format(time.RFC3339))
+	case r.Method == http.MethodPut:
+		body, err := readBody(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		header := http.Header{"Content-Type": {r.Header.Get("Content-Type")}}
+		for name, values := range r.Header {
+			if strings.HasPrefix(name, "X-Amz-Meta-") {
+				header[name] = values
+			}
+		}
+
+		f.objects[key] = fakeObject{body: body, header: header, modified: time.Now()}
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		object, ok := f.objects[key]
+		if !ok {
+			notFound(w)
+
+			return
+		}
+
+		for name, values := range object.header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", object.modified.UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.body)))
+		if r.Method == http.MethodGet {
+			w.Write(object.body)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "not implemented", http.StatusNotImplemented)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	type contents struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int
+	}
+
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string
+		Prefix      string
+		KeyCount    int
+		MaxKeys     int
+		IsTruncated bool
+		Contents    []contents
+	}{Name: TEST_BUCKET, Prefix: prefix, MaxKeys: 1000}
+
+	keys := []string{}
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		object := f.objects[key]
+		result.Contents = append(result.Contents, contents{
+			Key:          key,
+			LastModified: object.modified.UTC().Format("2006-01-02T15:04:05.000Z"),
+			ETag:         `"etag"`,
+			Size:         len(object.body),
+		})
+	}
+	result.KeyCount = len(result.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+// readBody decodes aws-chunked bodies, which the client streams over HTTP
+func readBody(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	body := bytes.Buffer{}
+	reader := bufio.NewReader(r.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		size, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		n, err := strconv.ParseInt(size, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return body.Bytes(), nil
+		}
+
+		if _, err := io.CopyN(&body, reader, n); err != nil {
+			return nil, err
+		}
+		reader.ReadString('\n')
+	}
+}
+
+func notFound(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprint(w, `<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+}
+
+func newTestS3Store(t *testing.T, f *fakeS3, config S3Config) *S3Store {
+	t.Helper()
+
+	config.Endpoint = strings.TrimPrefix(f.server.URL, "http://")
+	config.Region = "us-east-1"
+	config.Bucket = TEST_BUCKET
+	config.AccessKey = "access"
+	config.SecretKey = "secret"
+	if config.PublicUrl == "" {
+		config.LinkUrl = "https://landing.example.com/attachments/"
+	}
+	config.LinkExpiry = time.Hour
+
+	store, err := NewS3Store(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+func putTestObject(t *testing.T, store *S3Store, metadata map[string]string) *Stored {
+	t.Helper()
+
+	stored, err := store.Put(context.Background(), Object{
+		Filename:    "brief ü.pdf",
+		ContentType: "application/pdf",
+		Size:        4,
+		Metadata:    metadata,
+		Body:        strings.NewReader("%PDF"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return stored
+}
+
+func TestNewS3StoreRequiresLinks(t *testing.T) {
+	_, err := NewS3Store(S3Config{Endpoint: "localhost:9000", Bucket: TEST_BUCKET})
+	assert.Error(t, err, "presigned links would be stored and expire")
+}
+
+func TestS3Store(t *testing.T) {
+	f := newFakeS3(t)
+	store := newTestS3Store(t, f, S3Config{Prefix: "production"})
+	ctx := context.Background()
+
+	stored := putTestObject(t, store, map[string]string{META_LEAD: "lead-1", META_EMAIL: "Jane@Example.com", META_FIRST_NAME: "Zoë"})
+	assert.Regexp(t, `^production/lead-1/[0-9a-f]{8}-brief_.*\.pdf$`, stored.Id)
+	assert.Equal(t, "https://landing.example.com/attachments/"+stored.Id, stored.Link, "a stable link, not a presigned one")
+
+	reader, err := store.Open(ctx, stored.Id)
+	if assert.NoError(t, err) {
+		content, _ := io.ReadAll(reader)
+		reader.Close()
+		assert.Equal(t, "%PDF", string(content))
+	}
+
+	link, err := store.Presign(ctx, stored.Id)
+	assert.NoError(t, err)
+	assert.Contains(t, link, "X-Amz-Expires=3600")
+
+	_, err = store.Presign(ctx, "production/lead-1/missing.pdf")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = store.Presign(ctx, "development/lead-1/brief.pdf")
+	assert.ErrorIs(t, err, ErrNotFound, "outside the prefix")
+
+	files, err := store.FindByEmail(ctx, "jane@example.com")
+	assert.NoError(t, err)
+	if assert.Len(t, files, 1) {
+		assert.Equal(t, stored.Id, files[0].Id)
+		assert.Equal(t, "brief ü.pdf", files[0].Filename)
+		assert.Equal(t, "Zoë", files[0].Metadata[META_FIRST_NAME])
+		assert.Equal(t, "lead-1", files[0].Metadata[META_LEAD])
+	}
+
+	assert.NoError(t, store.Delete(ctx, stored.Id))
+	_, err = store.Open(ctx, stored.Id)
+	assert.Error(t, err)
+}
+
+func TestS3StorePublicUrl(t *testing.T) {
+	store := newTestS3Store(t, newFakeS3(t), S3Config{PublicUrl: "https://files.example.com/"})
+
+	stored := putTestObject(t, store, map[string]string{META_LEAD: "lead-1"})
+	assert.Equal(t, "https://files.example.com/"+stored.Id, stored.Link)
+}
+
+func TestS3StoreListArchiveAndQuota(t *testing.T) {
+	f := newFakeS3(t)
+	store := newTestS3Store(t, f, S3Config{Prefix: "landing", ArchivePrefix: "archive", Limit: 1000})
+	ctx := context.Background()
+
+	development := putTestObject(t, store, map[string]string{META_LEAD: "lead-1", META_ENVIRONMENT: "development"})
+	putTestObject(t, store, map[string]string{META_LEAD: "lead-2", META_ENVIRONMENT: "production"})
+	// not uploaded with an enquiry
+	putTestObject(t, store, map[string]string{META_ENVIRONMENT: "development"})
+
+	files, err := store.ListCreatedBefore(ctx, "development", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	if assert.Len(t, files, 1) {
+		assert.Equal(t, development.Id, files[0].Id)
+		assert.False(t, files[0].CreatedAt.IsZero())
+	}
+
+	files, err = store.ListCreatedBefore(ctx, "development", time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	usage, limit, err := store.Quota(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), usage)
+	assert.Equal(t, int64(1000), limit)
+
+	assert.NoError(t, store.Archive(ctx, development.Id))
+	_, err = store.Open(ctx, development.Id)
+	assert.Error(t, err, "moved")
+	_, ok := f.objects["archive/"+development.Id]
+	assert.True(t, ok)
+
+	files, err = store.ListCreatedBefore(ctx, "development", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, files, "archived objects are left out")
+
+	unarchived := newTestS3Store(t, f, S3Config{Prefix: "landing"})
+	assert.Error(t, unarchived.Archive(ctx, "landing/lead-2/brief.pdf"))
+}
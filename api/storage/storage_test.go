@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObjectKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		object   Object
+		expected string
+	}{
+		{"lead", Object{Filename: "brief.pdf", Metadata: map[string]string{META_LEAD: "lead"}}, "lead/brief.pdf"},
+		{"no lead", Object{Filename: "brief.pdf"}, "brief.pdf"},
+		{"unsafe filename", Object{Filename: "../my brief.pdf"}, "my_brief.pdf"},
+		{"empty filename", Object{Filename: ".."}, "attachment"},
+		{"folder", Object{Filename: "cv.pdf", Folder: "Careers Team", Metadata: map[string]string{META_LEAD: "lead"}}, "Careers_Team/lead/cv.pdf"},
+		{"nested folder", Object{Filename: "cv.pdf", Folder: "teams/careers/"}, "teams/careers/cv.pdf"},
+		{"folder can't escape", Object{Filename: "cv.pdf", Folder: "../../etc"}, "etc/cv.pdf"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, objectKey(test.object))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// New creates a server TLS config serving the reloader's current certificate
+func New(certificates *Reloader) *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certificates.GetCertificate,
+	}
+}
+
+// RequireOriginPull only accepts connections presenting a client certificate
+// signed by the given CA bundle, i.e. Cloudflare's origin pull CA
+// see: https://developers.cloudflare.com/ssl/origin-configuration/authenticated-origin-pull/
+func RequireOriginPull(config *tls.Config, caFile string) error {
+	pool, err := loadCertPool(caFile)
+	if err != nil {
+		return err
+	}
+
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	config.ClientCAs = pool
+
+	return nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	bundle, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+
+	return pool, nil
+}
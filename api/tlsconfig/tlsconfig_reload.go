@@ -0,0 +1,115 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reloader serves the most recently loaded key pair, picking up renewed
+// certificates (e.g. from certbot) without restarting the server
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	version fileVersion
+	cert    atomic.Pointer[tls.Certificate]
+}
+
+type fileVersion struct {
+	certModTime time.Time
+	certSize    int64
+	keyModTime  time.Time
+	keySize     int64
+}
+
+func NewReloader(certFile string, keyFile string) (*Reloader, error) {
+	reloader := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if _, err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// Reload loads the key pair again if either file has changed since the last
+// load. On failure the previous certificate continues to be served
+func (r *Reloader) Reload() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	version, err := r.stat()
+	if err != nil {
+		return false, err
+	}
+
+	if r.cert.Load() != nil && version == r.version {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	r.cert.Store(&cert)
+	r.version = version
+
+	return true, nil
+}
+
+// Watch polls the key pair for changes until the context is done
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if err != nil {
+				slog.ErrorContext(ctx, "error", "tls reload", err.Error())
+
+				continue
+			}
+
+			if reloaded {
+				slog.InfoContext(ctx, "reloaded", "tls certificate", r.certFile)
+			}
+		}
+	}
+}
+
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+func (r *Reloader) stat() (fileVersion, error) {
+	cert, err := os.Stat(r.certFile)
+	if err != nil {
+		return fileVersion{}, err
+	}
+
+	key, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fileVersion{}, err
+	}
+
+	return fileVersion{
+		certModTime: cert.ModTime(),
+		certSize:    cert.Size(),
+		keyModTime:  key.ModTime(),
+		keySize:     key.Size(),
+	}, nil
+}
@@ -0,0 +1,70 @@
+package tlsconfig
+
+import (
+	"crypto/x509"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloaderPicksUpRenewedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueCA(t, "origin")
+
+	original := issueLeaf(t, ca, x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := writeKeyPair(t, dir, "server", original)
+
+	reloader, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := reloader.Reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded, "unchanged files should not be reloaded")
+
+	renewed := issueLeaf(t, ca, x509.ExtKeyUsageServerAuth)
+	writeKeyPair(t, dir, "server", renewed)
+
+	// filesystems with coarse timestamps could otherwise report the same version
+	later := time.Now().Add(time.Minute)
+	for _, path := range []string{certFile, keyFile} {
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reloaded, err = reloader.Reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+
+	cert, err := reloader.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, renewed.cert.Raw, cert.Certificate[0])
+}
+
+func TestReloaderKeepsCertificateOnInvalidRenewal(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueCA(t, "origin")
+
+	original := issueLeaf(t, ca, x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := writeKeyPair(t, dir, "server", original)
+
+	reloader, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(certFile, []byte("partially written"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = reloader.Reload()
+	assert.Error(t, err)
+
+	cert, err := reloader.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, original.cert.Raw, cert.Certificate[0])
+}
@@ -0,0 +1,198 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type issued struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pair tls.Certificate
+}
+
+func issue(t *testing.T, template *x509.Certificate, parent *issued) *issued {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &issued{
+		cert: cert,
+		key:  key,
+		pair: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert},
+	}
+}
+
+func issueCA(t *testing.T, name string) *issued {
+	return issue(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+}
+
+func issueLeaf(t *testing.T, ca *issued, usage x509.ExtKeyUsage) *issued {
+	return issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{usage},
+	}, ca)
+}
+
+func writePem(t *testing.T, dir string, name string, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func writeKeyPair(t *testing.T, dir string, prefix string, leaf *issued) (string, string) {
+	t.Helper()
+
+	keyDer, err := x509.MarshalECPrivateKey(leaf.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return writePem(t, dir, prefix+".crt", "CERTIFICATE", leaf.cert.Raw),
+		writePem(t, dir, prefix+".key", "EC PRIVATE KEY", keyDer)
+}
+
+func TestRequireOriginPull(t *testing.T) {
+	dir := t.TempDir()
+
+	serverCA := issueCA(t, "origin")
+	originPullCA := issueCA(t, "origin pull")
+	rogueCA := issueCA(t, "rogue")
+
+	certFile, keyFile := writeKeyPair(t, dir, "server", issueLeaf(t, serverCA, x509.ExtKeyUsageServerAuth))
+	caFile := writePem(t, dir, "origin-pull-ca.pem", "CERTIFICATE", originPullCA.cert.Raw)
+
+	certificates, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := New(certificates)
+	if err := RequireOriginPull(config, caFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// httptest.Server installs its own certificate, which would bypass GetCertificate
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}),
+		TLSConfig: config,
+		ErrorLog:  log.New(io.Discard, "", 0),
+	}
+	go server.ServeTLS(listener, "", "")
+	defer server.Close()
+
+	url := fmt.Sprintf("https://%s", listener.Addr().String())
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+
+	tests := []struct {
+		name    string
+		client  []tls.Certificate
+		wantErr bool
+	}{
+		{name: "origin pull client", client: []tls.Certificate{issueLeaf(t, originPullCA, x509.ExtKeyUsageClientAuth).pair}},
+		{name: "no client certificate", wantErr: true},
+		{name: "untrusted client certificate", client: []tls.Certificate{issueLeaf(t, rogueCA, x509.ExtKeyUsageClientAuth).pair}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{
+						RootCAs:      roots,
+						Certificates: tt.client,
+					},
+				},
+			}
+
+			res, err := client.Get(url)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			if assert.NoError(t, err) {
+				defer res.Body.Close()
+				assert.Equal(t, http.StatusNoContent, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestRequireOriginPullInvalidBundle(t *testing.T) {
+	dir := t.TempDir()
+
+	caFile := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Error(t, RequireOriginPull(&tls.Config{}, caFile))
+	assert.Error(t, RequireOriginPull(&tls.Config{}, filepath.Join(dir, "missing.pem")))
+}
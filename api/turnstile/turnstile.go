@@ -0,0 +1,82 @@
+package turnstile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Form field populated by the Turnstile widget
+const FORM_FIELD = "cf-turnstile-response"
+
+const VERIFY_URL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+
+var ErrMissingToken = errors.New("missing challenge token")
+var ErrChallengeFailed = errors.New("challenge failed")
+
+var client = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+type verifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+type Verifier struct {
+	secret    string
+	verifyUrl string
+}
+
+// New verifies tokens with the widget's secret at verifyUrl, i.e. VERIFY_URL
+// outside tests
+func New(secret string, verifyUrl string) *Verifier {
+	return &Verifier{
+		secret:    secret,
+		verifyUrl: verifyUrl,
+	}
+}
+
+// Verify validates a Turnstile token server side
+// see: https://developers.cloudflare.com/turnstile/get-started/server-side-validation/
+func (v *Verifier) Verify(ctx context.Context, token string, remoteIp string) error {
+	if token == "" {
+		return ErrMissingToken
+	}
+
+	form := url.Values{
+		"secret":   {v.secret},
+		"response": {token},
+	}
+	if remoteIp != "" {
+		form.Set("remoteip", remoteIp)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var body verifyResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return err
+	}
+
+	if !body.Success {
+		return fmt.Errorf("%w: %s", ErrChallengeFailed, strings.Join(body.ErrorCodes, ", "))
+	}
+
+	return nil
+}
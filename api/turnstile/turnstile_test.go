@@ -0,0 +1,65 @@
+package turnstile
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	var received map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("response") == "slow" {
+			time.Sleep(200 * time.Millisecond)
+
+			return
+		}
+
+		received = map[string]string{
+			"secret":   r.PostForm.Get("secret"),
+			"response": r.PostForm.Get("response"),
+			"remoteip": r.PostForm.Get("remoteip"),
+		}
+
+		if r.PostForm.Get("response") == "valid" {
+			json.NewEncoder(w).Encode(verifyResponse{Success: true})
+		} else {
+			json.NewEncoder(w).Encode(verifyResponse{ErrorCodes: []string{"invalid-input-response"}})
+		}
+	}))
+	defer server.Close()
+
+	verifier := New("secret", server.URL)
+
+	t.Run("success", func(t *testing.T) {
+		assert.NoError(t, verifier.Verify(context.Background(), "valid", "203.0.113.7"))
+		assert.Equal(t, map[string]string{"secret": "secret", "response": "valid", "remoteip": "203.0.113.7"}, received)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		err := verifier.Verify(context.Background(), "forged", "")
+		assert.ErrorIs(t, err, ErrChallengeFailed)
+		assert.ErrorContains(t, err, "invalid-input-response")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := verifier.Verify(ctx, "slow", "")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("empty token", func(t *testing.T) {
+		received = nil
+
+		assert.ErrorIs(t, verifier.Verify(context.Background(), "", ""), ErrMissingToken)
+		assert.Nil(t, received, "not sent to Cloudflare")
+	})
+}
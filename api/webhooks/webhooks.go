@@ -0,0 +1,82 @@
+package webhooks
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+const EVENT_ENQUIRY_CREATED = "enquiry.created"
+
+// Config is read from YAML, e.g.
+//
+//	subscriptions:
+//	  - name: crm
+//	    url: https://crm.example.com/hooks/skulpture
+//	    secret: whsec_...
+//	    events: [enquiry.created]
+//
+// Subscriptions without events receive every event
+type Config struct {
+	Subscriptions []Subscription `yaml:"subscriptions"`
+}
+
+type Subscription struct {
+	Name   string   `yaml:"name"`
+	Url    string   `yaml:"url"`
+	Secret string   `yaml:"secret"`
+	Events []string `yaml:"events"`
+}
+
+// Event is the JSON body of every delivery
+type Event struct {
+	Id        string `json:"id"`
+	Type      string `json:"type"`
+	CreatedAt string `json:"createdAt"`
+	Data      any    `json:"data"`
+}
+
+func (s Subscription) wants(eventType string) bool {
+	return len(s.Events) == 0 || slices.Contains(s.Events, eventType)
+}
+
+func Parse(data []byte) ([]Subscription, error) {
+	config := Config{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid webhooks: %w", err)
+	}
+
+	names := map[string]bool{}
+	for i, subscription := range config.Subscriptions {
+		if subscription.Name == "" {
+			return nil, fmt.Errorf("invalid webhooks: subscription %d has no name", i+1)
+		}
+		if names[subscription.Name] {
+			return nil, fmt.Errorf("invalid webhooks: duplicate subscription %q", subscription.Name)
+		}
+		names[subscription.Name] = true
+
+		parsed, err := url.Parse(subscription.Url)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid webhooks: subscription %q has an invalid url", subscription.Name)
+		}
+
+		if subscription.Secret == "" {
+			return nil, fmt.Errorf("invalid webhooks: subscription %q has no secret", subscription.Name)
+		}
+	}
+
+	return config.Subscriptions, nil
+}
+
+func Load(path string) ([]Subscription, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
@@ -0,0 +1,397 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	enums "skulpture/landing/enums"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const MAX_ATTEMPTS = 8
+const BACKOFF = 30 * time.Second
+const MAX_BACKOFF = time.Hour
+
+// LEASE is how long a delivery is held by whoever is attempting it,
+// longer than the request timeout so it isn't attempted twice
+const LEASE = time.Minute
+
+var ErrNotFound = errors.New("delivery not found")
+
+// Delivery is one event sent to one subscription, kept as a log
+type Delivery struct {
+	Id             string
+	EventId        string
+	EventType      string
+	Subscription   string
+	Url            string
+	Payload        []byte
+	Status         enums.DeliveryStatus
+	Attempts       int
+	LastStatusCode int
+	LastError      string
+	NextAttemptAt  time.Time
+	DeliveredAt    sql.NullTime
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Dispatcher records deliveries in the database before sending them, so
+// retries survive restarts and any delivery can be replayed
+type Dispatcher struct {
+	db            *sql.DB
+	subscriptions map[string]Subscription
+	ordered       []Subscription
+	client        *http.Client
+	maxAttempts   int
+	backoff       time.Duration
+	now           func() time.Time
+	wake          chan struct{}
+}
+
+func NewDispatcher(db *sql.DB, subscriptions []Subscription, maxAttempts int) *Dispatcher {
+	byName := map[string]Subscription{}
+	for _, subscription := range subscriptions {
+		byName[subscription.Name] = subscription
+	}
+
+	return &Dispatcher{
+		db:            db,
+		subscriptions: byName,
+		ordered:       subscriptions,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		maxAttempts: maxAttempts,
+		backoff:     BACKOFF,
+		now:         time.Now,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+func (d *Dispatcher) Subscriptions() []Subscription {
+	return d.ordered
+}
+
+// Publish queues an event for every subscription that wants it
+func (d *Dispatcher) Publish(ctx context.Context, eventType string, data any) (string, error) {
+	now := d.now().UTC()
+
+	event := Event{
+		Id:        uuid.NewString(),
+		Type:      eventType,
+		CreatedAt: now.Format(time.RFC3339),
+		Data:      data,
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	queued := 0
+	for _, subscription := range d.ordered {
+		if !subscription.wants(eventType) {
+			continue
+		}
+
+		_, err := tx.ExecContext(ctx, `INSERT INTO webhook_deliveries (
+				id, event_id, event_type, subscription, url, payload, status, next_attempt_at, created_at, updated_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			uuid.NewString(), event.Id, eventType, subscription.Name, subscription.Url, payload, enums.DeliveryPending, now, now, now,
+		)
+		if err != nil {
+			return "", err
+		}
+
+		queued++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	if queued > 0 {
+		d.notify()
+	}
+
+	return event.Id, nil
+}
+
+// Run delivers due webhooks every interval, or as soon as one is published
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "error", "webhooks", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DeliverDue attempts every pending delivery that is due, returning how
+// many were attempted
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT id FROM webhook_deliveries
+		WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT 100`,
+		enums.DeliveryPending, d.now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+
+			return 0, err
+		}
+
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	attempted := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+
+		claimed, err := d.claim(ctx, id)
+		if err != nil {
+			return attempted, err
+		}
+		if !claimed {
+			continue
+		}
+
+		delivery, err := d.Get(ctx, id)
+		if err != nil {
+			return attempted, err
+		}
+
+		d.attempt(ctx, delivery)
+		attempted++
+	}
+
+	return attempted, nil
+}
+
+// claim leases a due delivery so another replica doesn't attempt it too
+func (d *Dispatcher) claim(ctx context.Context, id string) (bool, error) {
+	now := d.now().UTC()
+
+	res, err := d.db.ExecContext(ctx, `UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id = ? AND status = ? AND next_attempt_at <= ?`,
+		now.Add(LEASE), id, enums.DeliveryPending, now)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected == 1, err
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *Delivery) {
+	statusCode, err := d.send(ctx, delivery)
+
+	// recorded even when shutting down, the lease expires and it's retried
+	recordCtx := context.WithoutCancel(ctx)
+	now := d.now().UTC()
+	attempts := delivery.Attempts + 1
+
+	if err == nil {
+		_, err = d.db.ExecContext(recordCtx, `UPDATE webhook_deliveries SET
+				status = ?, attempts = ?, last_status_code = ?, last_error = '', delivered_at = ?, updated_at = ?
+			WHERE id = ?`,
+			enums.DeliveryDelivered, attempts, statusCode, now, now, delivery.Id)
+		if err != nil {
+			slog.ErrorContext(ctx, "error", "webhook delivery", err.Error(), "delivery", delivery.Id)
+		}
+
+		return
+	}
+
+	status := enums.DeliveryPending
+	if attempts >= d.maxAttempts {
+		status = enums.DeliveryFailed
+	}
+
+	slog.WarnContext(ctx, "retry", "webhook delivery", err.Error(), "delivery", delivery.Id, "subscription", delivery.Subscription, "attempts", attempts, "status", status)
+
+	_, err = d.db.ExecContext(recordCtx, `UPDATE webhook_deliveries SET
+			status = ?, attempts = ?, last_status_code = ?, last_error = ?, next_attempt_at = ?, updated_at = ?
+		WHERE id = ?`,
+		status, attempts, statusCode, err.Error(), now.Add(d.delay(attempts)), now, delivery.Id)
+	if err != nil {
+		slog.ErrorContext(ctx, "error", "webhook delivery", err.Error(), "delivery", delivery.Id)
+	}
+}
+
+// delay doubles with each attempt, up to MAX_BACKOFF
+func (d *Dispatcher) delay(attempts int) time.Duration {
+	delay := d.backoff
+	for i := 1; i < attempts && delay < MAX_BACKOFF; i++ {
+		delay *= 2
+	}
+
+	return min(delay, MAX_BACKOFF)
+}
+
+// send signs with the subscription's current secret, so rotated secrets
+// apply to retries and replays
+func (d *Dispatcher) send(ctx context.Context, delivery *Delivery) (int, error) {
+	subscription, ok := d.subscriptions[delivery.Subscription]
+	if !ok {
+		return 0, fmt.Errorf("subscription %q is no longer configured", delivery.Subscription)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HEADER_EVENT, delivery.EventType)
+	req.Header.Set(HEADER_DELIVERY, delivery.Id)
+	req.Header.Set(HEADER_SIGNATURE, Sign(subscription.Secret, d.now(), delivery.Payload))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	if res.StatusCode >= 300 {
+		// collapsed so the log stays one line per delivery
+		return res.StatusCode, fmt.Errorf("status %d: %s", res.StatusCode, strings.Join(strings.Fields(string(body)), " "))
+	}
+
+	return res.StatusCode, nil
+}
+
+// Replay queues a delivery to be sent again, whatever its status
+func (d *Dispatcher) Replay(ctx context.Context, id string) error {
+	now := d.now().UTC()
+
+	res, err := d.db.ExecContext(ctx, `UPDATE webhook_deliveries SET
+			status = ?, attempts = 0, next_attempt_at = ?, updated_at = ?
+		WHERE id = ?`,
+		enums.DeliveryPending, now, now, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	d.notify()
+
+	return nil
+}
+
+// Forget deletes deliveries whose payload mentions the value, e.g. a lead
+// id when its data is erased
+func (d *Dispatcher) Forget(ctx context.Context, value string) (int64, error) {
+	res, err := d.db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE instr(CAST(payload AS TEXT), ?) > 0`, value)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (d *Dispatcher) Get(ctx context.Context, id string) (*Delivery, error) {
+	delivery := &Delivery{}
+
+	err := d.db.QueryRowContext(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id).
+		Scan(delivery.fields()...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// List returns the most recent deliveries, optionally only those with status
+func (d *Dispatcher) List(ctx context.Context, status enums.DeliveryStatus, limit int) ([]Delivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries`
+	args := []any{}
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at DESC, id LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var delivery Delivery
+		if err := rows.Scan(delivery.fields()...); err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+const deliveryColumns = `id, event_id, event_type, subscription, url, payload, status, attempts,
+	last_status_code, last_error, next_attempt_at, delivered_at, created_at, updated_at`
+
+func (delivery *Delivery) fields() []any {
+	return []any{
+		&delivery.Id, &delivery.EventId, &delivery.EventType, &delivery.Subscription, &delivery.Url, &delivery.Payload, &delivery.Status, &delivery.Attempts,
+		&delivery.LastStatusCode, &delivery.LastError, &delivery.NextAttemptAt, &delivery.DeliveredAt, &delivery.CreatedAt, &delivery.UpdatedAt,
+	}
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,164 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"skulpture/landing/database/databasetest"
+	enums "skulpture/landing/enums"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body, _ := io.ReadAll(req.Body)
+	r.bodies = append(r.bodies, body)
+	r.headers = append(r.headers, req.Header.Clone())
+
+	if len(r.statuses) > 0 {
+		w.WriteHeader(r.statuses[0])
+		r.statuses = r.statuses[1:]
+	}
+}
+
+func newTestDispatcher(t *testing.T, handler http.Handler, maxAttempts int) (*Dispatcher, *time.Time) {
+	t.Helper()
+
+	db := databasetest.Open(t)
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	dispatcher := NewDispatcher(db, []Subscription{
+		{Name: "crm", Url: server.URL, Secret: "s3cret", Events: []string{EVENT_ENQUIRY_CREATED}},
+		{Name: "other", Url: server.URL, Secret: "other", Events: []string{"enquiry.deleted"}},
+	}, maxAttempts)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	dispatcher.now = func() time.Time { return now }
+
+	return dispatcher, &now
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	ctx := context.Background()
+	receiver := &receiver{}
+	dispatcher, now := newTestDispatcher(t, receiver, MAX_ATTEMPTS)
+
+	eventId, err := dispatcher.Publish(ctx, EVENT_ENQUIRY_CREATED, map[string]string{"id": "6f1c0b0e-lead"})
+	assert.NoError(t, err)
+
+	attempted, err := dispatcher.DeliverDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempted, "only subscriptions wanting the event")
+
+	assert.Len(t, receiver.bodies, 1)
+	assert.NoError(t, Verify("s3cret", receiver.headers[0].Get(HEADER_SIGNATURE), receiver.bodies[0], *now))
+	assert.Equal(t, EVENT_ENQUIRY_CREATED, receiver.headers[0].Get(HEADER_EVENT))
+
+	var event Event
+	assert.NoError(t, json.Unmarshal(receiver.bodies[0], &event))
+	assert.Equal(t, eventId, event.Id)
+	assert.Equal(t, map[string]any{"id": "6f1c0b0e-lead"}, event.Data)
+
+	deliveries, err := dispatcher.List(ctx, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, receiver.headers[0].Get(HEADER_DELIVERY), deliveries[0].Id)
+	assert.Equal(t, enums.DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.True(t, deliveries[0].DeliveredAt.Valid)
+
+	attempted, err = dispatcher.DeliverDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, attempted, "delivered once")
+}
+
+func TestDispatcherRetriesAndReplays(t *testing.T) {
+	ctx := context.Background()
+	receiver := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusGone}}
+	dispatcher, now := newTestDispatcher(t, receiver, 3)
+
+	_, err := dispatcher.Publish(ctx, EVENT_ENQUIRY_CREATED, map[string]string{"id": "6f1c0b0e-lead"})
+	assert.NoError(t, err)
+
+	dispatcher.DeliverDue(ctx)
+
+	deliveries, _ := dispatcher.List(ctx, enums.DeliveryPending, 10)
+	assert.Len(t, deliveries, 1)
+	delivery := deliveries[0]
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, delivery.LastStatusCode)
+	assert.WithinDuration(t, now.Add(BACKOFF), delivery.NextAttemptAt, 0)
+
+	attempted, _ := dispatcher.DeliverDue(ctx)
+	assert.Equal(t, 0, attempted, "not due yet")
+
+	*now = now.Add(BACKOFF)
+	dispatcher.DeliverDue(ctx)
+	delivery2, _ := dispatcher.Get(ctx, delivery.Id)
+	assert.Equal(t, 2, delivery2.Attempts)
+	assert.WithinDuration(t, now.Add(2*BACKOFF), delivery2.NextAttemptAt, 0, "backs off")
+
+	*now = now.Add(2 * BACKOFF)
+	dispatcher.DeliverDue(ctx)
+	failed, _ := dispatcher.Get(ctx, delivery.Id)
+	assert.Equal(t, enums.DeliveryFailed, failed.Status)
+	assert.Equal(t, http.StatusGone, failed.LastStatusCode)
+	assert.Contains(t, failed.LastError, "status 410")
+
+	*now = now.Add(MAX_BACKOFF)
+	attempted, _ = dispatcher.DeliverDue(ctx)
+	assert.Equal(t, 0, attempted, "failed deliveries aren't retried")
+
+	assert.NoError(t, dispatcher.Replay(ctx, delivery.Id))
+	dispatcher.DeliverDue(ctx)
+
+	replayed, _ := dispatcher.Get(ctx, delivery.Id)
+	assert.Equal(t, enums.DeliveryDelivered, replayed.Status)
+	assert.Len(t, receiver.bodies, 4)
+	assert.Equal(t, receiver.bodies[0], receiver.bodies[3], "replays send the original payload")
+
+	assert.ErrorIs(t, dispatcher.Replay(ctx, "missing"), ErrNotFound)
+}
+
+func TestDispatcherDelay(t *testing.T) {
+	dispatcher := NewDispatcher(nil, nil, MAX_ATTEMPTS)
+
+	assert.Equal(t, BACKOFF, dispatcher.delay(1))
+	assert.Equal(t, 4*BACKOFF, dispatcher.delay(3))
+	assert.Equal(t, MAX_BACKOFF, dispatcher.delay(20))
+}
+
+func TestDispatcherForget(t *testing.T) {
+	ctx := context.Background()
+	dispatcher, _ := newTestDispatcher(t, &receiver{}, MAX_ATTEMPTS)
+
+	for _, id := range []string{"6f1c0b0e-lead", "7a2d1c1f-lead"} {
+		_, err := dispatcher.Publish(ctx, EVENT_ENQUIRY_CREATED, map[string]string{"id": id})
+		assert.NoError(t, err)
+	}
+
+	forgotten, err := dispatcher.Forget(ctx, "6f1c0b0e-lead")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), forgotten)
+
+	deliveries, _ := dispatcher.List(ctx, "", 10)
+	assert.Len(t, deliveries, 1)
+	assert.Contains(t, string(deliveries[0].Payload), "7a2d1c1f-lead")
+}
@@ -0,0 +1,75 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	HEADER_SIGNATURE = "Skulpture-Signature"
+	HEADER_EVENT     = "Skulpture-Event"
+	HEADER_DELIVERY  = "Skulpture-Delivery"
+)
+
+// TOLERANCE is how old a signature may be before receivers should reject it
+const TOLERANCE = 5 * time.Minute
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+var ErrExpiredSignature = errors.New("expired webhook signature")
+
+// Sign returns the signature header, `t=<unix>,v1=<hex>`, where v1 is the
+// HMAC-SHA256 of `<unix>.<body>` keyed with the subscription's secret
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", timestamp.Unix(), signature(secret, timestamp.Unix(), body))
+}
+
+// Verify checks a signature header as a receiver would
+func Verify(secret string, header string, body []byte, now time.Time) error {
+	var timestamp int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrInvalidSignature
+			}
+			timestamp = parsed
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	if timestamp == 0 || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	if age := now.Sub(time.Unix(timestamp, 0)); age > TOLERANCE || age < -TOLERANCE {
+		return ErrExpiredSignature
+	}
+
+	expected := signature(secret, timestamp, body)
+	for _, candidate := range signatures {
+		if hmac.Equal([]byte(candidate), []byte(expected)) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+func signature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
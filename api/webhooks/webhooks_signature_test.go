@@ -0,0 +1,46 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignature(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	body := []byte(`{"type":"enquiry.created"}`)
+	header := Sign("secret", now, body)
+
+	assert.Regexp(t, `^t=1760000000,v1=[0-9a-f]{64}$`, header)
+
+	tests := []struct {
+		name     string
+		secret   string
+		header   string
+		body     []byte
+		now      time.Time
+		expected error
+	}{
+		{"valid", "secret", header, body, now, nil},
+		{"within tolerance", "secret", header, body, now.Add(TOLERANCE), nil},
+		{"rotated secret alongside", "secret", "t=1760000000,v1=00," + header[len("t=1760000000,"):], body, now, nil},
+		{"wrong secret", "other", header, body, now, ErrInvalidSignature},
+		{"tampered body", "secret", header, []byte(`{"type":"enquiry.deleted"}`), now, ErrInvalidSignature},
+		{"too old", "secret", header, body, now.Add(TOLERANCE + time.Second), ErrExpiredSignature},
+		{"from the future", "secret", header, body, now.Add(-TOLERANCE - time.Second), ErrExpiredSignature},
+		{"missing timestamp", "secret", header[len("t=1760000000,"):], body, now, ErrInvalidSignature},
+		{"garbage", "secret", "nonsense", body, now, ErrInvalidSignature},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Verify(test.secret, test.header, test.body, test.now)
+			if test.expected == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, test.expected)
+			}
+		})
+	}
+}
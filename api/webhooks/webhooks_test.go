@@ -0,0 +1,41 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	subscriptions, err := Parse([]byte(`
+subscriptions:
+  - name: crm
+    url: https://crm.example.com/hooks
+    secret: s3cret
+    events: [enquiry.created]
+  - name: everything
+    url: http://localhost:9000
+    secret: other
+`))
+	assert.NoError(t, err)
+	assert.Len(t, subscriptions, 2)
+	assert.True(t, subscriptions[0].wants(EVENT_ENQUIRY_CREATED))
+	assert.False(t, subscriptions[0].wants("enquiry.deleted"))
+	assert.True(t, subscriptions[1].wants("enquiry.deleted"))
+
+	subscriptions, err = Parse(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, subscriptions)
+
+	invalid := map[string]string{
+		"no name":   "subscriptions:\n  - url: https://example.com\n    secret: x\n",
+		"duplicate": "subscriptions:\n  - {name: a, url: https://example.com, secret: x}\n  - {name: a, url: https://example.com, secret: x}\n",
+		"bad url":   "subscriptions:\n  - {name: a, url: example.com, secret: x}\n",
+		"no secret": "subscriptions:\n  - {name: a, url: https://example.com}\n",
+		"yaml":      "subscriptions: {",
+	}
+	for name, config := range invalid {
+		_, err := Parse([]byte(config))
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,207 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const INSTRUMENTATION_NAME = "skulpture/landing/worker"
+
+var ErrQueueFull = errors.New("worker queue is full")
+var ErrClosed = errors.New("worker pool is shut down")
+
+type Job func(ctx context.Context)
+
+type task struct {
+	name string
+	link trace.Link
+	run  Job
+}
+
+// Pool runs background work on a fixed number of goroutines. Jobs outlive the
+// request that submitted them, so they run in the pool's own context and
+// link back to the submitting span instead of being its child
+type Pool struct {
+	name  string
+	queue chan task
+	wg    sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	depth  atomic.Int64
+	active atomic.Int64
+
+	tracer       trace.Tracer
+	registration metric.Registration
+}
+
+func New(name string, concurrency int, queueSize int) *Pool {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	pool := &Pool{
+		name:   name,
+		queue:  make(chan task, queueSize),
+		ctx:    ctx,
+		cancel: cancel,
+		tracer: otel.Tracer(INSTRUMENTATION_NAME),
+	}
+
+	pool.registerMetrics()
+
+	for range concurrency {
+		pool.wg.Add(1)
+		go pool.work()
+	}
+
+	return pool
+}
+
+// Submit queues a job without blocking
+func (p *Pool) Submit(ctx context.Context, name string, job Job) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+
+	t := task{
+		name: name,
+		link: trace.LinkFromContext(ctx, attribute.String("worker.pool", p.name)),
+		run:  job,
+	}
+
+	// counted before it's queued, a worker may take it before the send returns
+	p.depth.Add(1)
+
+	select {
+	case p.queue <- t:
+		return nil
+	default:
+		p.depth.Add(-1)
+		slog.WarnContext(ctx, "rejected", "worker pool", p.name, "job", name, "depth", p.Depth())
+
+		return ErrQueueFull
+	}
+}
+
+// Depth is the number of jobs waiting for a worker
+func (p *Pool) Depth() int64 {
+	return max(p.depth.Load(), 0)
+}
+
+// Shutdown stops accepting jobs and waits for queued jobs to finish.
+// If the context ends first, running jobs have their context cancelled,
+// queued jobs are dropped and Shutdown returns without waiting for them
+// to stop, so the caller can't assume nothing is using shared resources
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	defer func() {
+		if p.registration != nil {
+			p.registration.Unregister()
+		}
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+
+	for t := range p.queue {
+		p.depth.Add(-1)
+
+		// past the shutdown deadline, it could only fail part way
+		if p.ctx.Err() != nil {
+			slog.WarnContext(p.ctx, "dropped", "worker pool", p.name, "job", t.name)
+
+			continue
+		}
+
+		p.run(t)
+	}
+}
+
+func (p *Pool) run(t task) {
+	p.active.Add(1)
+	defer p.active.Add(-1)
+
+	ctx, span := p.tracer.Start(p.ctx, t.name,
+		trace.WithNewRoot(),
+		trace.WithLinks(t.link),
+		trace.WithAttributes(attribute.String("worker.pool", p.name)),
+	)
+	defer span.End()
+
+	defer func() {
+		if err := recover(); err != nil {
+			slog.ErrorContext(ctx, "error", "worker panic", err, "job", t.name)
+		}
+	}()
+
+	t.run(ctx)
+}
+
+func (p *Pool) registerMetrics() {
+	meter := otel.Meter(INSTRUMENTATION_NAME)
+
+	depth, err := meter.Int64ObservableGauge("worker.queue.depth",
+		metric.WithDescription("Jobs waiting for a worker"))
+	if err != nil {
+		slog.Error("error", "worker metrics", err.Error())
+
+		return
+	}
+
+	active, err := meter.Int64ObservableGauge("worker.jobs.active",
+		metric.WithDescription("Jobs currently running"))
+	if err != nil {
+		slog.Error("error", "worker metrics", err.Error())
+
+		return
+	}
+
+	attributes := metric.WithAttributes(attribute.String("worker.pool", p.name))
+	p.registration, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		o.ObserveInt64(depth, p.Depth(), attributes)
+		o.ObserveInt64(active, p.active.Load(), attributes)
+
+		return nil
+	}, depth, active)
+	if err != nil {
+		slog.Error("error", "worker metrics", err.Error())
+	}
+}
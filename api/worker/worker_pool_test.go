@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSubmitRejectsWhenQueueFull(t *testing.T) {
+	pool := New("test", 1, 1)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	assert.NoError(t, pool.Submit(context.Background(), "blocking", func(ctx context.Context) {
+		close(started)
+		<-release
+	}))
+	<-started
+
+	assert.NoError(t, pool.Submit(context.Background(), "queued", func(ctx context.Context) {}))
+	assert.Equal(t, int64(1), pool.Depth())
+	assert.ErrorIs(t, pool.Submit(context.Background(), "rejected", func(ctx context.Context) {}), ErrQueueFull)
+
+	close(release)
+	assert.NoError(t, pool.Shutdown(context.Background()))
+	assert.Equal(t, int64(0), pool.Depth())
+}
+
+func TestShutdownDrainsQueue(t *testing.T) {
+	pool := New("test", 2, 10)
+
+	var completed atomic.Int64
+	for range 10 {
+		assert.NoError(t, pool.Submit(context.Background(), "job", func(ctx context.Context) {
+			time.Sleep(time.Millisecond)
+			completed.Add(1)
+		}))
+	}
+
+	assert.NoError(t, pool.Shutdown(context.Background()))
+	assert.Equal(t, int64(10), completed.Load())
+	assert.ErrorIs(t, pool.Submit(context.Background(), "late", func(ctx context.Context) {}), ErrClosed)
+}
+
+func TestShutdownCancelsJobsAfterDeadline(t *testing.T) {
+	pool := New("test", 1, 1)
+
+	started := make(chan struct{})
+	assert.NoError(t, pool.Submit(context.Background(), "slow", func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+	}))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, pool.Shutdown(ctx), context.DeadlineExceeded)
+}
+
+func TestShutdownDoesNotWaitForJobsIgnoringCancellation(t *testing.T) {
+	pool := New("test", 1, 1)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	started := make(chan struct{})
+	assert.NoError(t, pool.Submit(context.Background(), "stuck", func(ctx context.Context) {
+		close(started)
+		<-release
+	}))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	returned := make(chan error)
+	go func() { returned <- pool.Shutdown(ctx) }()
+
+	select {
+	case err := <-returned:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(time.Second):
+		t.Fatal("shutdown waited for the stuck job")
+	}
+}
+
+func TestShutdownDropsQueuedJobsAfterDeadline(t *testing.T) {
+	pool := New("test", 1, 1)
+
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	assert.NoError(t, pool.Submit(context.Background(), "slow", func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		close(stopped)
+	}))
+	<-started
+
+	var ran atomic.Bool
+	assert.NoError(t, pool.Submit(context.Background(), "queued", func(ctx context.Context) {
+		ran.Store(true)
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, pool.Shutdown(ctx), context.DeadlineExceeded)
+	<-stopped
+	pool.wg.Wait()
+	assert.False(t, ran.Load(), "its context was already cancelled")
+	assert.Equal(t, int64(0), pool.Depth())
+}
+
+func TestJobSpanLinksToSubmitter(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	pool := New("test", 1, 1)
+	pool.tracer = provider.Tracer(INSTRUMENTATION_NAME)
+
+	ctx, request := provider.Tracer("test").Start(context.Background(), "request")
+	assert.NoError(t, pool.Submit(ctx, "job", func(ctx context.Context) {}))
+	request.End()
+
+	assert.NoError(t, pool.Shutdown(context.Background()))
+
+	var job sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "job" {
+			job = span
+		}
+	}
+
+	if assert.NotNil(t, job) {
+		assert.False(t, job.Parent().IsValid(), "job should start a new trace")
+		if assert.Len(t, job.Links(), 1) {
+			assert.Equal(t, request.SpanContext().TraceID(), job.Links()[0].SpanContext.TraceID())
+		}
+	}
+}
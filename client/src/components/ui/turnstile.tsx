@@ -0,0 +1,92 @@
+import React from "react";
+
+const SCRIPT_URL =
+	"https://challenges.cloudflare.com/turnstile/v0/api.js?render=explicit";
+
+type TurnstileApi = {
+	render: (
+		container: HTMLElement,
+		options: { sitekey: string; appearance?: string },
+	) => string;
+	reset: (widgetId: string) => void;
+	remove: (widgetId: string) => void;
+};
+
+declare global {
+	interface Window {
+		turnstile?: TurnstileApi;
+	}
+}
+
+let scriptPromise: Promise<TurnstileApi> | undefined;
+
+const loadScript = () => {
+	scriptPromise ??= new Promise((resolve, reject) => {
+		const script = document.createElement("script");
+		script.src = SCRIPT_URL;
+		script.async = true;
+		script.onload = () => resolve(window.turnstile as TurnstileApi);
+		script.onerror = reject;
+		document.head.appendChild(script);
+	});
+
+	return scriptPromise;
+};
+
+export type TurnstileHandle = {
+	// Tokens can only be verified once, a new one is needed for each submission
+	reset: () => void;
+};
+
+// Turnstile adds a cf-turnstile-response field to the form it's rendered in,
+// which the API verifies for countries with a challenge policy. It's only
+// shown when Cloudflare wants the visitor to interact with it
+export const Turnstile = React.forwardRef<
+	TurnstileHandle,
+	{ siteKey?: string }
+>(({ siteKey }, ref) => {
+	const containerRef = React.useRef<HTMLDivElement>(null);
+	const widgetIdRef = React.useRef<string>(undefined);
+
+	React.useImperativeHandle(ref, () => ({
+		reset: () => {
+			if (widgetIdRef.current) {
+				window.turnstile?.reset(widgetIdRef.current);
+			}
+		},
+	}));
+
+	React.useEffect(() => {
+		if (!siteKey) {
+			return;
+		}
+
+		let cancelled = false;
+
+		loadScript()
+			.then(turnstile => {
+				if (cancelled || !containerRef.current) {
+					return;
+				}
+
+				widgetIdRef.current = turnstile.render(containerRef.current, {
+					sitekey: siteKey,
+					appearance: "interaction-only",
+				});
+			})
+			.catch(console.error);
+
+		return () => {
+			cancelled = true;
+
+			if (widgetIdRef.current) {
+				window.turnstile?.remove(widgetIdRef.current);
+				widgetIdRef.current = undefined;
+			}
+		};
+	}, [siteKey]);
+
+	return <div ref={containerRef} />;
+});
+
+Turnstile.displayName = "Turnstile";
@@ -10,6 +10,7 @@ import {
 import { Label } from "@/components/ui/label";
 import { Dot, Loader } from "@/components/ui/loader";
 import { Textarea } from "@/components/ui/textarea";
+import { Turnstile, type TurnstileHandle } from "@/components/ui/turnstile";
 import { cn } from "@/lib/utils";
 import React from "react";
 import { Controller, useForm } from "react-hook-form";
@@ -64,9 +65,10 @@ const resources = {
 	},
 };
 
-export const EnquiryForm = ({ action = "" }) => {
+export const EnquiryForm = ({ action = "", turnstileSiteKey = "" }) => {
 	const abortControllerRef = React.useRef(new AbortController());
 	const formRef = React.useRef<HTMLFormElement>(null);
+	const turnstileRef = React.useRef<TurnstileHandle>(null);
 
 	const createSubmitStatus = () => ({
 		show: false,
@@ -121,7 +123,8 @@ export const EnquiryForm = ({ action = "" }) => {
 			.onAbort(abortSubmission)
 			.res()
 			.then(reset)
-			.catch(submissionFailed);
+			.catch(submissionFailed)
+			.finally(() => turnstileRef.current?.reset());
 
 		submissionStart();
 	});
@@ -416,6 +419,7 @@ export const EnquiryForm = ({ action = "" }) => {
 					}}
 				/>
 			</FormGroup>
+			<Turnstile ref={turnstileRef} siteKey={turnstileSiteKey} />
 			<FormGroup>
 				<Button
 					type="button"